@@ -0,0 +1,166 @@
+package cosmos_db_restapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TConsistencyLevel - consistency level of the account or of a read request
+type TConsistencyLevel string
+
+const (
+	ConsistencyStrong           TConsistencyLevel = "Strong"
+	ConsistencyBoundedStaleness TConsistencyLevel = "BoundedStaleness"
+	ConsistencySession          TConsistencyLevel = "Session"
+	ConsistencyConsistentPrefix TConsistencyLevel = "ConsistentPrefix"
+	ConsistencyEventual         TConsistencyLevel = "Eventual"
+)
+
+// strength - 5 for Strong down to 1 for Eventual, 0 for an unknown level
+func (me TConsistencyLevel) strength() int {
+	switch me {
+	case ConsistencyStrong:
+		return 5
+	case ConsistencyBoundedStaleness:
+		return 4
+	case ConsistencySession:
+		return 3
+	case ConsistencyConsistentPrefix:
+		return 2
+	case ConsistencyEventual:
+		return 1
+	}
+	return 0
+}
+
+// Valid - true for one of the five cosmos db consistency levels
+func (me TConsistencyLevel) Valid() bool {
+	return me.strength() > 0
+}
+
+// TConsistencyPolicy - the default consistency of the account
+type TConsistencyPolicy struct {
+	DefaultConsistencyLevel TConsistencyLevel `json:"defaultConsistencyLevel"`
+	MaxStalenessPrefix      int               `json:"maxStalenessPrefix"`
+	MaxIntervalInSeconds    int               `json:"maxIntervalInSeconds"`
+}
+
+// TAccountLocation - a regional endpoint of the account
+type TAccountLocation struct {
+	Name                    string `json:"name"`
+	DatabaseAccountEndpoint string `json:"databaseAccountEndpoint"`
+}
+
+// TDatabaseAccount - the database account resource, read with GET on the endpoint
+type TDatabaseAccount struct {
+	ID                           string             `json:"id"`
+	Rid                          string             `json:"_rid"`
+	WritableLocations            []TAccountLocation `json:"writableLocations"`
+	ReadableLocations            []TAccountLocation `json:"readableLocations"`
+	EnableMultipleWriteLocations bool               `json:"enableMultipleWriteLocations"`
+	UserConsistencyPolicy        TConsistencyPolicy `json:"userConsistencyPolicy"`
+}
+
+// tAccountCache - the last read database account, shared by copies of a TDatabase
+type tAccountCache struct {
+	mutex      sync.Mutex
+	account    *TDatabaseAccount
+	read_at    time.Time
+	refreshing bool //a background refresh is running
+
+	err      error         //error of the last failed read, kept until retry_at
+	retry_at time.Time     //the account is not read again before
+	backoff  time.Duration //doubled after each failed read
+}
+
+// the backoff after a failed read of the account by cachedAccount
+const (
+	accountMinBackoff = time.Second
+	accountMaxBackoff = time.Minute
+)
+
+/*
+ReadDatabaseAccount - read the database account resource via rest api
+
+returns:
+
+	Status - response status i.e. 200 ok
+	Body - response body as string
+	Account - the parsed account, i.e. with regions and default consistency
+*/
+func (me *TDatabase) ReadDatabaseAccount() (Status string, Body string, Account TDatabaseAccount) {
+	res := me.send(context.Background(), "GET", "", "", "", nil, nil)
+	if res.Meta.StatusCode == 200 {
+		if err := json.Unmarshal([]byte(res.Body), &Account); err == nil && me.account != nil {
+			me.account.mutex.Lock()
+			me.account.account = &Account
+			me.account.read_at = time.Now()
+			me.account.err, me.account.backoff = nil, 0
+			me.account.mutex.Unlock()
+		}
+	}
+	return res.Status, res.Body, Account
+}
+
+/*
+cachedAccount - the database account, it is read once if not yet known
+
+a failed read is cached, the account is read again after a backoff of
+accountMinBackoff, doubled after each failure up to accountMaxBackoff
+*/
+func (me *TDatabase) cachedAccount() (*TDatabaseAccount, error) {
+	if me.account != nil {
+		me.account.mutex.Lock()
+		account, err, retry_at := me.account.account, me.account.err, me.account.retry_at
+		me.account.mutex.Unlock()
+		if account != nil {
+			return account, nil
+		}
+		if err != nil && time.Now().Before(retry_at) {
+			return nil, err
+		}
+	}
+	status, body, account := me.ReadDatabaseAccount()
+	if !strings.HasPrefix(status, "200") {
+		err := fmt.Errorf("read database account: %s %s", status, body)
+		if me.account != nil {
+			me.account.mutex.Lock()
+			me.account.backoff *= 2
+			if me.account.backoff < accountMinBackoff {
+				me.account.backoff = accountMinBackoff
+			} else if me.account.backoff > accountMaxBackoff {
+				me.account.backoff = accountMaxBackoff
+			}
+			me.account.err, me.account.retry_at = err, time.Now().Add(me.account.backoff)
+			me.account.mutex.Unlock()
+		}
+		return nil, err
+	}
+	return &account, nil
+}
+
+/*
+validateConsistency - a read may only weaken the default consistency of the account,
+the account is read once to get the default
+
+if the account can not be read, the level is not checked on the client,
+the service rejects a level stronger than the default itself
+*/
+func (me *TDatabase) validateConsistency(level TConsistencyLevel) error {
+	if !level.Valid() {
+		return fmt.Errorf("invalid consistency level %q", level)
+	}
+	account, err := me.cachedAccount()
+	if err != nil {
+		return nil
+	}
+	account_level := account.UserConsistencyPolicy.DefaultConsistencyLevel
+	if account_level.Valid() && level.strength() > account_level.strength() {
+		return fmt.Errorf("consistency level %s is stronger than the account default %s", level, account_level)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package cosmos_db_restapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestConsistencyLevel(t *testing.T) {
+	var account_reads int
+	var got_level string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			account_reads += 1
+			w.Write([]byte(`{"id":"account","userConsistencyPolicy":{"defaultConsistencyLevel":"Session"}}`))
+			return
+		}
+		got_level = r.Header.Get("x-ms-consistency-level")
+		w.Write([]byte(`{"id":"1"}`))
+	}))
+	defer server.Close()
+
+	container := ContainerFactory(DatabaseFactory(server.URL+"/", "", "db"), "coll", "pk")
+
+	tests := []struct {
+		name       string
+		level      TConsistencyLevel
+		wantStatus string
+		wantHeader string
+	}{
+		{"no override", "", "200 OK", ""},
+		{"eventual", ConsistencyEventual, "200 OK", "Eventual"},
+		{"session", ConsistencySession, "200 OK", "Session"},
+		{"strong is stronger than account", ConsistencyStrong, "400 Bad Request", ""},
+		{"invalid level", "Whatever", "400 Bad Request", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got_level = ""
+			container.Options.ConsistencyLevel = tt.level
+			gotStatus, gotBody := container.GetDocumentByID("1")
+			if gotStatus != tt.wantStatus {
+				t.Errorf("GetDocumentByID() gotStatus = %v, want %v (%v)", gotStatus, tt.wantStatus, gotBody)
+			}
+			if got_level != tt.wantHeader {
+				t.Errorf("x-ms-consistency-level = %v, want %v", got_level, tt.wantHeader)
+			}
+		})
+	}
+	if account_reads != 1 {
+		t.Errorf("database account read %v times, want 1", account_reads)
+	}
+}
+
+func TestConsistencyLevelWithoutAccount(t *testing.T) {
+	account_reads, account_fails := 0, true
+	var got_level string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			account_reads += 1
+			if account_fails {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.Write([]byte(`{"id":"account","userConsistencyPolicy":{"defaultConsistencyLevel":"Session"}}`))
+			return
+		}
+		got_level = r.Header.Get("x-ms-consistency-level")
+		w.Write([]byte(`{"id":"1"}`))
+	}))
+	defer server.Close()
+
+	database := DatabaseFactory(server.URL+"/", "", "db")
+	container := ContainerFactory(database, "coll", "pk")
+	container.Options.ConsistencyLevel = ConsistencyStrong
+
+	//the account is unknown, the level is sent without a check, the failed read is not repeated at once
+	for i := 0; i < 3; i++ {
+		if status, _ := container.GetDocumentByID("1"); status != "200 OK" || got_level != "Strong" {
+			t.Errorf("GetDocumentByID() = %v, x-ms-consistency-level = %v", status, got_level)
+		}
+	}
+	if account_reads != 1 {
+		t.Errorf("database account read %v times, want 1", account_reads)
+	}
+
+	//after the backoff the account is read again
+	account_fails = false
+	database.account.mutex.Lock()
+	database.account.retry_at = time.Now()
+	database.account.mutex.Unlock()
+	if status, _ := container.GetDocumentByID("1"); status != "400 Bad Request" || account_reads != 2 {
+		t.Errorf("GetDocumentByID() = %v, account reads = %v", status, account_reads)
+	}
+}
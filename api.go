@@ -0,0 +1,41 @@
+package cosmos_db_restapi
+
+/*
+ContainerAPI - the document operations of a container
+
+implemented by *TContainer, service code which depends on the interface can be tested
+with a container of the in-memory server of the package cosmosfake
+*/
+type ContainerAPI interface {
+	GetDocumentByID(id string) (Status string, Body string)
+	CreateDocument(upset bool, data string) (Status string, Body string)
+	ReplaceDocument(id string, data string) (Status string, Body string)
+	PatchDocument(id string, operations []TPatchOperation) (Status string, Body string)
+	DeleteDocumentByID(id string) (Status string, Body string)
+
+	ExecuteQuerry(max_item_count int, continuation string, query TQuery) (Status string, Body string, Continuation string)
+	OpenQuery(max_item_count int, query TQuery)
+	Fetch() (Status string, Body string)
+
+	ExecuteBatch(operations []TBatchOperation, atomic bool) (Status string, Body string, Results []TBatchResult)
+	ReadChangeFeed(mode TChangeFeedMode, range_id string, continuation string, max_item_count int) (Status string, Body string, Continuation string)
+}
+
+// DatabaseAPI - the container operations of a database, implemented by *TDatabase
+type DatabaseAPI interface {
+	Container(container string, partitionkey string) ContainerAPI
+	CreateContainer(properties TContainerProperties, throughput int) (Status string, Body string)
+	ReadContainer(container string) (Status string, Body string, Properties TContainerProperties)
+	DeleteContainer(container string) (Status string, Body string)
+}
+
+var (
+	_ ContainerAPI = (*TContainer)(nil)
+	_ DatabaseAPI  = (*TDatabase)(nil)
+)
+
+// Container - a container of the database, like ContainerFactory
+func (me *TDatabase) Container(container string, partitionkey string) ContainerAPI {
+	result := ContainerFactory(*me, container, partitionkey)
+	return &result
+}
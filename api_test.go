@@ -0,0 +1,43 @@
+package cosmos_db_restapi
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestContainerAPI(t *testing.T) {
+	var got_method, got_path, got_body, got_type string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		got_method, got_path, got_body, got_type = r.Method, r.URL.Path, string(data), r.Header.Get("Content-Type")
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	database := DatabaseFactory(server.URL+"/", "", "db")
+	var api DatabaseAPI = &database
+	container := api.Container("user", "a")
+
+	tests := []struct {
+		name     string
+		call     func() (string, string)
+		wantPath string
+		wantBody string
+		wantType string
+	}{
+		{"replace", func() (string, string) { return container.ReplaceDocument("1", `{"id":"1"}`) }, "PUT /dbs/db/colls/user/docs/1", `{"id":"1"}`, "application/json"},
+		{"patch", func() (string, string) {
+			return container.PatchDocument("1", []TPatchOperation{{Op: "incr", Path: "/stock", Value: 1}})
+		}, "PATCH /dbs/db/colls/user/docs/1", `{"operations":[{"op":"incr","path":"/stock","value":1}]}`, "application/json_patch+json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, _ := tt.call()
+			if status != "200 OK" || got_method+" "+got_path != tt.wantPath || got_body != tt.wantBody || got_type != tt.wantType {
+				t.Errorf("request = %v %v, %v, %v", got_method, got_path, got_body, got_type)
+			}
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package cosmos_db_restapi
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// TAttachment - an attachment of a document, Media is the link to the content
+type TAttachment struct {
+	ID          string `json:"id"`
+	ContentType string `json:"contentType"`
+	Media       string `json:"media"` //external url or the link of the managed media i.e. "/media/{rid}"
+	Rid         string `json:"_rid,omitempty"`
+	Ts          int64  `json:"_ts,omitempty"`
+	Self        string `json:"_self,omitempty"`
+	Etag        string `json:"_etag,omitempty"`
+}
+
+// TAttachments - response body of the attachment feed of a document
+type TAttachments struct {
+	Rid         string        `json:"_rid"`
+	Attachments []TAttachment `json:"Attachments"`
+	Count       uint          `json:"_count"`
+}
+
+// attachmentLink - the link of an attachment of a document i.e. "dbs/db/colls/coll/docs/id/attachments/id"
+func (me *TContainer) attachmentLink(document_id string, id string) TResourceLink {
+	return me.documentLink(document_id).Child("attachments", id)
+}
+
+/*
+ListAttachments - read all attachments of a document via rest api
+
+returns:
+
+	Status - response status i.e. 200 ok
+	Body - response body as string, like TAttachments
+*/
+func (me *TContainer) ListAttachments(document_id string) (Status string, Body string) {
+	res := me.sendFeed("GET", "attachments", me.documentLink(document_id), nil, nil)
+	return res.Status, res.Body
+}
+
+/*
+ReadAttachment - read an attachment of a document by ID via rest api
+
+returns:
+
+	Status - response status i.e. 200 ok
+	Body - response body as string, like TAttachment
+*/
+func (me *TContainer) ReadAttachment(document_id string, id string) (Status string, Body string) {
+	res := me.sendResource("GET", me.attachmentLink(document_id, id), nil, nil)
+	return res.Status, res.Body
+}
+
+/*
+CreateAttachment - create an attachment with a media link via rest api
+
+returns:
+
+	Status - response status i.e. 201 Created
+	Body - response body as string, like TAttachment
+*/
+func (me *TContainer) CreateAttachment(document_id string, attachment TAttachment) (Status string, Body string) {
+	data, _ := json.Marshal(attachment)
+	res := me.sendFeed("POST", "attachments", me.documentLink(document_id), nil, data)
+	return res.Status, res.Body
+}
+
+/*
+ReplaceAttachment - replace an attachment, i.e. with a new media link via rest api
+
+returns:
+
+	Status - response status i.e. 200 ok
+	Body - response body as string, like TAttachment
+*/
+func (me *TContainer) ReplaceAttachment(document_id string, attachment TAttachment) (Status string, Body string) {
+	data, _ := json.Marshal(attachment)
+	res := me.sendResource("PUT", me.attachmentLink(document_id, attachment.ID), nil, data)
+	return res.Status, res.Body
+}
+
+/*
+DeleteAttachment - delete an attachment and its managed media via rest api
+
+returns:
+
+	Status - response status i.e. 204 No Content
+	Body - response body as string i.e. ""
+*/
+func (me *TContainer) DeleteAttachment(document_id string, id string) (Status string, Body string) {
+	res := me.sendResource("DELETE", me.attachmentLink(document_id, id), nil, nil)
+	return res.Status, res.Body
+}
+
+/*
+UploadAttachment - create an attachment with managed media via rest api
+
+parameters:
+
+	document_id - id of the document
+	id - id of the attachment (Slug header)
+	content_type - content type of the media i.e. "image/png"
+	data - the media content
+
+returns:
+
+	Status - response status i.e. 201 Created
+	Body - response body as string, like TAttachment with the media link
+*/
+func (me *TContainer) UploadAttachment(document_id string, id string, content_type string, data []byte) (Status string, Body string) {
+	header := http.Header{}
+	header.Set("Content-Type", content_type)
+	header.Set("Slug", id)
+	res := me.sendFeed("POST", "attachments", me.documentLink(document_id), header, data)
+	return res.Status, res.Body
+}
+
+/*
+ReadMedia - read the content of a managed media via rest api
+
+parameters:
+
+	media_link - the Media of the attachment i.e. "/media/{rid}", or the url of an external media
+
+returns:
+
+	Status - response status i.e. 200 ok
+	ContentType - content type of the media
+	Data - the media content
+
+managed media are signed with the lower case _rid, an external http(s) url
+is read with a plain GET without the authorization of the account
+*/
+func (me *TContainer) ReadMedia(media_link string) (Status string, ContentType string, Data []byte) {
+	var res tResponse
+	if strings.HasPrefix(media_link, "http://") || strings.HasPrefix(media_link, "https://") {
+		res = me.failed(me.Database.readExternal(media_link))
+	} else {
+		path := strings.TrimPrefix(media_link, "/")
+		resource_id := path[strings.LastIndex(path, "/")+1:]
+		header := http.Header{}
+		header.Set("Accept", "*/*")
+		res = me.send("GET", "media", strings.ToLower(resource_id), path, header, nil)
+	}
+	if res.Header != nil {
+		ContentType = res.Header.Get("Content-Type")
+	}
+	return res.Status, ContentType, []byte(res.Body)
+}
+
+// readExternal - an unsigned GET of an url outside of the account, i.e. an external media
+func (me *TDatabase) readExternal(url string) (res tResponse) {
+	start := time.Now()
+	transport := me.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	http_client := &http.Client{Transport: transport}
+	http_res, err := http_client.Get(url)
+	if err != nil {
+		res.Err = err
+		res.Body = err.Error()
+		res.Meta.Duration = time.Since(start)
+		return
+	}
+	defer http_res.Body.Close()
+
+	data, err := ioutil.ReadAll(http_res.Body)
+	res.Err = err
+	res.Status = http_res.Status
+	res.Body = string(data)
+	res.Header = http_res.Header
+	res.Meta.StatusCode = http_res.StatusCode
+	res.Meta.Duration = time.Since(start)
+	return
+}
@@ -0,0 +1,118 @@
+package cosmos_db_restapi
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAttachments(t *testing.T) {
+	var got_method, got_path, got_slug, got_type, got_body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		got_method, got_path, got_body = r.Method, r.URL.Path, string(data)
+		got_slug, got_type = r.Header.Get("Slug"), r.Header.Get("Content-Type")
+		switch {
+		case r.URL.Path == "/media/abc=":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte("png"))
+		case r.Method == "DELETE":
+			w.WriteHeader(http.StatusNoContent)
+		case r.Method == "POST":
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"id":"photo","contentType":"image/png","media":"/media/abc="}`))
+		case r.URL.Path == "/dbs/db/colls/user/docs/1/attachments":
+			w.Write([]byte(`{"_rid":"x","Attachments":[{"id":"photo","contentType":"image/png","media":"/media/abc="}],"_count":1}`))
+		default:
+			w.Write([]byte(`{"id":"photo","contentType":"image/png","media":"/media/abc="}`))
+		}
+	}))
+	defer server.Close()
+
+	container := ContainerFactory(DatabaseFactory(server.URL+"/", "", "db"), "user", "tenant")
+
+	status, body := container.ListAttachments("1")
+	var feed TAttachments
+	json.Unmarshal([]byte(body), &feed)
+	if status != "200 OK" || feed.Count != 1 || feed.Attachments[0].Media != "/media/abc=" {
+		t.Errorf("ListAttachments() = %v, %+v", status, feed)
+	}
+
+	status, _ = container.UploadAttachment("1", "photo", "image/png", []byte("png"))
+	if status != "201 Created" || got_slug != "photo" || got_type != "image/png" || got_body != "png" ||
+		got_path != "/dbs/db/colls/user/docs/1/attachments" {
+		t.Errorf("UploadAttachment() = %v, slug = %v, type = %v, body = %v", status, got_slug, got_type, got_body)
+	}
+
+	status, content_type, data := container.ReadMedia("/media/abc=")
+	if status != "200 OK" || content_type != "image/png" || string(data) != "png" || got_method != "GET" {
+		t.Errorf("ReadMedia() = %v, %v, %v", status, content_type, string(data))
+	}
+
+	attachment := TAttachment{ID: "photo", ContentType: "image/png", Media: "https://example.com/photo.png"}
+	tests := []struct {
+		name       string
+		call       func() (string, string)
+		wantMethod string
+		wantPath   string
+		wantStatus string
+	}{
+		{"create", func() (string, string) { return container.CreateAttachment("1", attachment) }, "POST", "/dbs/db/colls/user/docs/1/attachments", "201 Created"},
+		{"read", func() (string, string) { return container.ReadAttachment("1", "photo") }, "GET", "/dbs/db/colls/user/docs/1/attachments/photo", "200 OK"},
+		{"replace", func() (string, string) { return container.ReplaceAttachment("1", attachment) }, "PUT", "/dbs/db/colls/user/docs/1/attachments/photo", "200 OK"},
+		{"delete", func() (string, string) { return container.DeleteAttachment("1", "photo") }, "DELETE", "/dbs/db/colls/user/docs/1/attachments/photo", "204 No Content"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotStatus, _ := tt.call()
+			if gotStatus != tt.wantStatus || got_method != tt.wantMethod || got_path != tt.wantPath {
+				t.Errorf("gotStatus = %v, method = %v, path = %v", gotStatus, got_method, got_path)
+			}
+		})
+	}
+}
+
+func TestReadMediaLinks(t *testing.T) {
+	key := "a2V5a2V5aw=="
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, _ := AuthorizationToken("GET", "media", "abcd+ef=", r.Header.Get("x-ms-date"), key)
+		if r.Header.Get("authorization") != token {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "image/png")
+		w.Write([]byte("managed"))
+	}))
+	defer server.Close()
+	var got_authorization string
+	external := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got_authorization = r.Header.Get("authorization")
+		w.Header().Set("Content-Type", "image/jpeg")
+		w.Write([]byte("external"))
+	}))
+	defer external.Close()
+
+	container := ContainerFactory(DatabaseFactory(server.URL+"/", key, "db"), "user", "tenant")
+	tests := []struct {
+		name     string
+		link     string
+		wantType string
+		wantData string
+	}{
+		{"signed with the lower case rid", "/media/AbCd+Ef=", "image/png", "managed"},
+		{"external url", external.URL + "/photo.jpg", "image/jpeg", "external"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, content_type, data := container.ReadMedia(tt.link)
+			if status != "200 OK" || content_type != tt.wantType || string(data) != tt.wantData {
+				t.Errorf("ReadMedia() = %v, %v, %v", status, content_type, string(data))
+			}
+		})
+	}
+	if got_authorization != "" {
+		t.Errorf("the external url was read with authorization %v", got_authorization)
+	}
+}
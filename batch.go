@@ -0,0 +1,191 @@
+package cosmos_db_restapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// TOperationType - operation of a batch or bulk request
+type TOperationType string
+
+const (
+	OperationCreate  TOperationType = "Create"
+	OperationUpsert  TOperationType = "Upsert"
+	OperationReplace TOperationType = "Replace"
+	OperationDelete  TOperationType = "Delete"
+	OperationPatch   TOperationType = "Patch"
+	OperationRead    TOperationType = "Read"
+)
+
+// MaxBatchOperations - the maximum number of operations of one batch request
+const MaxBatchOperations = 100
+
+/*
+TPatchOperation - one operation of a partial document update
+
+	{"op": "set", "path": "/snippet", "value": "..."}
+
+op is one of "add", "set", "replace", "remove", "incr" or "move" (with from)
+*/
+type TPatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value,omitempty"`
+	From  string      `json:"from,omitempty"`
+}
+
+// TBatchOperation - one operation of a batch or bulk request
+type TBatchOperation struct {
+	OperationType TOperationType    `json:"operation_type"`
+	PartitionKey  string            `json:"partition_key"` //for bulk, a batch uses the partition key of the container
+	ID            string            `json:"id"`            //for replace, delete, patch and read
+	Data          string            `json:"data"`          //json of the document for create, upsert and replace
+	Patch         []TPatchOperation `json:"patch"`         //for patch
+	IfMatch       string            `json:"if_match"`      //optional etag for optimistic concurrency
+}
+
+// TBatchResult - the result of one operation of a batch or bulk request
+type TBatchResult struct {
+	Index          int             `json:"index"` //position of the operation in the request or input stream
+	Operation      TBatchOperation `json:"operation"`
+	StatusCode     int             `json:"status_code"`
+	SubStatusCode  int             `json:"sub_status_code"`
+	RequestCharge  float64         `json:"request_charge"`
+	ETag           string          `json:"etag"`
+	Body           string          `json:"body"` //the resource as json, if returned
+	RetryAfterInMs int             `json:"retry_after_ms"`
+	Err            error           `json:"-"`
+}
+
+// tBatchItem - an operation as sent to the batch api
+type tBatchItem struct {
+	OperationType TOperationType  `json:"operationType"`
+	ID            string          `json:"id,omitempty"`
+	ResourceBody  json.RawMessage `json:"resourceBody,omitempty"`
+	IfMatch       string          `json:"ifMatch,omitempty"`
+}
+
+// tBatchItemResult - an operation result as returned by the batch api
+type tBatchItemResult struct {
+	StatusCode             int             `json:"statusCode"`
+	SubStatusCode          int             `json:"subStatusCode"`
+	RequestCharge          float64         `json:"requestCharge"`
+	ETag                   string          `json:"eTag"`
+	ResourceBody           json.RawMessage `json:"resourceBody"`
+	RetryAfterMilliseconds int             `json:"retryAfterMilliseconds"`
+}
+
+// batchItem - the operation in the format of the batch api
+func (me TBatchOperation) batchItem() (Item tBatchItem, err error) {
+	Item.OperationType = me.OperationType
+	Item.IfMatch = me.IfMatch
+	switch me.OperationType {
+	case OperationCreate, OperationUpsert, OperationReplace:
+		if !json.Valid([]byte(me.Data)) {
+			return Item, fmt.Errorf("%s: data is not valid json", me.OperationType)
+		}
+		Item.ResourceBody = json.RawMessage(me.Data)
+		if me.OperationType == OperationReplace {
+			Item.ID = me.ID
+		}
+	case OperationDelete, OperationRead:
+		Item.ID = me.ID
+	case OperationPatch:
+		Item.ID = me.ID
+		Item.ResourceBody, err = json.Marshal(map[string]interface{}{"operations": me.Patch})
+	default:
+		err = fmt.Errorf("unknown operation type %q", me.OperationType)
+	}
+	if err == nil && Item.ID == "" && me.OperationType != OperationCreate && me.OperationType != OperationUpsert {
+		err = fmt.Errorf("%s: id is required", me.OperationType)
+	}
+	return
+}
+
+/*
+sendBatch - sends operations of one logical partition as batch request
+
+with atomic all operations succeed or none, else each operation is
+executed on its own and the batch continues after errors
+*/
+func (me *TDatabase) sendBatch(ctx context.Context, collection_link TResourceLink, partitionkey string, operations []TBatchOperation, atomic bool) (res tResponse, Results []TBatchResult) {
+	if err := collection_link.Err(); err != nil {
+		return errorResponse(http.StatusBadRequest, "BadRequest", err), nil
+	}
+	items := make([]tBatchItem, len(operations))
+	for i, operation := range operations {
+		item, err := operation.batchItem()
+		if err != nil {
+			return errorResponse(http.StatusBadRequest, "BadRequest", err), nil
+		}
+		items[i] = item
+	}
+	data, _ := json.Marshal(items)
+
+	header := http.Header{}
+	header.Set("x-ms-cosmos-is-batch-request", "True")
+	if atomic {
+		header.Set("x-ms-cosmos-batch-atomic", "True")
+	} else {
+		header.Set("x-ms-cosmos-batch-atomic", "False")
+		header.Set("x-ms-cosmos-batch-continue-on-error", "True")
+	}
+	header.Set("x-ms-documentdb-partitionkey", partitionKeyHeader(partitionkey))
+
+	res = me.send(ctx, "POST", "docs", collection_link.Link(), collection_link.Feed("docs"), header, data)
+	if res.Meta.StatusCode != http.StatusOK && res.Meta.StatusCode != http.StatusMultiStatus {
+		return
+	}
+
+	var item_results []tBatchItemResult
+	if err := json.Unmarshal([]byte(res.Body), &item_results); err != nil {
+		res.Err = err
+		return
+	}
+	Results = make([]TBatchResult, len(operations))
+	for i, operation := range operations {
+		Results[i] = TBatchResult{Index: i, Operation: operation}
+		if i >= len(item_results) {
+			//the service returns fewer results if it stopped early, the rest is not executed
+			Results[i].StatusCode = http.StatusFailedDependency
+			continue
+		}
+		item := item_results[i]
+		Results[i].StatusCode = item.StatusCode
+		Results[i].SubStatusCode = item.SubStatusCode
+		Results[i].RequestCharge = item.RequestCharge
+		Results[i].ETag = item.ETag
+		Results[i].RetryAfterInMs = item.RetryAfterMilliseconds
+		if len(item.ResourceBody) > 0 {
+			Results[i].Body = string(item.ResourceBody)
+		}
+	}
+	return
+}
+
+/*
+ExecuteBatch - execute up to 100 operations on the partition key of the container
+
+parameters:
+
+	operations - like TBatchOperation, the PartitionKey of the operations is ignored
+	atomic - true: transactional batch, all operations succeed or none
+
+returns:
+
+	Status - response status i.e. 200 ok, 207 Multi-Status if operations failed
+	Body - response body as string
+	Results - the result of each operation in the order of the operations
+*/
+func (me *TContainer) ExecuteBatch(operations []TBatchOperation, atomic bool) (Status string, Body string, Results []TBatchResult) {
+	if len(operations) == 0 || len(operations) > MaxBatchOperations {
+		res := errorResponse(http.StatusBadRequest, "BadRequest", fmt.Errorf("a batch needs 1 to %d operations", MaxBatchOperations))
+		return res.Status, res.Body, nil
+	}
+	res, Results := me.Database.sendBatch(context.Background(), me.collectionLink(), me.PartitionKey, operations, atomic)
+	me.Meta = res.Meta
+	me.Error = responseError(res)
+	return res.Status, res.Body, Results
+}
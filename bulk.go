@@ -0,0 +1,383 @@
+package cosmos_db_restapi
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// TBulkSummary - aggregated result of a bulk execution
+type TBulkSummary struct {
+	Operations    int           `json:"operations"`
+	Succeeded     int           `json:"succeeded"`
+	Failed        int           `json:"failed"`
+	Throttled     int           `json:"throttled"` //429 responses, the operations were retried
+	Batches       int           `json:"batches"`   //batch requests sent
+	RequestCharge float64       `json:"request_charge"`
+	Duration      time.Duration `json:"duration"`
+}
+
+/*
+TBulkExecutor - executes a stream of operations in batches per logical partition key with bounded concurrency
+
+a batch request carries one partition key (x-ms-documentdb-partitionkey), the operations are
+not grouped by partition key range, many keys with few operations each lead to small batches
+*/
+type TBulkExecutor struct {
+	Container      TContainer    `json:"container"`
+	MaxConcurrency int           `json:"max_concurrency"` //parallel batch requests, it is reduced while throttled
+	MaxBatchSize   int           `json:"max_batch_size"`  //operations per batch request, at most MaxBatchOperations
+	MaxRetries     int           `json:"max_retries"`     //retries of a throttled operation
+	FlushInterval  time.Duration `json:"flush_interval"`  //max. wait for a batch to fill up
+}
+
+// BulkExecutorFactory - creates a bulk executor for the container
+func BulkExecutorFactory(container TContainer, max_concurrency int) *TBulkExecutor {
+	if max_concurrency <= 0 {
+		max_concurrency = 4
+	}
+	return &TBulkExecutor{
+		Container:      container,
+		MaxConcurrency: max_concurrency,
+		MaxBatchSize:   MaxBatchOperations,
+		MaxRetries:     9,
+		FlushInterval:  100 * time.Millisecond,
+	}
+}
+
+// tBulkItem - an operation with its position in the input stream
+type tBulkItem struct {
+	index     int
+	operation TBatchOperation
+	retries   int
+}
+
+// tBulkJob - operations of one partition key for one batch request
+type tBulkJob struct {
+	partitionkey string
+	items        []tBulkItem
+}
+
+// tBulkQueue - unbounded queue of jobs, retries are pushed back without blocking
+type tBulkQueue struct {
+	mutex  sync.Mutex
+	cond   *sync.Cond
+	jobs   []*tBulkJob
+	closed bool
+}
+
+func (me *tBulkQueue) push(job *tBulkJob) {
+	me.mutex.Lock()
+	me.jobs = append(me.jobs, job)
+	me.mutex.Unlock()
+	me.cond.Signal()
+}
+
+func (me *tBulkQueue) pop() *tBulkJob {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	for len(me.jobs) == 0 && !me.closed {
+		me.cond.Wait()
+	}
+	if len(me.jobs) == 0 {
+		return nil
+	}
+	job := me.jobs[0]
+	me.jobs = me.jobs[1:]
+	return job
+}
+
+func (me *tBulkQueue) close() {
+	me.mutex.Lock()
+	me.closed = true
+	me.mutex.Unlock()
+	me.cond.Broadcast()
+}
+
+// tLimiter - adaptive concurrency limit, halved on throttling and raised by one on success
+type tLimiter struct {
+	mutex  sync.Mutex
+	cond   *sync.Cond
+	limit  int
+	max    int
+	active int
+}
+
+func (me *tLimiter) acquire() {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	for me.active >= me.limit {
+		me.cond.Wait()
+	}
+	me.active += 1
+}
+
+func (me *tLimiter) release(throttled bool) {
+	me.mutex.Lock()
+	me.active -= 1
+	if throttled {
+		if me.limit = me.limit / 2; me.limit < 1 {
+			me.limit = 1
+		}
+	} else if me.limit < me.max {
+		me.limit += 1
+	}
+	me.mutex.Unlock()
+	me.cond.Broadcast()
+}
+
+/*
+Run - executes the operations of the stream until it is closed
+
+parameters:
+
+	ctx - cancels the execution, open operations fail with the error of the context
+	operations - stream of operations, with PartitionKey and ID like TBatchOperation
+	results - optional channel for the result of each operation, nil to get the summary only
+
+returns:
+
+	Summary - counts and the request charge (RU) of all batch requests
+
+the operations are grouped by logical partition key, not by partition key range, into batch requests (not atomic),
+throttled operations are retried after the retry-after time of the response,
+results are sent in the order of completion, Index is the position in the
+stream; Run returns after the last result was sent, the results channel is not closed
+*/
+func (me *TBulkExecutor) Run(ctx context.Context, operations <-chan TBatchOperation, results chan<- TBatchResult) (Summary TBulkSummary) {
+	start := time.Now()
+	batch_size := me.MaxBatchSize
+	if batch_size <= 0 || batch_size > MaxBatchOperations {
+		batch_size = MaxBatchOperations
+	}
+	flush_interval := me.FlushInterval
+	if flush_interval <= 0 {
+		flush_interval = 100 * time.Millisecond
+	}
+	max_concurrency := me.MaxConcurrency
+	if max_concurrency <= 0 {
+		max_concurrency = 1
+	}
+
+	queue := &tBulkQueue{}
+	queue.cond = sync.NewCond(&queue.mutex)
+	limiter := &tLimiter{limit: max_concurrency, max: max_concurrency}
+	limiter.cond = sync.NewCond(&limiter.mutex)
+
+	var summary_mutex sync.Mutex
+	var pending sync.WaitGroup //jobs in the queue or in execution
+
+	emit := func(result TBatchResult) {
+		summary_mutex.Lock()
+		if result.StatusCode >= 200 && result.StatusCode < 300 {
+			Summary.Succeeded += 1
+		} else {
+			Summary.Failed += 1
+		}
+		summary_mutex.Unlock()
+		if results != nil {
+			results <- result
+		}
+	}
+	fail := func(job *tBulkJob, res tResponse) {
+		for _, item := range job.items {
+			emit(TBatchResult{Index: item.index, Operation: item.operation, StatusCode: res.Meta.StatusCode,
+				SubStatusCode: res.Meta.SubStatusCode, Body: res.Body, Err: res.Err})
+		}
+	}
+	retry := func(job *tBulkJob, retry_after int) {
+		pending.Add(1)
+		time.AfterFunc(time.Duration(retry_after)*time.Millisecond, func() { queue.push(job) })
+	}
+
+	database := me.Container.Database
+	collection_link := me.Container.collectionLink()
+
+	var workers sync.WaitGroup
+	for i := 0; i < max_concurrency; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for job := queue.pop(); job != nil; job = queue.pop() {
+				if ctx.Err() != nil {
+					fail(job, errorResponse(http.StatusRequestTimeout, "RequestTimeout", ctx.Err()))
+					pending.Done()
+					continue
+				}
+
+				operations := make([]TBatchOperation, len(job.items))
+				for i, item := range job.items {
+					operations[i] = item.operation
+				}
+				limiter.acquire()
+				res, batch_results := database.sendBatch(ctx, collection_link, job.partitionkey, operations, false)
+				throttled := res.Meta.StatusCode == http.StatusTooManyRequests
+
+				summary_mutex.Lock()
+				Summary.Batches += 1
+				Summary.RequestCharge += res.Meta.RequestCharge
+				if throttled {
+					Summary.Throttled += len(job.items)
+				}
+				summary_mutex.Unlock()
+
+				switch {
+				case throttled && job.items[0].retries < me.MaxRetries:
+					for i := range job.items {
+						job.items[i].retries += 1
+					}
+					retry(job, res.Meta.RetryAfterInMs)
+				case res.Meta.StatusCode == http.StatusRequestEntityTooLarge && len(job.items) > 1:
+					half := len(job.items) / 2
+					pending.Add(2)
+					queue.push(&tBulkJob{partitionkey: job.partitionkey, items: job.items[:half]})
+					queue.push(&tBulkJob{partitionkey: job.partitionkey, items: job.items[half:]})
+				case batch_results == nil:
+					fail(job, res)
+				default:
+					retry_job := &tBulkJob{partitionkey: job.partitionkey}
+					retry_after := 0
+					for i, result := range batch_results {
+						item := job.items[i]
+						result.Index = item.index
+						if result.StatusCode == http.StatusTooManyRequests && item.retries < me.MaxRetries {
+							throttled = true
+							item.retries += 1
+							retry_job.items = append(retry_job.items, item)
+							if result.RetryAfterInMs > retry_after {
+								retry_after = result.RetryAfterInMs
+							}
+							continue
+						}
+						emit(result)
+					}
+					if len(retry_job.items) > 0 {
+						summary_mutex.Lock()
+						Summary.Throttled += len(retry_job.items)
+						summary_mutex.Unlock()
+						retry(retry_job, retry_after)
+					}
+				}
+				limiter.release(throttled)
+				pending.Done()
+			}
+		}()
+	}
+
+	//group the stream by partition key, full batches are sent at once, the rest after the flush interval
+	groups := map[string]*tBulkJob{}
+	flush := func() {
+		for partitionkey, job := range groups {
+			pending.Add(1)
+			queue.push(job)
+			delete(groups, partitionkey)
+		}
+	}
+	ticker := time.NewTicker(flush_interval)
+	defer ticker.Stop()
+	index := 0
+read_loop:
+	for {
+		select {
+		case <-ctx.Done():
+			break read_loop
+		case <-ticker.C:
+			flush()
+		case operation, ok := <-operations:
+			if !ok {
+				break read_loop
+			}
+			job := groups[operation.PartitionKey]
+			if job == nil {
+				job = &tBulkJob{partitionkey: operation.PartitionKey}
+				groups[operation.PartitionKey] = job
+			}
+			job.items = append(job.items, tBulkItem{index: index, operation: operation})
+			index += 1
+			if len(job.items) >= batch_size {
+				pending.Add(1)
+				queue.push(job)
+				delete(groups, operation.PartitionKey)
+			}
+		}
+	}
+	flush()
+
+	//operations already waiting in the stream are not sent after the cancellation
+	if ctx.Err() != nil {
+		canceled := errorResponse(http.StatusRequestTimeout, "RequestTimeout", ctx.Err())
+	drain_loop:
+		for {
+			select {
+			case operation, ok := <-operations:
+				if !ok {
+					break drain_loop
+				}
+				fail(&tBulkJob{items: []tBulkItem{{index: index, operation: operation}}}, canceled)
+				index += 1
+			default:
+				break drain_loop
+			}
+		}
+	}
+
+	pending.Wait()
+	queue.close()
+	workers.Wait()
+
+	Summary.Operations = index
+	Summary.Duration = time.Since(start)
+	return
+}
+
+/*
+Execute - executes a list of operations like Run
+
+returns:
+
+	Results - the result of each operation in the order of the operations, after a cancellation
+	          the operations not executed fail with the error of the context
+	Summary - counts and the request charge (RU) of all batch requests
+*/
+func (me *TBulkExecutor) Execute(ctx context.Context, operations []TBatchOperation) (Results []TBatchResult, Summary TBulkSummary) {
+	input := make(chan TBatchOperation)
+	output := make(chan TBatchResult)
+	go func() {
+		defer close(input)
+		for _, operation := range operations {
+			select {
+			case input <- operation:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	Results = make([]TBatchResult, len(operations))
+	received := make([]bool, len(operations))
+	done := make(chan struct{})
+	go func() {
+		for result := range output {
+			Results[result.Index] = result
+			received[result.Index] = true
+		}
+		close(done)
+	}()
+
+	Summary = me.Run(ctx, input, output)
+	close(output)
+	<-done
+
+	//operations never picked up after the cancellation fail with the error of the context
+	for i, operation := range operations {
+		if received[i] || ctx.Err() == nil {
+			continue
+		}
+		canceled := errorResponse(http.StatusRequestTimeout, "RequestTimeout", ctx.Err())
+		Results[i] = TBatchResult{Index: i, Operation: operation, StatusCode: canceled.Meta.StatusCode, Body: canceled.Body, Err: canceled.Err}
+		Summary.Failed += 1
+	}
+	Summary.Operations = len(operations)
+	return
+}
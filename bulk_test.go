@@ -0,0 +1,163 @@
+package cosmos_db_restapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// batchServer - answers batch requests, the first request of each partition key is throttled
+func batchServer(t *testing.T, max_items int) (*httptest.Server, *sync.Map) {
+	var seen sync.Map
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("x-ms-cosmos-is-batch-request") != "True" {
+			t.Errorf("missing batch header")
+		}
+		var items []tBatchItem
+		data, _ := ioutil.ReadAll(r.Body)
+		json.Unmarshal(data, &items)
+		if len(items) > max_items {
+			t.Errorf("batch with %v items, want at most %v", len(items), max_items)
+		}
+
+		partitionkey := r.Header.Get("x-ms-documentdb-partitionkey")
+		if _, throttled := seen.LoadOrStore(partitionkey, true); !throttled {
+			w.Header().Set("x-ms-retry-after-ms", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+
+		w.Header().Set("x-ms-request-charge", fmt.Sprint(len(items)))
+		results := make([]tBatchItemResult, len(items))
+		for i, item := range items {
+			switch item.OperationType {
+			case OperationCreate:
+				results[i] = tBatchItemResult{StatusCode: 201, RequestCharge: 1, ResourceBody: item.ResourceBody}
+			case OperationDelete:
+				results[i] = tBatchItemResult{StatusCode: 404, RequestCharge: 1}
+			default:
+				results[i] = tBatchItemResult{StatusCode: 200, RequestCharge: 1}
+			}
+		}
+		json.NewEncoder(w).Encode(results)
+	}))
+	return server, &seen
+}
+
+func TestBulkExecutor(t *testing.T) {
+	server, _ := batchServer(t, 10)
+	defer server.Close()
+
+	container := ContainerFactory(DatabaseFactory(server.URL+"/", "", "db"), "dictionary", "")
+	executor := BulkExecutorFactory(container, 3)
+	executor.MaxBatchSize = 10
+
+	var operations []TBatchOperation
+	for i := 0; i < 45; i++ {
+		operations = append(operations, TBatchOperation{
+			OperationType: OperationCreate,
+			PartitionKey:  fmt.Sprint("pk", i%3),
+			Data:          fmt.Sprintf(`{"id":"%d"}`, i),
+		})
+	}
+	operations = append(operations,
+		TBatchOperation{OperationType: OperationDelete, PartitionKey: "pk0", ID: "missing"},
+		TBatchOperation{OperationType: OperationPatch, PartitionKey: "pk1", ID: "1",
+			Patch: []TPatchOperation{{Op: "set", Path: "/word", Value: "Zwerg"}}})
+
+	results, summary := executor.Execute(context.Background(), operations)
+
+	if summary.Operations != 47 || summary.Succeeded != 46 || summary.Failed != 1 {
+		t.Errorf("summary = %+v", summary)
+	}
+	if summary.Throttled == 0 || summary.RequestCharge != 47 {
+		t.Errorf("summary throttled = %v, request charge = %v", summary.Throttled, summary.RequestCharge)
+	}
+	for i, result := range results {
+		if result.Index != i {
+			t.Errorf("results[%v].Index = %v", i, result.Index)
+		}
+	}
+	if results[0].StatusCode != 201 || results[0].Body != `{"id":"0"}` {
+		t.Errorf("results[0] = %+v", results[0])
+	}
+	if results[45].StatusCode != 404 || results[46].StatusCode != 200 {
+		t.Errorf("results[45] = %v, results[46] = %v", results[45].StatusCode, results[46].StatusCode)
+	}
+}
+
+func TestBulkExecutorCanceled(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests += 1
+	}))
+	defer server.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	executor := BulkExecutorFactory(ContainerFactory(DatabaseFactory(server.URL+"/", "", "db"), "dictionary", ""), 1)
+	executor.MaxBatchSize = 1
+	executor.FlushInterval = time.Millisecond
+	var operations []TBatchOperation
+	for i := 0; i < 50; i++ {
+		operations = append(operations, TBatchOperation{OperationType: OperationCreate, PartitionKey: "pk", Data: fmt.Sprintf(`{"id":"%d"}`, i)})
+	}
+
+	results, summary := executor.Execute(ctx, operations)
+	canceled := 0
+	for i, result := range results {
+		if result.Index != i || (result.StatusCode == 0 && result.Err == nil) || result.Operation.Data != operations[i].Data {
+			t.Fatalf("results[%v] = %+v", i, result)
+		}
+		if errors.Is(result.Err, context.Canceled) {
+			canceled += 1
+		}
+	}
+	if canceled != 50 || summary.Operations != 50 || summary.Failed != 50 || requests != 0 {
+		t.Errorf("canceled = %v, requests = %v, summary = %+v", canceled, requests, summary)
+	}
+}
+
+func TestExecuteBatch(t *testing.T) {
+	var got_atomic string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got_atomic = r.Header.Get("x-ms-cosmos-batch-atomic")
+		w.Write([]byte(`[{"statusCode":201,"requestCharge":5.2,"eTag":"e1"},{"statusCode":204}]`))
+	}))
+	defer server.Close()
+
+	container := ContainerFactory(DatabaseFactory(server.URL+"/", "", "db"), "user", "tenant")
+
+	tests := []struct {
+		name       string
+		operations []TBatchOperation
+		wantStatus string
+		wantCount  int
+	}{
+		{"empty batch", nil, "400 Bad Request", 0},
+		{"invalid json", []TBatchOperation{{OperationType: OperationCreate, Data: "{"}}, "400 Bad Request", 0},
+		{"missing id", []TBatchOperation{{OperationType: OperationDelete}}, "400 Bad Request", 0},
+		{"create and delete", []TBatchOperation{
+			{OperationType: OperationCreate, Data: `{"id":"1"}`},
+			{OperationType: OperationDelete, ID: "2"},
+		}, "200 OK", 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotStatus, _, gotResults := container.ExecuteBatch(tt.operations, true)
+			if gotStatus != tt.wantStatus || len(gotResults) != tt.wantCount {
+				t.Fatalf("ExecuteBatch() = %v, %v results, want %v, %v", gotStatus, len(gotResults), tt.wantStatus, tt.wantCount)
+			}
+			if tt.wantCount > 0 && (got_atomic != "True" || gotResults[0].ETag != "e1" || gotResults[1].StatusCode != 204) {
+				t.Errorf("ExecuteBatch() atomic = %v, results = %+v", got_atomic, gotResults)
+			}
+		})
+	}
+}
@@ -0,0 +1,217 @@
+package cosmos_db_restapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+)
+
+// TChangeFeedMode - value of the A-IM header of a change feed request
+type TChangeFeedMode string
+
+const (
+	ChangeFeedLatestVersion         TChangeFeedMode = "Incremental feed"   //latest version of the changed documents
+	ChangeFeedAllVersionsAndDeletes TChangeFeedMode = "Full-Fidelity Feed" //all versions and deletes with metadata
+)
+
+// ChangeFeedStartNow - continuation to read only changes after the first request
+const ChangeFeedStartNow = "*"
+
+// substatus of 410 Gone if the partition key range was split or merged
+const subStatusPartitionKeyRangeGone = 1002
+
+// TPartitionKeyRange - a physical partition of a container, Parents are the ranges it was split from
+type TPartitionKeyRange struct {
+	ID           string   `json:"id"`
+	MinInclusive string   `json:"minInclusive"`
+	MaxExclusive string   `json:"maxExclusive"`
+	Parents      []string `json:"parents"`
+}
+
+// TPartitionKeyRanges - response body of the pkranges feed
+type TPartitionKeyRanges struct {
+	Rid                string               `json:"_rid"`
+	PartitionKeyRanges []TPartitionKeyRange `json:"PartitionKeyRanges"`
+	Count              uint                 `json:"_count"`
+}
+
+/*
+ReadPartitionKeyRanges - read the partition key ranges of the container via rest api
+
+returns:
+
+	Status - response status i.e. 200 ok
+	Body - response body as string, like TPartitionKeyRanges
+	Ranges - the ranges of the body
+*/
+func (me *TContainer) ReadPartitionKeyRanges() (Status string, Body string, Ranges []TPartitionKeyRange) {
+	res := me.sendFeed("GET", "pkranges", me.collectionLink(), nil, nil)
+	var content TPartitionKeyRanges
+	if res.Meta.StatusCode == http.StatusOK && json.Unmarshal([]byte(res.Body), &content) == nil {
+		Ranges = content.PartitionKeyRanges
+	}
+	return res.Status, res.Body, Ranges
+}
+
+/*
+ReadChangeFeed - read one page of the change feed via rest api
+
+parameters:
+
+	mode - ChangeFeedLatestVersion or ChangeFeedAllVersionsAndDeletes
+	range_id - the partition key range, "" for the partition key of the container
+	continuation - etag of the last page, "" from the beginning or ChangeFeedStartNow
+	max_item_count - changes per page, 0 for the default of the server
+
+returns:
+
+	Status - response status i.e. 200 ok, 304 Not Modified without changes
+	Body - response body as string, the changes in "Documents"
+	Continuation - the continuation for the next page
+*/
+func (me *TContainer) ReadChangeFeed(mode TChangeFeedMode, range_id string, continuation string, max_item_count int) (Status string, Body string, Continuation string) {
+	header := http.Header{}
+	header.Set("A-IM", string(mode))
+	if range_id != "" {
+		header.Set("x-ms-documentdb-partitionkeyrangeid", range_id)
+	}
+	if continuation != "" {
+		header.Set("If-None-Match", continuation)
+	}
+	if max_item_count > 0 {
+		header.Set("x-ms-max-item-count", strconv.Itoa(max_item_count))
+	}
+	res := me.sendFeed("GET", "docs", me.collectionLink(), header, nil)
+	Continuation = res.Meta.ETag
+	if Continuation == "" {
+		Continuation = continuation
+	}
+	return res.Status, res.Body, Continuation
+}
+
+// TChangeFeedReader - reads the change feed of all partition key ranges and follows their splits
+type TChangeFeedReader struct {
+	Container     TContainer        `json:"container"`
+	Mode          TChangeFeedMode   `json:"mode"`
+	MaxItemCount  int               `json:"max_item_count"`
+	Continuations map[string]string `json:"continuations"` //continuation per range id, can be stored to resume
+	Splits        int               `json:"splits"`        //number of split ranges replaced by their children
+
+	start string //continuation of new ranges
+}
+
+/*
+ChangeFeedReaderFactory - creates a reader of the change feed
+
+with a partition key of the container only the changes of the partition key are read,
+start is "" to read from the beginning or ChangeFeedStartNow
+*/
+func ChangeFeedReaderFactory(container TContainer, mode TChangeFeedMode, start string) *TChangeFeedReader {
+	return &TChangeFeedReader{
+		Container:     container,
+		Mode:          mode,
+		Continuations: map[string]string{},
+		start:         start,
+	}
+}
+
+/*
+ReadNext - reads the changes of all ranges until no more changes are available
+
+returns:
+
+	Documents - the changes, documents or for ChangeFeedAllVersionsAndDeletes items with "current", "previous" and "metadata"
+	err - the error of a failed request, the continuations of the ranges read before are kept
+
+a range which is gone with 410/1002 is replaced by its children, which continue with the continuation of the parent
+*/
+func (me *TChangeFeedReader) ReadNext() (Documents []json.RawMessage, err error) {
+	if me.Continuations == nil {
+		me.Continuations = map[string]string{}
+	}
+	if len(me.Continuations) == 0 {
+		if me.Container.PartitionKey != "" {
+			me.Continuations[""] = me.start
+		} else if err = me.refreshRanges(); err != nil {
+			return nil, err
+		}
+	}
+
+	queue := make([]string, 0, len(me.Continuations))
+	for id := range me.Continuations {
+		queue = append(queue, id)
+	}
+	sort.Strings(queue)
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		for {
+			status, body, continuation := me.Container.ReadChangeFeed(me.Mode, id, me.Continuations[id], me.MaxItemCount)
+			meta := me.Container.Meta
+			if meta.StatusCode == http.StatusNotModified {
+				me.Continuations[id] = continuation
+				break
+			}
+			if meta.StatusCode == http.StatusGone && meta.SubStatusCode == subStatusPartitionKeyRangeGone {
+				children, err := me.split(id)
+				if err != nil {
+					return Documents, err
+				}
+				queue = append(queue, children...)
+				break
+			}
+			if me.Container.Error != nil {
+				return Documents, me.Container.Error
+			}
+			var content struct {
+				Documents []json.RawMessage `json:"Documents"`
+			}
+			if err = json.Unmarshal([]byte(body), &content); err != nil {
+				return Documents, fmt.Errorf("change feed of range %q: %s: %w", id, status, err)
+			}
+			me.Continuations[id] = continuation
+			Documents = append(Documents, content.Documents...)
+			if len(content.Documents) == 0 {
+				break
+			}
+		}
+	}
+	return Documents, nil
+}
+
+// refreshRanges - starts the ranges of the container with the start continuation
+func (me *TChangeFeedReader) refreshRanges() error {
+	_, _, ranges := me.Container.ReadPartitionKeyRanges()
+	if me.Container.Error != nil {
+		return me.Container.Error
+	}
+	for _, r := range ranges {
+		me.Continuations[r.ID] = me.start
+	}
+	return nil
+}
+
+// split - replaces the range by its children, which inherit its continuation
+func (me *TChangeFeedReader) split(id string) ([]string, error) {
+	_, _, ranges := me.Container.ReadPartitionKeyRanges()
+	if me.Container.Error != nil {
+		return nil, me.Container.Error
+	}
+	var children []string
+	for _, r := range ranges {
+		for _, parent := range r.Parents {
+			if _, known := me.Continuations[r.ID]; parent == id && !known {
+				me.Continuations[r.ID] = me.Continuations[id]
+				children = append(children, r.ID)
+			}
+		}
+	}
+	if len(children) == 0 {
+		return nil, fmt.Errorf("partition key range %q is gone, but no child range was found", id)
+	}
+	delete(me.Continuations, id)
+	me.Splits += 1
+	return children, nil
+}
@@ -0,0 +1,63 @@
+package cosmos_db_restapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestChangeFeedReader(t *testing.T) {
+	split := false
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/pkranges") {
+			if split {
+				w.Write([]byte(`{"PartitionKeyRanges":[{"id":"1","parents":["0"]},{"id":"2","parents":["0"]}]}`))
+				return
+			}
+			w.Write([]byte(`{"PartitionKeyRanges":[{"id":"0","parents":[]}]}`))
+			return
+		}
+		id, match := r.Header.Get("x-ms-documentdb-partitionkeyrangeid"), r.Header.Get("If-None-Match")
+		requests = append(requests, id+" "+match+" "+r.Header.Get("A-IM"))
+		switch {
+		case id == "0" && split:
+			w.Header().Set("x-ms-substatus", "1002")
+			w.WriteHeader(http.StatusGone)
+		case match == `"5"`:
+			w.Header().Set("etag", `"5"`)
+			w.WriteHeader(http.StatusNotModified)
+		default:
+			w.Header().Set("etag", `"5"`)
+			w.Write([]byte(`{"Documents":[{"id":"` + id + `"}]}`))
+		}
+	}))
+	defer server.Close()
+
+	container := ContainerFactory(DatabaseFactory(server.URL+"/", "", "db"), "user", "")
+	reader := ChangeFeedReaderFactory(container, ChangeFeedLatestVersion, "")
+
+	documents, err := reader.ReadNext()
+	if err != nil || len(documents) != 1 || reader.Continuations["0"] != `"5"` {
+		t.Fatalf("ReadNext() = %s, %v, %v", documents, err, reader.Continuations)
+	}
+
+	split = true
+	requests = nil
+	documents, err = reader.ReadNext()
+	if err != nil || reader.Splits != 1 || len(reader.Continuations) != 2 {
+		t.Fatalf("ReadNext() after split = %s, %v, %v", documents, err, reader.Continuations)
+	}
+	want := []string{`0 "5" Incremental feed`, `1 "5" Incremental feed`, `2 "5" Incremental feed`}
+	if strings.Join(requests, "|") != strings.Join(want, "|") {
+		t.Errorf("requests = %v, want %v", requests, want)
+	}
+
+	data, _ := json.Marshal(reader)
+	var resumed TChangeFeedReader
+	if err := json.Unmarshal(data, &resumed); err != nil || resumed.Continuations["2"] != `"5"` {
+		t.Errorf("resumed = %v, %v", resumed.Continuations, err)
+	}
+}
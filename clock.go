@@ -0,0 +1,111 @@
+package cosmos_db_restapi
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TClockSkewSnapshot - exported state of a TClockSkew
+type TClockSkewSnapshot struct {
+	Offset      time.Duration `json:"offset"`      //added to the local time to sign the requests
+	Measured    time.Duration `json:"measured"`    //server time minus local time of the last response with a Date header
+	Corrections int64         `json:"corrections"` //requests rejected for the time and sent again
+	MeasuredAt  time.Time     `json:"measured_at"` //local time of the last measurement
+}
+
+/*
+TClockSkew - the difference between the local time and the time of the cosmos db, safe for concurrent use
+
+cosmos db rejects requests with a date more than 15 minutes off its time with 401 Unauthorized,
+then the offset is taken from the Date header of the response and the request is signed once
+more with the corrected time
+*/
+type TClockSkew struct {
+	mutex       sync.Mutex
+	offset      time.Duration
+	measured    time.Duration
+	corrections int64
+	measured_at time.Time
+}
+
+// ClockSkewFactory - creates a clock without offset
+func ClockSkewFactory() *TClockSkew {
+	return &TClockSkew{}
+}
+
+// Now - the local time corrected by the offset, the time for the signature
+func (me *TClockSkew) Now() time.Time {
+	if me == nil {
+		return time.Now()
+	}
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	return time.Now().Add(me.offset)
+}
+
+// Offset - the correction of the local time
+func (me *TClockSkew) Offset() time.Duration {
+	if me == nil {
+		return 0
+	}
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	return me.offset
+}
+
+// Export - the current state of the clock
+func (me *TClockSkew) Export() (Snapshot TClockSkewSnapshot) {
+	if me == nil {
+		return
+	}
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	Snapshot.Offset = me.offset
+	Snapshot.Measured = me.measured
+	Snapshot.Corrections = me.corrections
+	Snapshot.MeasuredAt = me.measured_at
+	return
+}
+
+/*
+measure - the skew of a response from its Date header
+
+parameters:
+
+	header - the response header
+	start, end - local time of the request
+
+returns the server time minus the local time in the middle of the request, false without Date header
+*/
+func (me *TClockSkew) measure(header http.Header, start time.Time, end time.Time) (time.Duration, bool) {
+	if me == nil || header == nil {
+		return 0, false
+	}
+	date, err := http.ParseTime(header.Get("Date"))
+	if err != nil {
+		return 0, false
+	}
+	//the Date header has seconds, the server time is in the second after the date
+	skew := date.Add(500 * time.Millisecond).Sub(start.Add(end.Sub(start) / 2))
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	me.measured = skew
+	me.measured_at = end
+	return skew, true
+}
+
+// correct - the requests are signed with the offset from now on
+func (me *TClockSkew) correct(offset time.Duration) {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	me.offset = offset
+	me.corrections += 1
+}
+
+// isClockSkewError - the request was rejected because its date is too far off the server time
+func isClockSkewError(res tResponse) bool {
+	return res.Meta.StatusCode == http.StatusUnauthorized &&
+		strings.Contains(strings.ToLower(res.Body), "not valid at the current time")
+}
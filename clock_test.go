@@ -0,0 +1,127 @@
+package cosmos_db_restapi
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestClockSkew(t *testing.T) {
+	server_time := time.Now().Add(-2 * time.Hour)
+	requests := 0
+	date := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests += 1
+		w.Header()["Date"] = nil //no Date header of the http server
+		if date {
+			w.Header().Set("Date", server_time.UTC().Format(http.TimeFormat))
+		}
+		signed_at, _ := time.Parse("Mon, 02 Jan 2006 15:04:05 gmt", r.Header.Get("x-ms-date"))
+		if skew := signed_at.Sub(server_time); skew > 15*time.Minute || skew < -15*time.Minute {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"code":"Unauthorized","message":"The authorization token is not valid at the current time. Please create another token and retry."}`))
+			return
+		}
+		w.Write([]byte(`{"id":"1"}`))
+	}))
+	defer server.Close()
+
+	keys, _ := KeyProviderFactory("cHJpbWFyeQ==", "c2Vjb25kYXJ5")
+	database := DatabaseFactory(server.URL+"/", "", "db")
+	database.Keys = keys
+	container := ContainerFactory(database, "user", "a")
+
+	tests := []struct {
+		name            string
+		date            bool
+		wantStatus      string
+		wantRequests    int
+		wantCorrections int64
+	}{
+		{"no Date header", false, "401 Unauthorized", 2, 0},
+		{"corrected", true, "200 OK", 2, 1},
+		{"signed with the offset", true, "200 OK", 1, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			date, requests = tt.date, 0
+			status, _ := container.GetDocumentByID("1")
+			clock := database.Clock.Export()
+			if status != tt.wantStatus || requests != tt.wantRequests || clock.Corrections != tt.wantCorrections {
+				t.Errorf("status = %v, requests = %v, corrections = %v", status, requests, clock.Corrections)
+			}
+		})
+	}
+	//the rejected date is no reason to switch the key
+	if database.KeyInUse() != KeyPrimary {
+		t.Errorf("KeyInUse() = %v", database.KeyInUse())
+	}
+	if offset := database.Clock.Offset(); offset > -2*time.Hour+2*time.Second || offset < -2*time.Hour-2*time.Second {
+		t.Errorf("Offset() = %v", offset)
+	}
+}
+
+// tInterleave - runs a request of another goroutine while the body of a response is closed
+type tInterleave struct {
+	io.ReadCloser
+	other func()
+}
+
+func (me *tInterleave) Close() error {
+	if me.other != nil {
+		me.other()
+		me.other = nil
+	}
+	return me.ReadCloser.Close()
+}
+
+type tTransportFunc func(req *http.Request) (*http.Response, error)
+
+func (me tTransportFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return me(req)
+}
+
+func TestClockSkewOfOwnResponse(t *testing.T) {
+	server_time := time.Now().Add(-2 * time.Hour)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header()["Date"] = nil
+		if r.URL.Query().Get("in_time") != "" {
+			w.Header().Set("Date", time.Now().UTC().Format(http.TimeFormat))
+			return
+		}
+		w.Header().Set("Date", server_time.UTC().Format(http.TimeFormat))
+		signed_at, _ := time.Parse("Mon, 02 Jan 2006 15:04:05 gmt", r.Header.Get("x-ms-date"))
+		if skew := signed_at.Sub(server_time); skew > 15*time.Minute || skew < -15*time.Minute {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"code":"Unauthorized","message":"The authorization token is not valid at the current time."}`))
+			return
+		}
+		w.Write([]byte(`{"id":"1"}`))
+	}))
+	defer server.Close()
+
+	database := DatabaseFactory(server.URL+"/", "a2V5", "db")
+	interleaved := false
+	database.Transport = tTransportFunc(func(req *http.Request) (*http.Response, error) {
+		res, err := http.DefaultTransport.RoundTrip(req)
+		if err == nil && !interleaved && res.StatusCode == http.StatusUnauthorized {
+			//a concurrent request measures no skew after the 401 was measured
+			interleaved = true
+			res.Body = &tInterleave{ReadCloser: res.Body, other: func() {
+				database.sendSigned(req.Context(), server.URL+"/", "GET", "docs", "", "?in_time=1", http.Header{}, nil, "a2V5")
+			}}
+		}
+		return res, err
+	})
+
+	res := database.sendInTime(context.Background(), server.URL+"/", "GET", "docs", "", "dbs/db/colls/user/docs/1", http.Header{}, nil, "a2V5")
+	if !interleaved || res.Status != "200 OK" {
+		t.Errorf("sendInTime() = %v, interleaved %v", res.Status, interleaved)
+	}
+	if offset := database.Clock.Offset(); offset > -2*time.Hour+2*time.Second || offset < -2*time.Hour-2*time.Second {
+		t.Errorf("Offset() = %v, want the skew of the rejected request", offset)
+	}
+}
@@ -0,0 +1,194 @@
+package cosmos_db_restapi
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultProfile - the profile of a config file if none is given
+const DefaultProfile = "default"
+
+/*
+TConfig - the settings of a database, from a connection string, the environment or a config file
+
+the json names are the ones of TDatabase, ConnectionString is an alternative for EndpointUri and MasterKey
+*/
+type TConfig struct {
+	EndpointUri             string   `json:"endpoint_uri"`
+	MasterKey               string   `json:"master_key"`
+	Database                string   `json:"database"`
+	ConnectionString        string   `json:"connection_string"`         //i.e. "AccountEndpoint=https://...;AccountKey=...;"
+	EnableEndpointDiscovery bool     `json:"enable_endpoint_discovery"` //route via the regions of the account
+	PreferredRegions        []string `json:"preferred_regions"`         //read regions in order of preference
+}
+
+/*
+ParseConnectionString - the endpoint and key of a connection string of the cosmos db
+
+	AccountEndpoint=https://account.documents.azure.com:443/;AccountKey=...;Database=db;
+
+the names are not case sensitive, Database is optional and not part of the connection strings of the portal
+*/
+func ParseConnectionString(connection_string string) (Config TConfig, err error) {
+	for _, field := range strings.Split(connection_string, ";") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		name, value, found := strings.Cut(field, "=")
+		if !found {
+			return Config, fmt.Errorf("connection string: %q is not name=value", name)
+		}
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "accountendpoint":
+			Config.EndpointUri = strings.TrimSpace(value)
+		case "accountkey":
+			Config.MasterKey = strings.TrimSpace(value)
+		case "database":
+			Config.Database = strings.TrimSpace(value)
+		}
+	}
+	if Config.EndpointUri == "" || Config.MasterKey == "" {
+		return Config, fmt.Errorf("connection string: AccountEndpoint and AccountKey are required")
+	}
+	err = Config.Validate()
+	return Config, err
+}
+
+/*
+ConfigFromEnv - the config of the environment variables with the prefix
+
+the variables are prefix+"ENDPOINT_URI", prefix+"MASTER_KEY", prefix+"DATABASE" or
+prefix+"CONNECTION_STRING", prefix+"PREFERRED_REGIONS" (comma separated) and
+prefix+"ENABLE_ENDPOINT_DISCOVERY", i.e. with the prefix "COSMOS_" COSMOS_ENDPOINT_URI,
+a .env file has to be loaded before, i.e. with godotenv.Load(".env")
+*/
+func ConfigFromEnv(prefix string) (Config TConfig, err error) {
+	Config.EndpointUri = os.Getenv(prefix + "ENDPOINT_URI")
+	Config.MasterKey = os.Getenv(prefix + "MASTER_KEY")
+	Config.Database = os.Getenv(prefix + "DATABASE")
+	Config.ConnectionString = os.Getenv(prefix + "CONNECTION_STRING")
+	if regions := os.Getenv(prefix + "PREFERRED_REGIONS"); regions != "" {
+		for _, region := range strings.Split(regions, ",") {
+			Config.PreferredRegions = append(Config.PreferredRegions, strings.TrimSpace(region))
+		}
+	}
+	if value := os.Getenv(prefix + "ENABLE_ENDPOINT_DISCOVERY"); value != "" {
+		if Config.EnableEndpointDiscovery, err = strconv.ParseBool(value); err != nil {
+			return Config, fmt.Errorf("%sENABLE_ENDPOINT_DISCOVERY: %w", prefix, err)
+		}
+	}
+	return Config.resolve()
+}
+
+/*
+LoadConfig - a profile of a json or yaml config file
+
+	{"default": {"endpoint_uri": "https://...", "master_key": "${COSMOS_KEY}", "database": "db"}, "test": {...}}
+
+the format is chosen by the extension .json, .yaml or .yml, the values may refer to environment
+variables with ${NAME}, so the keys need not be stored in the file
+
+parameters:
+
+	path - the config file
+	profile - name of the profile, "" for DefaultProfile
+*/
+func LoadConfig(path string, profile string) (Config TConfig, err error) {
+	extension := strings.ToLower(filepath.Ext(path))
+	if extension != ".json" && extension != ".yaml" && extension != ".yml" {
+		return Config, fmt.Errorf("%s: the config file must be .json, .yaml or .yml", path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config, err
+	}
+	if profile == "" {
+		profile = DefaultProfile
+	}
+	if extension != ".json" {
+		var content map[string]interface{}
+		if err = yaml.Unmarshal(data, &content); err != nil {
+			return Config, fmt.Errorf("%s: %w", path, err)
+		}
+		if data, err = json.Marshal(content); err != nil {
+			return Config, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	var profiles map[string]json.RawMessage
+	if err = json.Unmarshal(data, &profiles); err != nil {
+		return Config, fmt.Errorf("%s: %w", path, err)
+	}
+	settings, found := profiles[profile]
+	if !found {
+		return Config, fmt.Errorf("%s: the profile %q does not exist", path, profile)
+	}
+	if err = json.Unmarshal(settings, &Config); err != nil {
+		return Config, fmt.Errorf("%s: profile %q: %w", path, profile, err)
+	}
+	Config.EndpointUri = os.ExpandEnv(Config.EndpointUri)
+	Config.MasterKey = os.ExpandEnv(Config.MasterKey)
+	Config.Database = os.ExpandEnv(Config.Database)
+	Config.ConnectionString = os.ExpandEnv(Config.ConnectionString)
+	return Config.resolve()
+}
+
+// resolve - takes endpoint and key of the connection string and validates the config
+func (me TConfig) resolve() (TConfig, error) {
+	if me.ConnectionString != "" {
+		connection, err := ParseConnectionString(me.ConnectionString)
+		if err != nil {
+			return me, err
+		}
+		me.EndpointUri, me.MasterKey = connection.EndpointUri, connection.MasterKey
+		if me.Database == "" {
+			me.Database = connection.Database
+		}
+	}
+	err := me.Validate()
+	return me, err
+}
+
+// Validate - the endpoint must be a http(s) url and the key base64
+func (me *TConfig) Validate() error {
+	endpoint, err := url.Parse(me.EndpointUri)
+	if err != nil || (endpoint.Scheme != "https" && endpoint.Scheme != "http") || endpoint.Host == "" {
+		return fmt.Errorf("the endpoint %q is not a http(s) url", me.EndpointUri)
+	}
+	if !strings.HasSuffix(me.EndpointUri, "/") {
+		me.EndpointUri += "/"
+	}
+	if key, err := base64.StdEncoding.DecodeString(me.MasterKey); err != nil || len(key) == 0 {
+		return fmt.Errorf("the master key is not base64 encoded")
+	}
+	return nil
+}
+
+/*
+DatabaseFromConfig - creates a database object of a validated config
+
+returns:
+
+	Database - like DatabaseFactory with the regions of the config
+	err - the config is invalid or has no database
+*/
+func DatabaseFromConfig(config TConfig) (Database TDatabase, err error) {
+	if config, err = config.resolve(); err != nil {
+		return
+	}
+	if config.Database == "" {
+		return Database, fmt.Errorf("the config has no database")
+	}
+	Database = DatabaseFactory(config.EndpointUri, config.MasterKey, config.Database)
+	Database.EnableEndpointDiscovery = config.EnableEndpointDiscovery
+	Database.PreferredRegions = config.PreferredRegions
+	return
+}
@@ -0,0 +1,137 @@
+package cosmos_db_restapi
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseConnectionString(t *testing.T) {
+	tests := []struct {
+		name         string
+		connection   string
+		wantEndpoint string
+		wantKey      string
+		wantDatabase string
+		wantErr      string
+	}{
+		{"portal", "AccountEndpoint=https://account.documents.azure.com:443/;AccountKey=a2V5a2V5aw==;",
+			"https://account.documents.azure.com:443/", "a2V5a2V5aw==", "", ""},
+		{"database and case", "accountendpoint=https://localhost:8081 ; ACCOUNTKEY=a2V5; Database=db",
+			"https://localhost:8081/", "a2V5", "db", ""},
+		{"missing key", "AccountEndpoint=https://localhost:8081/;", "", "", "", "required"},
+		{"no url", "AccountEndpoint=localhost;AccountKey=a2V5", "", "", "", "url"},
+		{"no base64", "AccountEndpoint=https://localhost/;AccountKey=key!", "", "", "", "base64"},
+		{"no pair", "AccountEndpoint", "", "", "", "name=value"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseConnectionString(tt.connection)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("ParseConnectionString() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got.EndpointUri != tt.wantEndpoint || got.MasterKey != tt.wantKey || got.Database != tt.wantDatabase {
+				t.Errorf("ParseConnectionString() = %+v, %v", got, err)
+			}
+		})
+	}
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv("COSMOS_ENDPOINT_URI", "https://localhost:8081")
+	t.Setenv("COSMOS_MASTER_KEY", "a2V5")
+	t.Setenv("COSMOS_DATABASE", "db")
+	t.Setenv("COSMOS_PREFERRED_REGIONS", "West Europe, North Europe")
+	t.Setenv("COSMOS_ENABLE_ENDPOINT_DISCOVERY", "true")
+	config, err := ConfigFromEnv("COSMOS_")
+	if err != nil || config.EndpointUri != "https://localhost:8081/" || config.Database != "db" ||
+		len(config.PreferredRegions) != 2 || config.PreferredRegions[1] != "North Europe" || !config.EnableEndpointDiscovery {
+		t.Errorf("ConfigFromEnv() = %+v, %v", config, err)
+	}
+
+	t.Setenv("OTHER_CONNECTION_STRING", "AccountEndpoint=https://other:443/;AccountKey=b3RoZXI=;")
+	t.Setenv("OTHER_DATABASE", "dictionary")
+	database, err := DatabaseFromConfig(TConfig{ConnectionString: os.Getenv("OTHER_CONNECTION_STRING")})
+	if err == nil {
+		t.Errorf("DatabaseFromConfig() without database = %+v", database)
+	}
+	config, err = ConfigFromEnv("OTHER_")
+	if database, err = DatabaseFromConfig(config); err != nil || database.EndpointUri != "https://other:443/" ||
+		database.MasterKey != "b3RoZXI=" || database.Database != "dictionary" {
+		t.Errorf("DatabaseFromConfig() = %+v, %v", database, err)
+	}
+
+	t.Setenv("BROKEN_ENDPOINT_URI", "https://localhost/")
+	t.Setenv("BROKEN_MASTER_KEY", "not base64")
+	if _, err := ConfigFromEnv("BROKEN_"); err == nil {
+		t.Errorf("ConfigFromEnv() with an invalid key, want error")
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	json_file := filepath.Join(dir, "cosmos.json")
+	os.WriteFile(json_file, []byte(`{
+		"default": {"endpoint_uri": "https://localhost:8081/", "master_key": "${TEST_COSMOS_KEY}", "database": "db"},
+		"prod": {"connection_string": "AccountEndpoint=https://prod:443/;AccountKey=cHJvZA==;", "database": "shop"}
+	}`), 0o644)
+	yaml_file := filepath.Join(dir, "cosmos.yaml")
+	os.WriteFile(yaml_file, []byte(`# profiles of the cosmos db
+default:
+  endpoint_uri: https://localhost:8081/
+  master_key: "${TEST_COSMOS_KEY}"
+  database: 'lerneria-express' # the dictionary
+  enable_endpoint_discovery: true
+  preferred_regions: [West Europe, North Europe]
+test: &test
+  connection_string: AccountEndpoint=https://test:443/;AccountKey=dGVzdA==;
+  database: test#1
+  preferred_regions:
+  - West Europe
+staging:
+  <<: *test
+  database: >-
+    staging
+`), 0o644)
+	broken_file := filepath.Join(dir, "broken.yml")
+	os.WriteFile(broken_file, []byte("default:\n  database: db\n    master_key: a2V5\n"), 0o644)
+	t.Setenv("TEST_COSMOS_KEY", "a2V5")
+
+	tests := []struct {
+		name         string
+		path         string
+		profile      string
+		wantEndpoint string
+		wantDatabase string
+		wantRegions  int
+		wantErr      string
+	}{
+		{"json default", json_file, "", "https://localhost:8081/", "db", 0, ""},
+		{"json connection string", json_file, "prod", "https://prod:443/", "shop", 0, ""},
+		{"json missing profile", json_file, "dev", "", "", 0, "does not exist"},
+		{"yaml default", yaml_file, "", "https://localhost:8081/", "lerneria-express", 2, ""},
+		{"yaml block sequence", yaml_file, "test", "https://test:443/", "test#1", 1, ""},
+		{"yaml anchor and folded scalar", yaml_file, "staging", "https://test:443/", "staging", 1, ""},
+		{"yaml indentation", broken_file, "", "", "", 0, "line 3"},
+		{"extension", filepath.Join(dir, "cosmos.toml"), "", "", "", 0, "must be .json"},
+		{"missing file", filepath.Join(dir, "missing.json"), "", "", "", 0, "no such file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LoadConfig(tt.path, tt.profile)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("LoadConfig() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got.EndpointUri != tt.wantEndpoint || got.Database != tt.wantDatabase || len(got.PreferredRegions) != tt.wantRegions {
+				t.Errorf("LoadConfig() = %+v, %v", got, err)
+			}
+		})
+	}
+}
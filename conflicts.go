@@ -0,0 +1,77 @@
+package cosmos_db_restapi
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// TConflict - a conflict of the conflict feed of a container
+type TConflict struct {
+	ID            string `json:"id"`
+	Rid           string `json:"_rid"`
+	ResourceType  string `json:"resourceType"`  //i.e. "document"
+	OperationType string `json:"operationType"` //"create", "replace" or "delete"
+	ResourceID    string `json:"resourceId"`    //_rid of the conflicting resource
+	Content       string `json:"content"`       //the conflicting version as json string
+	Ts            int64  `json:"_ts"`
+	Etag          string `json:"_etag"`
+}
+
+// TConflicts - response body of the conflict feed
+type TConflicts struct {
+	Rid       string      `json:"_rid"`
+	Conflicts []TConflict `json:"Conflicts"`
+	Count     uint        `json:"_count"`
+}
+
+/*
+ListConflicts - read the conflict feed of the container via rest api
+
+parameters:
+
+	max_item_count - optional max item count else 0
+	continuation - the continuation of the previous page else ""
+
+returns:
+
+	Status - response status i.e. 200 ok
+	Body - response body as string, like TConflicts
+	Continuation - the Continuation-token if there are more conflicts to read
+*/
+func (me *TContainer) ListConflicts(max_item_count int, continuation string) (Status string, Body string, Continuation string) {
+	header := http.Header{}
+	if max_item_count > 0 {
+		header.Set("x-ms-max-item-count", strconv.Itoa(max_item_count))
+	}
+	if continuation != "" {
+		header.Set("x-ms-continuation", continuation)
+	}
+	res := me.sendFeed("GET", "conflicts", me.collectionLink(), header, nil)
+	return res.Status, res.Body, res.Meta.Continuation
+}
+
+/*
+ReadConflict - read a conflict by ID via rest api
+
+returns:
+
+	Status - response status i.e. 200 ok
+	Body - response body as string, like TConflict
+*/
+func (me *TContainer) ReadConflict(id string) (Status string, Body string) {
+	res := me.sendResource("GET", me.collectionLink().Child("conflicts", id), nil, nil)
+	return res.Status, res.Body
+}
+
+/*
+DeleteConflict - delete a resolved conflict by ID via rest api
+
+returns:
+
+	Status - response status i.e. 204 No Content
+	Body - response body as string i.e. ""
+*/
+func (me *TContainer) DeleteConflict(id string) (Status string, Body string) {
+	res := me.sendResource("DELETE", me.collectionLink().Child("conflicts", id), nil, nil)
+	return res.Status, res.Body
+}
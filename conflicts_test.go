@@ -0,0 +1,54 @@
+package cosmos_db_restapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestConflicts(t *testing.T) {
+	var got_method, got_path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got_method, got_path = r.Method, r.URL.Path
+		switch r.Method {
+		case "DELETE":
+			w.WriteHeader(http.StatusNoContent)
+		case "GET":
+			if r.URL.Path == "/dbs/db/colls/user/conflicts" {
+				w.Header().Set("x-ms-continuation", "next")
+				w.Write([]byte(`{"_rid":"abc","Conflicts":[{"id":"c1","resourceType":"document","operationType":"replace","content":"{\"id\":\"1\"}"}],"_count":1}`))
+				return
+			}
+			w.Write([]byte(`{"id":"c1","operationType":"replace"}`))
+		}
+	}))
+	defer server.Close()
+
+	container := ContainerFactory(DatabaseFactory(server.URL+"/", "", "db"), "user", "tenant")
+
+	status, body, continuation := container.ListConflicts(10, "")
+	var feed TConflicts
+	json.Unmarshal([]byte(body), &feed)
+	if status != "200 OK" || continuation != "next" || feed.Count != 1 || feed.Conflicts[0].OperationType != "replace" {
+		t.Errorf("ListConflicts() = %v, %v, %+v", status, continuation, feed)
+	}
+
+	tests := []struct {
+		name       string
+		call       func() (string, string)
+		wantMethod string
+		wantStatus string
+	}{
+		{"read", func() (string, string) { return container.ReadConflict("c1") }, "GET", "200 OK"},
+		{"delete", func() (string, string) { return container.DeleteConflict("c1") }, "DELETE", "204 No Content"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotStatus, _ := tt.call()
+			if gotStatus != tt.wantStatus || got_method != tt.wantMethod || got_path != "/dbs/db/colls/user/conflicts/c1" {
+				t.Errorf("gotStatus = %v, method = %v, path = %v", gotStatus, got_method, got_path)
+			}
+		})
+	}
+}
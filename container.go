@@ -0,0 +1,157 @@
+package cosmos_db_restapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+)
+
+// TPartitionKeyDefinition - partition key paths of a container, i.e. ["/word"]
+type TPartitionKeyDefinition struct {
+	Paths   []string `json:"paths"`
+	Kind    string   `json:"kind"`              //"Hash" or "MultiHash"
+	Version int      `json:"version,omitempty"` //2 for large partition keys
+}
+
+const (
+	ConflictResolutionLastWriterWins = "LastWriterWins"
+	ConflictResolutionCustom         = "Custom"
+)
+
+/*
+TConflictResolutionPolicy - resolution of write conflicts in accounts with multiple write regions
+
+	{"mode": "LastWriterWins", "conflictResolutionPath": "/_ts"}
+	{"mode": "Custom", "conflictResolutionProcedure": "dbs/db/colls/coll/sprocs/resolver"}
+
+with mode "Custom" and no procedure the conflicts are kept in the conflict feed
+*/
+type TConflictResolutionPolicy struct {
+	Mode                        string `json:"mode"`
+	ConflictResolutionPath      string `json:"conflictResolutionPath,omitempty"`
+	ConflictResolutionProcedure string `json:"conflictResolutionProcedure,omitempty"`
+}
+
+// LastWriterWinsPolicy - the highest value of the numeric path wins, "" for the default "/_ts"
+func LastWriterWinsPolicy(path string) *TConflictResolutionPolicy {
+	return &TConflictResolutionPolicy{Mode: ConflictResolutionLastWriterWins, ConflictResolutionPath: path}
+}
+
+// CustomConflictPolicy - the stored procedure resolves conflicts, "" to resolve them via the conflict feed
+func CustomConflictPolicy(sproc_link string) *TConflictResolutionPolicy {
+	return &TConflictResolutionPolicy{Mode: ConflictResolutionCustom, ConflictResolutionProcedure: sproc_link}
+}
+
+// TContainerProperties - the properties of a container (collection resource)
+type TContainerProperties struct {
+	ID                       string                     `json:"id"`
+	PartitionKey             *TPartitionKeyDefinition   `json:"partitionKey,omitempty"`
+	ConflictResolutionPolicy *TConflictResolutionPolicy `json:"conflictResolutionPolicy,omitempty"`
+	DefaultTtl               *int                       `json:"defaultTtl,omitempty"`           //see TTL, nil: time to live is off
+	AnalyticalStorageTtl     *int                       `json:"analyticalStorageTtl,omitempty"` //ttl in the analytical store, TTLNever to keep forever
+	IndexingPolicy           *TIndexingPolicy           `json:"indexingPolicy,omitempty"`
+	VectorEmbeddingPolicy    *TVectorEmbeddingPolicy    `json:"vectorEmbeddingPolicy,omitempty"`
+	UniqueKeyPolicy          *TUniqueKeyPolicy          `json:"uniqueKeyPolicy,omitempty"` //only at creation
+
+	Rid  string `json:"_rid,omitempty"`
+	Ts   int64  `json:"_ts,omitempty"`
+	Self string `json:"_self,omitempty"`
+	Etag string `json:"_etag,omitempty"`
+}
+
+// TUniqueKey - paths which must be unique in combination within a logical partition
+type TUniqueKey struct {
+	Paths []string `json:"paths"`
+}
+
+/*
+TUniqueKeyPolicy - unique keys of a container, it can only be set when the container is created
+
+	{"uniqueKeys": [{"paths": ["/email"]}]}
+*/
+type TUniqueKeyPolicy struct {
+	UniqueKeys []TUniqueKey `json:"uniqueKeys"`
+}
+
+// databaseLink - the resource link of the database i.e. "dbs/db"
+func (me *TDatabase) databaseLink() TResourceLink {
+	return DatabaseLink(me.Database)
+}
+
+// sendResource - sends a request for the resource of the link, i.e. read a container
+func (me *TDatabase) sendResource(ctx context.Context, verb string, link TResourceLink, header http.Header, body []byte) tResponse {
+	if err := link.Err(); err != nil {
+		return errorResponse(http.StatusBadRequest, "BadRequest", err)
+	}
+	return me.send(ctx, verb, link.ResourceType(), link.Link(), link.Path(), header, body)
+}
+
+// sendFeed - sends a request for the feed of the children of the link, i.e. create a container
+func (me *TDatabase) sendFeed(ctx context.Context, verb string, resource_type string, link TResourceLink, header http.Header, body []byte) tResponse {
+	if err := link.Err(); err != nil {
+		return errorResponse(http.StatusBadRequest, "BadRequest", err)
+	}
+	return me.send(ctx, verb, resource_type, link.Link(), link.Feed(resource_type), header, body)
+}
+
+/*
+CreateContainer - create a container in the database via rest api
+
+parameters:
+
+	properties - like TContainerProperties, at least the id and the partition key
+	throughput - optional manual throughput in RU/s else 0
+
+returns:
+
+	Status - response status i.e. 201 Created
+	Body - response body as string
+*/
+func (me *TDatabase) CreateContainer(properties TContainerProperties, throughput int) (Status string, Body string) {
+	data, err := json.Marshal(properties)
+	if err != nil {
+		res := errorResponse(http.StatusBadRequest, "BadRequest", err)
+		return res.Status, res.Body
+	}
+	header := http.Header{}
+	if throughput > 0 {
+		header.Set("x-ms-offer-throughput", strconv.Itoa(throughput))
+	}
+	res := me.sendFeed(context.Background(), "POST", "colls", me.databaseLink(), header, data)
+	return res.Status, res.Body
+}
+
+/*
+ReadContainer - read the properties of a container via rest api
+
+returns:
+
+	Status - response status i.e. 200 ok
+	Body - response body as string
+	Properties - the parsed properties
+*/
+func (me *TDatabase) ReadContainer(container string) (Status string, Body string, Properties TContainerProperties) {
+	res := me.sendResource(context.Background(), "GET", me.databaseLink().Child("colls", container), nil, nil)
+	if res.Meta.StatusCode == http.StatusOK {
+		_ = json.Unmarshal([]byte(res.Body), &Properties)
+	}
+	return res.Status, res.Body, Properties
+}
+
+/*
+DeleteContainer - delete a container with all documents via rest api
+
+returns:
+
+	Status - response status i.e. 204 No Content
+	Body - response body as string i.e. ""
+*/
+func (me *TDatabase) DeleteContainer(container string) (Status string, Body string) {
+	link := me.databaseLink().Child("colls", container)
+	res := me.sendResource(context.Background(), "DELETE", link, nil, nil)
+	if res.Meta.StatusCode == http.StatusNoContent {
+		me.Sessions.Clear(link.String())
+	}
+	return res.Status, res.Body
+}
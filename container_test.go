@@ -0,0 +1,75 @@
+package cosmos_db_restapi
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCreateContainer(t *testing.T) {
+	var got_path, got_throughput string
+	var got_body map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got_path = r.URL.Path
+		got_throughput = r.Header.Get("x-ms-offer-throughput")
+		data, _ := ioutil.ReadAll(r.Body)
+		got_body = nil
+		json.Unmarshal(data, &got_body)
+		w.WriteHeader(http.StatusCreated)
+		w.Write(data)
+	}))
+	defer server.Close()
+
+	database := DatabaseFactory(server.URL+"/", "", "db")
+
+	tests := []struct {
+		name       string
+		properties TContainerProperties
+		throughput int
+		wantPolicy map[string]interface{}
+	}{
+		{
+			name: "last writer wins",
+			properties: TContainerProperties{
+				ID:                       "user",
+				PartitionKey:             &TPartitionKeyDefinition{Paths: []string{"/tenant"}, Kind: "Hash"},
+				ConflictResolutionPolicy: LastWriterWinsPolicy("/modified"),
+			},
+			throughput: 400,
+			wantPolicy: map[string]interface{}{"mode": "LastWriterWins", "conflictResolutionPath": "/modified"},
+		},
+		{
+			name: "custom resolver",
+			properties: TContainerProperties{
+				ID:                       "user",
+				ConflictResolutionPolicy: CustomConflictPolicy("dbs/db/colls/user/sprocs/resolver"),
+			},
+			wantPolicy: map[string]interface{}{"mode": "Custom", "conflictResolutionProcedure": "dbs/db/colls/user/sprocs/resolver"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotStatus, _ := database.CreateContainer(tt.properties, tt.throughput)
+			if gotStatus != "201 Created" {
+				t.Errorf("CreateContainer() gotStatus = %v, want 201 Created", gotStatus)
+			}
+			if got_path != "/dbs/db/colls" {
+				t.Errorf("path = %v, want /dbs/db/colls", got_path)
+			}
+			if tt.throughput > 0 && got_throughput != "400" {
+				t.Errorf("x-ms-offer-throughput = %v, want 400", got_throughput)
+			}
+			policy, _ := got_body["conflictResolutionPolicy"].(map[string]interface{})
+			if len(policy) != len(tt.wantPolicy) {
+				t.Fatalf("conflictResolutionPolicy = %v, want %v", policy, tt.wantPolicy)
+			}
+			for key, value := range tt.wantPolicy {
+				if policy[key] != value {
+					t.Errorf("conflictResolutionPolicy[%v] = %v, want %v", key, policy[key], value)
+				}
+			}
+		})
+	}
+}
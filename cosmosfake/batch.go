@@ -0,0 +1,105 @@
+package cosmosfake
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// MaxBatchOperations - the maximum number of operations of one batch request
+const MaxBatchOperations = 100
+
+// tBatchItem - an operation of a batch request
+type tBatchItem struct {
+	OperationType string          `json:"operationType"`
+	ID            string          `json:"id"`
+	ResourceBody  json.RawMessage `json:"resourceBody"`
+	IfMatch       string          `json:"ifMatch"`
+}
+
+// tBatchItemResult - the result of an operation of a batch request
+type tBatchItemResult struct {
+	StatusCode    int             `json:"statusCode"`
+	SubStatusCode int             `json:"subStatusCode,omitempty"`
+	RequestCharge float64         `json:"requestCharge"`
+	ETag          string          `json:"eTag,omitempty"`
+	ResourceBody  json.RawMessage `json:"resourceBody,omitempty"`
+}
+
+/*
+executeBatch - the operations of a batch request on one partition key
+
+an atomic batch is rolled back after the first failed operation, the response has its status
+and the other operations 424 Failed Dependency, else all operations are executed and the
+status is 207 Multi-Status if an operation failed
+*/
+func (me *TServer) executeBatch(collection *tCollection, req tRequest, partition_key string, has_key bool) tResponse {
+	if !has_key {
+		return errorResponse(http.StatusBadRequest, "PartitionKey value must be supplied for this operation.")
+	}
+	var items []tBatchItem
+	if err := json.Unmarshal(req.body, &items); err != nil || len(items) == 0 || len(items) > MaxBatchOperations {
+		return errorResponse(http.StatusBadRequest, "The batch request needs 1 to %d operations.", MaxBatchOperations)
+	}
+	atomic := !strings.EqualFold(req.header.Get("x-ms-cosmos-batch-atomic"), "false")
+
+	snapshot := collection.snapshot()
+	results := make([]tBatchItemResult, len(items))
+	status_code := http.StatusOK
+	charge := 0.0
+	for i, item := range items {
+		res := me.batchOperation(collection, partition_key, item)
+		results[i] = tBatchItemResult{StatusCode: res.status_code, RequestCharge: res.charge}
+		if res.header != nil {
+			results[i].ETag = res.header.Get("etag")
+		}
+		if res.status_code < 300 && res.body != nil {
+			results[i].ResourceBody, _ = json.Marshal(res.body)
+		}
+		charge += res.charge
+		if res.status_code < 400 {
+			continue
+		}
+		if !atomic {
+			status_code = http.StatusMultiStatus
+			continue
+		}
+		collection.restore(snapshot)
+		for j := range results {
+			if j != i {
+				results[j] = tBatchItemResult{StatusCode: http.StatusFailedDependency}
+			}
+		}
+		return collection.withSession(tResponse{status_code: res.status_code, body: results, charge: charge})
+	}
+	return collection.withSession(tResponse{status_code: status_code, body: results, charge: charge})
+}
+
+// batchOperation - executes one operation of a batch
+func (me *TServer) batchOperation(collection *tCollection, partition_key string, item tBatchItem) tResponse {
+	switch item.OperationType {
+	case "Create", "Upsert":
+		body, res := decodeResource(item.ResourceBody)
+		if res != nil {
+			return *res
+		}
+		return me.createItem(collection, partition_key, body, item.OperationType == "Upsert", item.IfMatch)
+	case "Replace":
+		body, res := decodeResource(item.ResourceBody)
+		if res != nil {
+			return *res
+		}
+		return me.replaceItem(collection, partition_key, item.ID, body, item.IfMatch)
+	case "Delete":
+		return me.deleteItem(collection, partition_key, item.ID, item.IfMatch)
+	case "Patch":
+		return me.patchItem(collection, partition_key, item.ID, item.ResourceBody, item.IfMatch)
+	case "Read":
+		document := collection.documents[partition_key+"\x00"+item.ID]
+		if document == nil {
+			return notFound()
+		}
+		return tResponse{status_code: http.StatusOK, header: etagHeader(document.body["_etag"].(string)), body: document.body, charge: 1}
+	}
+	return errorResponse(http.StatusBadRequest, "The batch operation type %q is not supported.", item.OperationType)
+}
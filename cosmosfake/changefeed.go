@@ -0,0 +1,282 @@
+package cosmosfake
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// change feed modes of the A-IM header
+const (
+	IncrementalFeed  = "Incremental feed"   //latest version of the changed documents
+	FullFidelityFeed = "Full-Fidelity Feed" //all versions and deletes
+)
+
+// tRange - a partition key range of the effective partition keys [min, max)
+type tRange struct {
+	id       string
+	min, max string
+	parents  []string
+}
+
+// tChange - an entry of the change log of a collection
+type tChange struct {
+	lsn           int64
+	operation     string //"create", "replace" or "delete"
+	partition_key string
+	id            string
+	current       map[string]interface{} //nil for a delete
+	previous      map[string]interface{} //nil for a create
+	ts            int64
+	ttl_expired   bool
+}
+
+// effectivePartitionKey - the hash of the partition key as 16 hex digits below "FF"
+func effectivePartitionKey(partition_key string) string {
+	hash := fnv.New64a()
+	hash.Write([]byte(partition_key))
+	return fmt.Sprintf("%016X", hash.Sum64()%boundValue("FF"))
+}
+
+// boundValue - the numeric value of a range bound, "" is the minimum and "FF" the maximum
+func boundValue(bound string) uint64 {
+	if len(bound) < 16 {
+		bound += strings.Repeat("0", 16-len(bound))
+	}
+	value, _ := strconv.ParseUint(bound[:16], 16, 64)
+	return value
+}
+
+func (me *tRange) contains(partition_key string) bool {
+	value := boundValue(effectivePartitionKey(partition_key))
+	return value >= boundValue(me.min) && value < boundValue(me.max)
+}
+
+/*
+SplitPartition - splits a partition key range of the container into two child ranges
+
+requests for the parent range are answered with 410 Gone and sub status 1002 afterwards,
+the child ranges are listed with the parent in "parents"
+
+parameters:
+  - database, container - the ids
+  - range_id - the id of the range to split, the initial range of a container is "0"
+
+returns:
+  - the ids of the child ranges
+  - error for a missing container or range
+*/
+func (me *TServer) SplitPartition(database string, container string, range_id string) ([]string, error) {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	db := me.databases[database]
+	if db == nil || db.collections[container] == nil {
+		return nil, fmt.Errorf("container %s/%s does not exist", database, container)
+	}
+	collection := db.collections[container]
+	for i, parent := range collection.ranges {
+		if parent.id != range_id {
+			continue
+		}
+		low, high := boundValue(parent.min), boundValue(parent.max)
+		if high-low < 2 {
+			return nil, fmt.Errorf("range %s cannot be split", range_id)
+		}
+		middle := fmt.Sprintf("%016X", low+(high-low)/2)
+		parents := append(append([]string{}, parent.parents...), parent.id)
+		next := 0
+		for _, r := range collection.ranges {
+			if value, err := strconv.Atoi(r.id); err == nil && value >= next {
+				next = value + 1
+			}
+		}
+		for id := range collection.gone {
+			if value, err := strconv.Atoi(id); err == nil && value >= next {
+				next = value + 1
+			}
+		}
+		left := &tRange{id: strconv.Itoa(next), min: parent.min, max: middle, parents: parents}
+		right := &tRange{id: strconv.Itoa(next + 1), min: middle, max: parent.max, parents: parents}
+		collection.ranges = append(collection.ranges[:i:i], append([]*tRange{left, right}, collection.ranges[i+1:]...)...)
+		collection.gone[parent.id] = true
+		return []string{left.id, right.id}, nil
+	}
+	return nil, fmt.Errorf("range %s does not exist", range_id)
+}
+
+/*
+requestRange - the range of the x-ms-documentdb-partitionkeyrangeid header
+
+returns nil if the header is not set and 410 Gone with sub status 1002 for a split range
+*/
+func (me *tCollection) requestRange(req tRequest) (*tRange, *tResponse) {
+	id := req.header.Get("x-ms-documentdb-partitionkeyrangeid")
+	if id == "" {
+		return nil, nil
+	}
+	if index := strings.LastIndex(id, ","); index >= 0 {
+		id = id[index+1:]
+	}
+	for _, r := range me.ranges {
+		if r.id == id {
+			return r, nil
+		}
+	}
+	if me.gone[id] {
+		res := errorResponse(http.StatusGone, "The requested partition key range %s is gone due to a split.", id)
+		res.header = http.Header{}
+		res.header.Set("x-ms-substatus", "1002")
+		return nil, &res
+	}
+	res := errorResponse(http.StatusBadRequest, "The partition key range %s does not exist.", id)
+	return nil, &res
+}
+
+// record - appends the change to the change log, the lsn is the one of the collection
+func (me *tCollection) record(change tChange) {
+	change.lsn = me.lsn
+	me.changes = append(me.changes, change)
+}
+
+// handlePartitionKeyRanges - dbs/{db}/colls/{coll}/pkranges, the ranges not split
+func (me *TServer) handlePartitionKeyRanges(req tRequest) tResponse {
+	collection, res := me.collection(req)
+	if res != nil {
+		return *res
+	}
+	if req.verb != "GET" {
+		return errorResponse(http.StatusMethodNotAllowed, "%s is not allowed on pkranges", req.verb)
+	}
+	rid := collection.properties["_rid"].(string)
+	ranges := []interface{}{}
+	for _, r := range collection.ranges {
+		ranges = append(ranges, map[string]interface{}{
+			"id":           r.id,
+			"_rid":         rid,
+			"minInclusive": r.min,
+			"maxExclusive": r.max,
+			"parents":      r.parents,
+			"status":       "online",
+		})
+	}
+	return feedResponse(req, rid, "PartitionKeyRanges", ranges, 1)
+}
+
+/*
+readChangeFeed - the changes after the lsn of the If-None-Match header
+
+the A-IM header selects the latest version of the documents or all versions and deletes,
+the etag of the response is the continuation for the next request and "*" starts with the
+current state, without changes the response is 304 Not Modified
+*/
+func (me *TServer) readChangeFeed(collection *tCollection, req tRequest, partition_key string, has_key bool, r *tRange) tResponse {
+	mode := req.header.Get("A-IM")
+	if !strings.EqualFold(mode, IncrementalFeed) && !strings.EqualFold(mode, FullFidelityFeed) {
+		return errorResponse(http.StatusBadRequest, "The A-IM header %q is not supported.", mode)
+	}
+	var since int64
+	switch match := strings.Trim(req.header.Get("If-None-Match"), "\""); match {
+	case "":
+		if modified := req.header.Get("If-Modified-Since"); modified != "" {
+			start, err := http.ParseTime(modified)
+			if err != nil {
+				return errorResponse(http.StatusBadRequest, "The If-Modified-Since header is invalid.")
+			}
+			for _, change := range collection.changes {
+				if change.ts < start.Unix() {
+					since = change.lsn
+				}
+			}
+		}
+	case "*":
+		since = collection.lsn
+	default:
+		value, err := strconv.ParseInt(match, 10, 64)
+		if err != nil || value < 0 {
+			return errorResponse(http.StatusBadRequest, "The continuation etag %q is invalid.", match)
+		}
+		since = value
+	}
+
+	var changes []tChange
+	for _, change := range collection.changes {
+		if change.lsn > since && (!has_key || change.partition_key == partition_key) && (r == nil || r.contains(change.partition_key)) {
+			changes = append(changes, change)
+		}
+	}
+	last := since
+	if len(changes) > 0 {
+		last = changes[len(changes)-1].lsn
+	}
+
+	var items []interface{}
+	var lsns []int64
+	if strings.EqualFold(mode, FullFidelityFeed) {
+		for _, change := range changes {
+			items = append(items, fullFidelityItem(change))
+			lsns = append(lsns, change.lsn)
+		}
+	} else {
+		latest := map[string]tChange{}
+		for _, change := range changes {
+			latest[change.partition_key+"\x00"+change.id] = change
+		}
+		var list []tChange
+		for _, change := range latest {
+			if change.current != nil {
+				list = append(list, change)
+			}
+		}
+		sort.Slice(list, func(i, j int) bool { return list[i].lsn < list[j].lsn })
+		for _, change := range list {
+			items = append(items, change.current)
+			lsns = append(lsns, change.lsn)
+		}
+	}
+
+	max_item_count := DefaultMaxItemCount
+	if value, err := strconv.Atoi(req.header.Get("x-ms-max-item-count")); err == nil && value > 0 {
+		max_item_count = value
+	}
+	if len(items) > max_item_count {
+		items = items[:max_item_count]
+		last = lsns[max_item_count-1]
+	}
+
+	header := etagHeader("\"" + strconv.FormatInt(last, 10) + "\"")
+	if len(items) == 0 {
+		return collection.withSession(tResponse{status_code: http.StatusNotModified, header: header, charge: 1})
+	}
+	header.Set("x-ms-item-count", strconv.Itoa(len(items)))
+	res := tResponse{
+		status_code: http.StatusOK,
+		header:      header,
+		body:        map[string]interface{}{"_rid": collection.properties["_rid"], "Documents": items, "_count": len(items)},
+		charge:      float64(len(items)),
+	}
+	return collection.withSession(res)
+}
+
+// fullFidelityItem - the change with current and previous image and the metadata
+func fullFidelityItem(change tChange) map[string]interface{} {
+	metadata := map[string]interface{}{
+		"operationType":     change.operation,
+		"lsn":               change.lsn,
+		"crts":              change.ts,
+		"timeToLiveExpired": change.ttl_expired,
+	}
+	item := map[string]interface{}{"metadata": metadata}
+	if change.current != nil {
+		item["current"] = change.current
+	}
+	if change.previous != nil {
+		item["previous"] = change.previous
+		if lsn, ok := change.previous["_lsn"]; ok {
+			metadata["previousImageLSN"] = lsn
+		}
+	}
+	return item
+}
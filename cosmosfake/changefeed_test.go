@@ -0,0 +1,131 @@
+package cosmosfake_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"testing"
+
+	cosmos "github.com/jankstar/cosmos_db_restapi"
+	"github.com/jankstar/cosmos_db_restapi/cosmosfake"
+)
+
+func TestChangeFeed(t *testing.T) {
+	fake := cosmosfake.ServerFactory("")
+	defer fake.Close()
+	fake.CreateContainer("db", "user", "/tenant")
+	database := cosmos.DatabaseFactory(fake.EndpointUri, fake.MasterKey, "db")
+
+	tenant := cosmos.ContainerFactory(database, "user", "a")
+	tenant.CreateDocument(false, `{"id":"1","tenant":"a","name":"Zwerg"}`)
+	tenant.CreateDocument(true, `{"id":"1","tenant":"a","name":"Riese"}`)
+	tenant.CreateDocument(false, `{"id":"2","tenant":"a"}`)
+	tenant.DeleteDocumentByID("2")
+
+	status, body, continuation := tenant.ReadChangeFeed(cosmos.ChangeFeedLatestVersion, "", "", 0)
+	var feed struct {
+		Documents []map[string]interface{} `json:"Documents"`
+	}
+	json.Unmarshal([]byte(body), &feed)
+	if status != "200 OK" || len(feed.Documents) != 1 || feed.Documents[0]["name"] != "Riese" || continuation != `"4"` {
+		t.Fatalf("ReadChangeFeed() latest = %v, %v, %v", status, body, continuation)
+	}
+
+	status, body, _ = tenant.ReadChangeFeed(cosmos.ChangeFeedAllVersionsAndDeletes, "", "", 0)
+	var all struct {
+		Documents []struct {
+			Current  map[string]interface{} `json:"current"`
+			Previous map[string]interface{} `json:"previous"`
+			Metadata map[string]interface{} `json:"metadata"`
+		} `json:"Documents"`
+	}
+	json.Unmarshal([]byte(body), &all)
+	var operations []string
+	for _, item := range all.Documents {
+		operations = append(operations, fmt.Sprint(item.Metadata["operationType"], item.Metadata["lsn"]))
+	}
+	if status != "200 OK" || fmt.Sprint(operations) != "[create1 replace2 create3 delete4]" ||
+		all.Documents[1].Previous["name"] != "Zwerg" || all.Documents[3].Current != nil {
+		t.Fatalf("ReadChangeFeed() all versions = %v, %v", status, body)
+	}
+
+	status, _, next := tenant.ReadChangeFeed(cosmos.ChangeFeedLatestVersion, "", continuation, 0)
+	if status != "304 Not Modified" || next != continuation {
+		t.Errorf("ReadChangeFeed() without changes = %v, %v", status, next)
+	}
+	status, _, next = tenant.ReadChangeFeed(cosmos.ChangeFeedLatestVersion, "", cosmos.ChangeFeedStartNow, 0)
+	if status != "304 Not Modified" || next != `"4"` {
+		t.Errorf("ReadChangeFeed() from now = %v, %v", status, next)
+	}
+	tenant.CreateDocument(false, `{"id":"3","tenant":"a"}`)
+	status, body, _ = tenant.ReadChangeFeed(cosmos.ChangeFeedLatestVersion, "", next, 0)
+	if status != "200 OK" || tenant.Meta.ItemCount != 1 {
+		t.Errorf("ReadChangeFeed() after continuation = %v, %v", status, body)
+	}
+}
+
+func TestPartitionSplit(t *testing.T) {
+	fake := cosmosfake.ServerFactory("")
+	defer fake.Close()
+	fake.CreateContainer("db", "user", "/tenant")
+	database := cosmos.DatabaseFactory(fake.EndpointUri, fake.MasterKey, "db")
+	for i := 0; i < 20; i++ {
+		tenant := cosmos.ContainerFactory(database, "user", fmt.Sprint("t", i))
+		tenant.CreateDocument(false, fmt.Sprintf(`{"id":"%d","tenant":"t%d"}`, i, i))
+	}
+
+	container := cosmos.ContainerFactory(database, "user", "")
+	reader := cosmos.ChangeFeedReaderFactory(container, cosmos.ChangeFeedLatestVersion, "")
+	reader.MaxItemCount = 7
+	documents, err := reader.ReadNext()
+	if err != nil || len(documents) != 20 {
+		t.Fatalf("ReadNext() = %v documents, %v", len(documents), err)
+	}
+
+	children, err := fake.SplitPartition("db", "user", "0")
+	if err != nil || fmt.Sprint(children) != "[1 2]" {
+		t.Fatalf("SplitPartition() = %v, %v", children, err)
+	}
+	if _, err := fake.SplitPartition("db", "user", "0"); err == nil {
+		t.Errorf("SplitPartition() of a split range, want error")
+	}
+
+	status, _, _ := container.ReadChangeFeed(cosmos.ChangeFeedLatestVersion, "0", "", 0)
+	if status != "410 Gone" || container.Meta.SubStatusCode != 1002 {
+		t.Errorf("ReadChangeFeed() of the split range = %v, %+v", status, container.Meta)
+	}
+	_, _, ranges := container.ReadPartitionKeyRanges()
+	if len(ranges) != 2 || ranges[0].Parents[0] != "0" || ranges[0].MaxExclusive != ranges[1].MinInclusive {
+		t.Errorf("ReadPartitionKeyRanges() = %+v", ranges)
+	}
+
+	for i := 20; i < 30; i++ {
+		tenant := cosmos.ContainerFactory(database, "user", fmt.Sprint("t", i))
+		tenant.CreateDocument(false, fmt.Sprintf(`{"id":"%d","tenant":"t%d"}`, i, i))
+	}
+	documents, err = reader.ReadNext()
+	if err != nil || len(documents) != 10 || reader.Splits != 1 {
+		t.Fatalf("ReadNext() after split = %v documents, %v, %v", len(documents), err, reader.Continuations)
+	}
+	var ids []string
+	for _, document := range documents {
+		var content struct {
+			ID string `json:"id"`
+		}
+		json.Unmarshal(document, &content)
+		ids = append(ids, content.ID)
+	}
+	sort.Strings(ids)
+	if fmt.Sprint(ids) != "[20 21 22 23 24 25 26 27 28 29]" {
+		t.Errorf("ReadNext() ids = %v", ids)
+	}
+
+	query := cosmos.TQuery{Query: "SELECT VALUE COUNT(1) FROM c"}
+	status, body, _ := container.ExecuteQuerry(0, "", query)
+	if status != "200 OK" || body == "" {
+		t.Errorf("ExecuteQuerry() after split = %v, %v", status, body)
+	}
+	if reader.Continuations["1"] == "" || reader.Continuations["2"] == "" {
+		t.Errorf("continuations = %v", reader.Continuations)
+	}
+}
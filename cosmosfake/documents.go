@@ -0,0 +1,389 @@
+package cosmosfake
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// tDocument - a stored document with the system properties
+type tDocument struct {
+	partition_key string //the partition key value as json array i.e. ["tenant"]
+	body          map[string]interface{}
+	sequence      uint64 //order of creation
+}
+
+// tQuery - the body of a query request
+type tQuery struct {
+	Query      string `json:"query"`
+	Parameters []struct {
+		Name  string      `json:"name"`
+		Value interface{} `json:"value"`
+	} `json:"parameters"`
+}
+
+// valueAt - the value of a path like "/address/city" in the document
+func valueAt(document map[string]interface{}, path string) (interface{}, bool) {
+	var value interface{} = document
+	for _, name := range strings.Split(strings.Trim(path, "/"), "/") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if value, ok = object[name]; !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// partitionKeyOf - the partition key value of the document as json array, {} for a missing value
+func (me *tCollection) partitionKeyOf(document map[string]interface{}) string {
+	if len(me.partition_key) == 0 {
+		return ""
+	}
+	values := make([]interface{}, len(me.partition_key))
+	for i, path := range me.partition_key {
+		value, found := valueAt(document, path)
+		if !found {
+			value = map[string]interface{}{}
+		}
+		values[i] = value
+	}
+	data, _ := json.Marshal(values)
+	return string(data)
+}
+
+/*
+requestPartitionKey - the partition key of the x-ms-documentdb-partitionkey header
+
+returns the key as json array and false if the header is not set,
+a collection without partitions has the key ""
+*/
+func (me *tCollection) requestPartitionKey(req tRequest) (string, bool, *tResponse) {
+	if len(me.partition_key) == 0 {
+		return "", true, nil
+	}
+	header := req.header.Get("x-ms-documentdb-partitionkey")
+	if header == "" {
+		return "", false, nil
+	}
+	var values []interface{}
+	if err := json.Unmarshal([]byte(header), &values); err != nil || len(values) != len(me.partition_key) {
+		res := errorResponse(http.StatusBadRequest, "Partition key provided either doesn't correspond to definition in the collection or doesn't match partition key field values specified in the document.")
+		return "", false, &res
+	}
+	data, _ := json.Marshal(values)
+	return string(data), true, nil
+}
+
+// expired - the document is past its time to live
+func (me *tCollection) expired(document *tDocument, now int64) bool {
+	default_ttl, ok := me.properties["defaultTtl"].(float64)
+	if !ok {
+		return false
+	}
+	ttl := default_ttl
+	if value, found := document.body["ttl"].(float64); found {
+		ttl = value
+	}
+	if ttl <= 0 {
+		return false
+	}
+	ts, _ := document.body["_ts"].(int64)
+	return ts+int64(ttl) <= now
+}
+
+// purgeExpired - removes the documents past their time to live
+func (me *TServer) purgeExpired(collection *tCollection) {
+	now := me.Now().Unix()
+	for key, document := range collection.documents {
+		if collection.expired(document, now) {
+			delete(collection.documents, key)
+			collection.lsn += 1
+			collection.record(tChange{operation: "delete", partition_key: document.partition_key, id: document.body["id"].(string),
+				previous: document.body, ts: now, ttl_expired: true})
+		}
+	}
+}
+
+// violatesUniqueKey - another document of the partition has the same values of a unique key
+func (me *tCollection) violatesUniqueKey(partition_key string, document map[string]interface{}) bool {
+	for _, paths := range me.unique_keys {
+		values := func(body map[string]interface{}) string {
+			list := make([]interface{}, len(paths))
+			for i, path := range paths {
+				list[i], _ = valueAt(body, path)
+			}
+			data, _ := json.Marshal(list)
+			return string(data)
+		}
+		own := values(document)
+		for _, other := range me.documents {
+			if other.partition_key == partition_key && other.body["id"] != document["id"] && values(other.body) == own {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// sortedDocuments - the documents of the partition key, all for "", in order of creation
+func (me *tCollection) sortedDocuments(partition_key string, all bool) []*tDocument {
+	return me.rangeDocuments(partition_key, all, nil)
+}
+
+// rangeDocuments - the sorted documents, with all restricted to the range if not nil
+func (me *tCollection) rangeDocuments(partition_key string, all bool, r *tRange) []*tDocument {
+	var list []*tDocument
+	for _, document := range me.documents {
+		if (all && (r == nil || r.contains(document.partition_key))) || (!all && document.partition_key == partition_key) {
+			list = append(list, document)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].sequence < list[j].sequence })
+	return list
+}
+
+// handleDocuments - dbs/{db}/colls/{coll}/docs and dbs/{db}/colls/{coll}/docs/{id}
+func (me *TServer) handleDocuments(req tRequest) tResponse {
+	collection, res := me.collection(req)
+	if res != nil {
+		return *res
+	}
+	me.purgeExpired(collection)
+
+	partition_key, has_key, res := collection.requestPartitionKey(req)
+	if res != nil {
+		return *res
+	}
+
+	if len(req.segments) == 5 {
+		r, res := collection.requestRange(req)
+		if res != nil {
+			return *res
+		}
+		switch {
+		case req.verb == "POST" && (strings.EqualFold(req.header.Get("x-ms-documentdb-isquery"), "true") ||
+			strings.HasPrefix(req.header.Get("Content-Type"), "application/query+json")):
+			if !has_key && !strings.EqualFold(req.header.Get("x-ms-documentdb-query-enablecrosspartition"), "true") {
+				return errorResponse(http.StatusBadRequest, "Cross partition query is required but disabled. Please set x-ms-documentdb-query-enablecrosspartition to true, specify x-ms-documentdb-partitionkey, or revise your query to avoid this exception.")
+			}
+			return me.queryDocuments(collection, req, partition_key, !has_key, r)
+		case req.verb == "POST" && strings.EqualFold(req.header.Get("x-ms-cosmos-is-batch-request"), "true"):
+			return me.executeBatch(collection, req, partition_key, has_key)
+		case req.verb == "POST":
+			return me.createDocument(collection, req, partition_key, has_key)
+		case req.verb == "GET" && req.header.Get("A-IM") != "":
+			return me.readChangeFeed(collection, req, partition_key, has_key, r)
+		case req.verb == "GET":
+			var list []interface{}
+			for _, document := range collection.rangeDocuments(partition_key, !has_key, r) {
+				list = append(list, document.body)
+			}
+			res := feedResponse(req, collection.properties["_rid"].(string), "Documents", list, 1)
+			return collection.withSession(res)
+		}
+		return errorResponse(http.StatusMethodNotAllowed, "%s is not allowed on docs", req.verb)
+	}
+
+	if !has_key {
+		return errorResponse(http.StatusBadRequest, "PartitionKey value must be supplied for this operation.")
+	}
+	id := req.segments[5]
+	if_match := req.header.Get("If-Match")
+
+	switch req.verb {
+	case "GET":
+		document := collection.documents[partition_key+"\x00"+id]
+		if document == nil {
+			return collection.withSession(notFound())
+		}
+		etag := document.body["_etag"].(string)
+		if match := req.header.Get("If-None-Match"); match != "" && (match == etag || match == "*") {
+			return collection.withSession(tResponse{status_code: http.StatusNotModified, header: etagHeader(etag), charge: 1})
+		}
+		return collection.withSession(tResponse{status_code: http.StatusOK, header: etagHeader(etag), body: document.body, charge: 1})
+	case "PUT":
+		body, res := decodeResource(req.body)
+		if res != nil {
+			return *res
+		}
+		return me.withTriggers(collection, req, partition_key, "Replace", body, func(body map[string]interface{}) tResponse {
+			return me.replaceItem(collection, partition_key, id, body, if_match)
+		})
+	case "DELETE":
+		return me.withTriggers(collection, req, partition_key, "Delete", nil, func(map[string]interface{}) tResponse {
+			return me.deleteItem(collection, partition_key, id, if_match)
+		})
+	case "PATCH":
+		return me.patchItem(collection, partition_key, id, req.body, if_match)
+	}
+	return errorResponse(http.StatusMethodNotAllowed, "%s is not allowed on a document", req.verb)
+}
+
+// notFound - the response for a missing document
+func notFound() tResponse {
+	return errorResponse(http.StatusNotFound, "Entity with the specified id does not exist in the system. More info: https://aka.ms/cosmosdb-tsg-not-found")
+}
+
+// createDocument - create or with x-ms-documentdb-is-upsert create or replace
+func (me *TServer) createDocument(collection *tCollection, req tRequest, partition_key string, has_key bool) tResponse {
+	body, res := decodeResource(req.body)
+	if res != nil {
+		return *res
+	}
+	if !has_key {
+		return errorResponse(http.StatusBadRequest, "PartitionKey value must be supplied for this operation.")
+	}
+	upsert := strings.EqualFold(req.header.Get("x-ms-documentdb-is-upsert"), "true")
+	operation := "Create"
+	if upsert {
+		operation = "Upsert"
+	}
+	if_match := req.header.Get("If-Match")
+	return me.withTriggers(collection, req, partition_key, operation, body, func(body map[string]interface{}) tResponse {
+		return me.createItem(collection, partition_key, body, upsert, if_match)
+	})
+}
+
+// createItem - creates the document, with upsert an existing document is replaced
+func (me *TServer) createItem(collection *tCollection, partition_key string, body map[string]interface{}, upsert bool, if_match string) tResponse {
+	if collection.partitionKeyOf(body) != partition_key {
+		return errorResponse(http.StatusBadRequest, "PartitionKey extracted from document doesn't match the one specified in the header.")
+	}
+	existing := collection.documents[partition_key+"\x00"+body["id"].(string)]
+	status_code := http.StatusCreated
+	if existing != nil {
+		if !upsert {
+			return errorResponse(http.StatusConflict, "Entity with the specified id already exists in the system.")
+		}
+		if res := preconditionFailed(if_match, existing.body["_etag"].(string)); res != nil {
+			return *res
+		}
+		status_code = http.StatusOK
+	}
+	if collection.violatesUniqueKey(partition_key, body) {
+		return errorResponse(http.StatusConflict, "Unique index constraint violation.")
+	}
+	return me.writeDocument(collection, partition_key, body, existing, status_code)
+}
+
+// replaceItem - replaces the existing document
+func (me *TServer) replaceItem(collection *tCollection, partition_key string, id string, body map[string]interface{}, if_match string) tResponse {
+	document := collection.documents[partition_key+"\x00"+id]
+	if document == nil {
+		return collection.withSession(notFound())
+	}
+	if res := preconditionFailed(if_match, document.body["_etag"].(string)); res != nil {
+		return *res
+	}
+	if body["id"] != id {
+		return errorResponse(http.StatusBadRequest, "The id of the document in the request body does not match the id of the resource link.")
+	}
+	if collection.partitionKeyOf(body) != partition_key {
+		return errorResponse(http.StatusBadRequest, "PartitionKey extracted from document doesn't match the one specified in the header.")
+	}
+	if collection.violatesUniqueKey(partition_key, body) {
+		return errorResponse(http.StatusConflict, "Unique index constraint violation.")
+	}
+	return me.writeDocument(collection, partition_key, body, document, http.StatusOK)
+}
+
+// deleteItem - deletes the existing document
+func (me *TServer) deleteItem(collection *tCollection, partition_key string, id string, if_match string) tResponse {
+	document := collection.documents[partition_key+"\x00"+id]
+	if document == nil {
+		return collection.withSession(notFound())
+	}
+	if res := preconditionFailed(if_match, document.body["_etag"].(string)); res != nil {
+		return *res
+	}
+	delete(collection.documents, partition_key+"\x00"+id)
+	collection.lsn += 1
+	collection.record(tChange{operation: "delete", partition_key: partition_key, id: id, previous: document.body, ts: me.Now().Unix()})
+	return collection.withSession(tResponse{status_code: http.StatusNoContent, charge: 5})
+}
+
+// writeDocument - stores the document with new system properties, existing is nil for a new document
+func (me *TServer) writeDocument(collection *tCollection, partition_key string, body map[string]interface{}, existing *tDocument, status_code int) tResponse {
+	document := &tDocument{partition_key: partition_key, body: body}
+	if existing != nil {
+		document.sequence = existing.sequence
+		body["_rid"] = existing.body["_rid"]
+	} else {
+		me.sequence += 1
+		document.sequence = me.sequence
+		body["_rid"] = me.nextRid()
+	}
+	body["_self"] = collection.properties["_self"].(string) + "docs/" + body["_rid"].(string) + "/"
+	body["_etag"] = me.etag()
+	body["_attachments"] = "attachments/"
+	body["_ts"] = me.Now().Unix()
+	collection.documents[partition_key+"\x00"+body["id"].(string)] = document
+	collection.lsn += 1
+	body["_lsn"] = collection.lsn
+	change := tChange{operation: "create", partition_key: partition_key, id: body["id"].(string), current: body, ts: body["_ts"].(int64)}
+	if existing != nil {
+		change.operation = "replace"
+		change.previous = existing.body
+	}
+	collection.record(change)
+	return collection.withSession(tResponse{status_code: status_code, header: etagHeader(body["_etag"].(string)), body: body, charge: 5})
+}
+
+// queryDocuments - runs the query on the documents of the partition key, of all partitions or the range with all
+func (me *TServer) queryDocuments(collection *tCollection, req tRequest, partition_key string, all bool, r *tRange) tResponse {
+	var query tQuery
+	if err := json.Unmarshal(req.body, &query); err != nil {
+		return errorResponse(http.StatusBadRequest, "The query is invalid: %s", err.Error())
+	}
+	parsed, err := parseQuery(query.Query)
+	if err != nil {
+		return errorResponse(http.StatusBadRequest, "%s", err.Error())
+	}
+	if parsed.from == nil || rootName(parsed.from.source) == "" {
+		return errorResponse(http.StatusBadRequest, "The query must select FROM the collection.")
+	}
+
+	ctx := &tContext{parameters: map[string]interface{}{}, udf: me.udfFunction(collection, partition_key)}
+	for _, parameter := range query.Parameters {
+		ctx.parameters[parameter.Name] = parameter.Value
+	}
+	documents := []interface{}{}
+	for _, document := range collection.rangeDocuments(partition_key, all, r) {
+		documents = append(documents, document.body)
+	}
+	list, err := runQuery(ctx, parsed, tEnv{}, documents)
+	if err != nil {
+		return errorResponse(http.StatusBadRequest, "%s", err.Error())
+	}
+	res := feedResponse(req, collection.properties["_rid"].(string), "Documents", list, 2.5)
+	return collection.withSession(res)
+}
+
+// withSession - adds the session token of the collection to the response
+func (me *tCollection) withSession(res tResponse) tResponse {
+	if res.header == nil {
+		res.header = http.Header{}
+	}
+	res.header.Set("x-ms-session-token", "0:-1#"+strconv.FormatInt(me.lsn, 10))
+	return res
+}
+
+// preconditionFailed - the If-Match header does not match the etag
+func preconditionFailed(match string, etag string) *tResponse {
+	if match != "" && match != "*" && match != etag {
+		res := errorResponse(http.StatusPreconditionFailed, "Operation cannot be performed because one of the specified precondition is not met.")
+		return &res
+	}
+	return nil
+}
+
+func etagHeader(etag string) http.Header {
+	header := http.Header{}
+	header.Set("etag", etag)
+	return header
+}
@@ -0,0 +1,822 @@
+package cosmosfake
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// tUndefined - the value of a missing property, it is left out of results
+type tUndefined struct{}
+
+var undefined = tUndefined{}
+
+func (tUndefined) MarshalJSON() ([]byte, error) {
+	return []byte("null"), nil
+}
+
+// tEnv - the bindings of the aliases of FROM and JOIN for one row
+type tEnv map[string]interface{}
+
+// with - a copy of the bindings with one more alias
+func (me tEnv) with(alias string, value interface{}) tEnv {
+	env := make(tEnv, len(me)+1)
+	for key, item := range me {
+		env[key] = item
+	}
+	env[alias] = value
+	return env
+}
+
+// tContext - the state of a query execution
+type tContext struct {
+	parameters map[string]interface{}
+	udf        func(name string, args []interface{}) (interface{}, error) //user defined functions, nil if there are none
+	group      []tEnv                                                     //rows of the group for aggregates
+	err        error                                                      //the first error, the execution stops
+}
+
+func (me *tContext) fail(format string, args ...interface{}) interface{} {
+	if me.err == nil {
+		me.err = fmt.Errorf(format, args...)
+	}
+	return undefined
+}
+
+// tExpr - an expression of a query
+type tExpr interface {
+	eval(ctx *tContext, env tEnv) interface{}
+}
+
+type tLiteral struct {
+	value interface{}
+}
+
+type tParameterRef struct {
+	name string
+}
+
+type tIdentifier struct {
+	name string
+}
+
+// tProperty - object.key or object[key]
+type tProperty struct {
+	object tExpr
+	key    tExpr
+}
+
+type tUnary struct {
+	op      string
+	operand tExpr
+}
+
+type tBinary struct {
+	op    string
+	left  tExpr
+	right tExpr
+}
+
+type tConditional struct {
+	condition tExpr
+	then      tExpr
+	otherwise tExpr
+}
+
+type tIn struct {
+	value tExpr
+	list  []tExpr
+	not   bool
+}
+
+type tBetween struct {
+	value tExpr
+	low   tExpr
+	high  tExpr
+	not   bool
+}
+
+type tLike struct {
+	value   tExpr
+	pattern tExpr
+	escape  tExpr
+	not     bool
+}
+
+type tArrayLiteral struct {
+	items []tExpr
+}
+
+type tObjectLiteral struct {
+	keys   []string
+	values []tExpr
+}
+
+type tCall struct {
+	name string
+	args []tExpr
+	udf  bool
+}
+
+type tAggregate struct {
+	name string
+	arg  tExpr
+}
+
+// tSubquery - kind "" for a scalar subquery, "ARRAY" or "EXISTS"
+type tSubquery struct {
+	query *tSelect
+	kind  string
+}
+
+func (me *tLiteral) eval(ctx *tContext, env tEnv) interface{} {
+	return me.value
+}
+
+func (me *tParameterRef) eval(ctx *tContext, env tEnv) interface{} {
+	value, found := ctx.parameters[me.name]
+	if !found {
+		return ctx.fail("The parameter %s is not defined.", me.name)
+	}
+	return value
+}
+
+func (me *tIdentifier) eval(ctx *tContext, env tEnv) interface{} {
+	value, found := env[me.name]
+	if !found {
+		return ctx.fail("Identifier '%s' could not be resolved.", me.name)
+	}
+	return value
+}
+
+func (me *tProperty) eval(ctx *tContext, env tEnv) interface{} {
+	object := me.object.eval(ctx, env)
+	key := me.key.eval(ctx, env)
+	switch o := object.(type) {
+	case map[string]interface{}:
+		if name, ok := key.(string); ok {
+			if value, found := o[name]; found {
+				return value
+			}
+		}
+	case []interface{}:
+		if index, ok := toNumber(key); ok && index == math.Trunc(index) && index >= 0 && index < float64(len(o)) {
+			return o[int(index)]
+		}
+	}
+	return undefined
+}
+
+func (me *tUnary) eval(ctx *tContext, env tEnv) interface{} {
+	value := me.operand.eval(ctx, env)
+	switch me.op {
+	case "NOT":
+		if b, ok := value.(bool); ok {
+			return !b
+		}
+	case "-":
+		if n, ok := toNumber(value); ok {
+			return -n
+		}
+	case "+":
+		if n, ok := toNumber(value); ok {
+			return n
+		}
+	case "~":
+		if n, ok := toNumber(value); ok {
+			return float64(^int64(n))
+		}
+	}
+	return undefined
+}
+
+func (me *tBinary) eval(ctx *tContext, env tEnv) interface{} {
+	left := me.left.eval(ctx, env)
+	switch me.op {
+	case "AND":
+		if left == false {
+			return false
+		}
+		right := me.right.eval(ctx, env)
+		if right == false {
+			return false
+		}
+		if left == true && right == true {
+			return true
+		}
+		return undefined
+	case "OR":
+		if left == true {
+			return true
+		}
+		right := me.right.eval(ctx, env)
+		if right == true {
+			return true
+		}
+		if left == false && right == false {
+			return false
+		}
+		return undefined
+	case "??":
+		if left != undefined {
+			return left
+		}
+		return me.right.eval(ctx, env)
+	}
+
+	right := me.right.eval(ctx, env)
+	switch me.op {
+	case "=", "!=":
+		equal, ok := equalValues(left, right)
+		if !ok {
+			return undefined
+		}
+		return equal == (me.op == "=")
+	case "<", "<=", ">", ">=":
+		order, ok := compareValues(left, right)
+		if !ok {
+			return undefined
+		}
+		switch me.op {
+		case "<":
+			return order < 0
+		case "<=":
+			return order <= 0
+		case ">":
+			return order > 0
+		}
+		return order >= 0
+	case "||":
+		a, ok_a := left.(string)
+		b, ok_b := right.(string)
+		if ok_a && ok_b {
+			return a + b
+		}
+		return undefined
+	}
+
+	a, ok_a := toNumber(left)
+	b, ok_b := toNumber(right)
+	if !ok_a || !ok_b {
+		return undefined
+	}
+	switch me.op {
+	case "+":
+		return a + b
+	case "-":
+		return a - b
+	case "*":
+		return a * b
+	case "/":
+		if b == 0 {
+			return undefined
+		}
+		return a / b
+	case "%":
+		if b == 0 {
+			return undefined
+		}
+		return math.Mod(a, b)
+	case "&":
+		return float64(int64(a) & int64(b))
+	case "|":
+		return float64(int64(a) | int64(b))
+	case "^":
+		return float64(int64(a) ^ int64(b))
+	}
+	return undefined
+}
+
+func (me *tConditional) eval(ctx *tContext, env tEnv) interface{} {
+	if me.condition.eval(ctx, env) == true {
+		return me.then.eval(ctx, env)
+	}
+	return me.otherwise.eval(ctx, env)
+}
+
+func (me *tIn) eval(ctx *tContext, env tEnv) interface{} {
+	value := me.value.eval(ctx, env)
+	if value == undefined {
+		return undefined
+	}
+	for _, item := range me.list {
+		if equal, ok := equalValues(value, item.eval(ctx, env)); ok && equal {
+			return !me.not
+		}
+	}
+	return me.not
+}
+
+func (me *tBetween) eval(ctx *tContext, env tEnv) interface{} {
+	value := me.value.eval(ctx, env)
+	low, ok_low := compareValues(value, me.low.eval(ctx, env))
+	high, ok_high := compareValues(value, me.high.eval(ctx, env))
+	if !ok_low || !ok_high {
+		return undefined
+	}
+	return (low >= 0 && high <= 0) != me.not
+}
+
+func (me *tLike) eval(ctx *tContext, env tEnv) interface{} {
+	value, ok_value := me.value.eval(ctx, env).(string)
+	pattern, ok_pattern := me.pattern.eval(ctx, env).(string)
+	if !ok_value || !ok_pattern {
+		return undefined
+	}
+	escape := ""
+	if me.escape != nil {
+		escape, _ = me.escape.eval(ctx, env).(string)
+	}
+	var expression strings.Builder
+	expression.WriteString("(?s)^")
+	runes := []rune(pattern)
+	for i := 0; i < len(runes); i++ {
+		switch {
+		case escape != "" && string(runes[i]) == escape && i+1 < len(runes):
+			i += 1
+			expression.WriteString(regexp.QuoteMeta(string(runes[i])))
+		case runes[i] == '%':
+			expression.WriteString(".*")
+		case runes[i] == '_':
+			expression.WriteString(".")
+		case runes[i] == '[':
+			end := strings.IndexRune(string(runes[i:]), ']')
+			if end < 0 {
+				expression.WriteString(regexp.QuoteMeta("["))
+				continue
+			}
+			class := string(runes[i+1 : i+end])
+			if strings.HasPrefix(class, "^") {
+				expression.WriteString("[^" + regexp.QuoteMeta(class[1:]) + "]")
+			} else {
+				expression.WriteString("[" + strings.ReplaceAll(regexp.QuoteMeta(class), `\-`, "-") + "]")
+			}
+			i += end
+		default:
+			expression.WriteString(regexp.QuoteMeta(string(runes[i])))
+		}
+	}
+	expression.WriteString("$")
+	matcher, err := regexp.Compile(expression.String())
+	if err != nil {
+		return undefined
+	}
+	return matcher.MatchString(value) != me.not
+}
+
+func (me *tArrayLiteral) eval(ctx *tContext, env tEnv) interface{} {
+	array := []interface{}{}
+	for _, item := range me.items {
+		if value := item.eval(ctx, env); value != undefined {
+			array = append(array, value)
+		}
+	}
+	return array
+}
+
+func (me *tObjectLiteral) eval(ctx *tContext, env tEnv) interface{} {
+	object := map[string]interface{}{}
+	for i, key := range me.keys {
+		if value := me.values[i].eval(ctx, env); value != undefined {
+			object[key] = value
+		}
+	}
+	return object
+}
+
+func (me *tCall) eval(ctx *tContext, env tEnv) interface{} {
+	args := make([]interface{}, len(me.args))
+	for i, arg := range me.args {
+		args[i] = arg.eval(ctx, env)
+	}
+	if me.udf {
+		if ctx.udf == nil {
+			return ctx.fail("The user defined function '%s' does not exist.", me.name)
+		}
+		value, err := ctx.udf(me.name, args)
+		if err != nil {
+			return ctx.fail("%s", err.Error())
+		}
+		return value
+	}
+	return builtinFunctions[me.name].call(args)
+}
+
+// aggregateFunctions - functions over the rows of a group
+var aggregateFunctions = map[string]bool{"COUNT": true, "SUM": true, "AVG": true, "MIN": true, "MAX": true}
+
+func (me *tAggregate) eval(ctx *tContext, env tEnv) interface{} {
+	if ctx.group == nil {
+		return ctx.fail("The aggregate function %s is not allowed here.", me.name)
+	}
+	group := ctx.group
+	ctx.group = nil
+	defer func() { ctx.group = group }()
+
+	var values []interface{}
+	for _, row := range group {
+		if value := me.arg.eval(ctx, row); value != undefined {
+			values = append(values, value)
+		}
+	}
+	switch me.name {
+	case "COUNT":
+		return float64(len(values))
+	case "SUM", "AVG":
+		sum := 0.0
+		for _, value := range values {
+			number, ok := toNumber(value)
+			if !ok {
+				return undefined
+			}
+			sum += number
+		}
+		if me.name == "SUM" {
+			return sum
+		}
+		if len(values) == 0 {
+			return undefined
+		}
+		return sum / float64(len(values))
+	}
+	if len(values) == 0 {
+		return undefined
+	}
+	result := values[0]
+	for _, value := range values[1:] {
+		order := compareOrder(value, result)
+		if (me.name == "MIN" && order < 0) || (me.name == "MAX" && order > 0) {
+			result = value
+		}
+	}
+	return result
+}
+
+func (me *tSubquery) eval(ctx *tContext, env tEnv) interface{} {
+	results, err := runQuery(ctx, me.query, env, nil)
+	if err != nil {
+		return ctx.fail("%s", err.Error())
+	}
+	switch me.kind {
+	case "ARRAY":
+		return results
+	case "EXISTS":
+		return len(results) > 0
+	}
+	if len(results) == 0 {
+		return undefined
+	}
+	return results[0]
+}
+
+/*
+runQuery - executes the query
+
+parameters:
+
+	outer - the bindings of the enclosing query, empty for the query of a request
+	documents - the documents of the collection for the query of a request, nil for a subquery
+
+returns the results like the Documents of the response
+*/
+func runQuery(ctx *tContext, query *tSelect, outer tEnv, documents []interface{}) (results []interface{}, err error) {
+	if query.star && query.from == nil {
+		return nil, fmt.Errorf("'SELECT *' is not valid if FROM clause is omitted.")
+	}
+	if query.star && len(query.joins) > 0 {
+		return nil, fmt.Errorf("'SELECT *' is only valid with a single input set.")
+	}
+	if len(query.group_by) > 0 && len(query.order_by) > 0 {
+		return nil, fmt.Errorf("ORDER BY is not supported in presence of GROUP BY.")
+	}
+
+	//FROM and JOIN
+	rows := []tEnv{outer}
+	if query.from != nil {
+		rows = nil
+		bind := func(env tEnv, from tFrom, value interface{}) {
+			if from.in {
+				if array, ok := value.([]interface{}); ok {
+					for _, item := range array {
+						rows = append(rows, env.with(from.alias, item))
+					}
+				}
+			} else if value != undefined {
+				rows = append(rows, env.with(from.alias, value))
+			}
+		}
+		if documents != nil {
+			root := rootName(query.from.source)
+			for _, document := range documents {
+				bind(outer, *query.from, query.from.source.eval(ctx, outer.with(root, document)))
+			}
+		} else {
+			bind(outer, *query.from, query.from.source.eval(ctx, outer))
+		}
+		for _, join := range query.joins {
+			input := rows
+			rows = nil
+			for _, row := range input {
+				bind(row, join, join.source.eval(ctx, row))
+			}
+		}
+	}
+
+	//WHERE
+	if query.where != nil {
+		input := rows
+		rows = nil
+		for _, row := range input {
+			if query.where.eval(ctx, row) == true {
+				rows = append(rows, row)
+			}
+		}
+	}
+	if ctx.err != nil {
+		return nil, ctx.err
+	}
+
+	project := func(env tEnv) {
+		var value interface{}
+		switch {
+		case query.star:
+			value = env[query.from.alias]
+		case query.value:
+			value = query.items[0].expr.eval(ctx, env)
+		default:
+			object := map[string]interface{}{}
+			unnamed := 0
+			for _, item := range query.items {
+				name := item.alias
+				if name == "" {
+					name = pathName(item.expr)
+				}
+				if name == "" {
+					unnamed += 1
+					name = fmt.Sprintf("$%d", unnamed)
+				}
+				if item_value := item.expr.eval(ctx, env); item_value != undefined {
+					object[name] = item_value
+				}
+			}
+			value = object
+		}
+		if value != undefined {
+			results = append(results, value)
+		}
+	}
+
+	if len(query.group_by) > 0 || query.aggregates {
+		//GROUP BY or aggregates over all rows
+		var keys []string
+		groups := map[string][]tEnv{}
+		if len(query.group_by) == 0 {
+			keys = []string{""}
+			groups[""] = rows
+		}
+		for _, row := range rows {
+			if len(query.group_by) == 0 {
+				break
+			}
+			values := make([]interface{}, len(query.group_by))
+			for i, expr := range query.group_by {
+				values[i] = expr.eval(ctx, row)
+				if values[i] == undefined {
+					values[i] = map[string]interface{}{"$undefined": true}
+				}
+			}
+			key := canonical(values)
+			if _, found := groups[key]; !found {
+				keys = append(keys, key)
+			}
+			groups[key] = append(groups[key], row)
+		}
+		for _, key := range keys {
+			group := groups[key]
+			if group == nil {
+				group = []tEnv{} //aggregates over no rows, i.e. COUNT 0
+			}
+			ctx.group = group
+			env := outer
+			if len(group) > 0 {
+				env = group[0]
+			}
+			project(env)
+			ctx.group = nil
+		}
+	} else {
+		//ORDER BY
+		if len(query.order_by) > 0 {
+			keys := make([][]interface{}, len(rows))
+			for i, row := range rows {
+				keys[i] = make([]interface{}, len(query.order_by))
+				for j, item := range query.order_by {
+					keys[i][j] = item.expr.eval(ctx, row)
+				}
+			}
+			index := make([]int, len(rows))
+			for i := range index {
+				index[i] = i
+			}
+			sort.SliceStable(index, func(a, b int) bool {
+				for j, item := range query.order_by {
+					order := compareOrder(keys[index[a]][j], keys[index[b]][j])
+					if item.descending {
+						order = -order
+					}
+					if order != 0 {
+						return order < 0
+					}
+				}
+				return false
+			})
+			sorted := make([]tEnv, len(rows))
+			for i, position := range index {
+				sorted[i] = rows[position]
+			}
+			rows = sorted
+		}
+		for _, row := range rows {
+			project(row)
+		}
+	}
+	if ctx.err != nil {
+		return nil, ctx.err
+	}
+
+	//DISTINCT, OFFSET LIMIT and TOP
+	if query.distinct {
+		seen := map[string]bool{}
+		input := results
+		results = nil
+		for _, value := range input {
+			key := canonical(value)
+			if !seen[key] {
+				seen[key] = true
+				results = append(results, value)
+			}
+		}
+	}
+	if query.offset != nil {
+		offset, ok_offset := toCount(query.offset.eval(ctx, outer))
+		limit, ok_limit := toCount(query.limit.eval(ctx, outer))
+		if !ok_offset || !ok_limit {
+			return nil, fmt.Errorf("The OFFSET and LIMIT values must be non-negative integers.")
+		}
+		results = window(results, offset, limit)
+	}
+	if query.top != nil {
+		top, ok := toCount(query.top.eval(ctx, outer))
+		if !ok {
+			return nil, fmt.Errorf("The TOP value must be a non-negative integer.")
+		}
+		results = window(results, 0, top)
+	}
+	if results == nil {
+		results = []interface{}{}
+	}
+	return results, ctx.err
+}
+
+// window - count values from offset
+func window(values []interface{}, offset int, count int) []interface{} {
+	if offset < 0 {
+		offset = 0
+	}
+	if offset > len(values) {
+		offset = len(values)
+	}
+	if count < 0 {
+		count = 0
+	}
+	end := len(values)
+	if count < end-offset {
+		end = offset + count
+	}
+	return values[offset:end]
+}
+
+// toCount - the value of TOP, OFFSET or LIMIT, a non-negative 32 bit integer like in cosmos db
+func toCount(value interface{}) (int, bool) {
+	n, ok := toNumber(value)
+	if !ok || n != math.Trunc(n) || n < 0 || n > math.MaxInt32 {
+		return 0, false
+	}
+	return int(n), true
+}
+
+// toNumber - the value as float64 if it is a number
+func toNumber(value interface{}) (float64, bool) {
+	switch n := value.(type) {
+	case float64:
+		return n, true
+	case int64:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// typeRank - the order of the types for ORDER BY, MIN and MAX
+func typeRank(value interface{}) int {
+	switch value.(type) {
+	case tUndefined:
+		return 0
+	case nil:
+		return 1
+	case bool:
+		return 2
+	case float64, int64, int, json.Number:
+		return 3
+	case string:
+		return 4
+	case []interface{}:
+		return 5
+	}
+	return 6
+}
+
+// compareValues - the order of two values of the same primitive type, false for different types
+func compareValues(a interface{}, b interface{}) (int, bool) {
+	rank := typeRank(a)
+	if rank != typeRank(b) || rank == 0 || rank > 4 {
+		return 0, false
+	}
+	return compareOrder(a, b), true
+}
+
+// compareOrder - the order of any two values, by type first
+func compareOrder(a interface{}, b interface{}) int {
+	rank_a, rank_b := typeRank(a), typeRank(b)
+	if rank_a != rank_b {
+		return rank_a - rank_b
+	}
+	switch rank_a {
+	case 2:
+		if a == b {
+			return 0
+		}
+		if a == false {
+			return -1
+		}
+		return 1
+	case 3:
+		x, _ := toNumber(a)
+		y, _ := toNumber(b)
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+	case 4:
+		return strings.Compare(a.(string), b.(string))
+	case 5, 6:
+		return strings.Compare(canonical(a), canonical(b))
+	}
+	return 0
+}
+
+// equalValues - deep equality of two values of the same type, false for different types
+func equalValues(a interface{}, b interface{}) (bool, bool) {
+	rank := typeRank(a)
+	if rank != typeRank(b) || rank == 0 {
+		return false, false
+	}
+	return compareOrder(a, b) == 0, true
+}
+
+// canonical - json with sorted keys, for DISTINCT and GROUP BY
+func canonical(value interface{}) string {
+	data, _ := json.Marshal(normalize(value))
+	return string(data)
+}
+
+// normalize - numbers as float64, so 1 and 1.0 are equal
+func normalize(value interface{}) interface{} {
+	switch v := value.(type) {
+	case []interface{}:
+		list := make([]interface{}, len(v))
+		for i, item := range v {
+			list[i] = normalize(item)
+		}
+		return list
+	case map[string]interface{}:
+		object := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			object[key] = normalize(item)
+		}
+		return object
+	}
+	if n, ok := toNumber(value); ok {
+		return n
+	}
+	return value
+}
@@ -0,0 +1,595 @@
+package cosmosfake
+
+import (
+	"encoding/json"
+	"math"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// tFunction - a built-in function, max_args -1 for any number of arguments
+type tFunction struct {
+	min_args int
+	max_args int
+	call     func(args []interface{}) interface{}
+}
+
+// builtinFunctions - the built-in functions by upper case name, set in init to allow the references to each other
+var builtinFunctions map[string]tFunction
+
+// mathFunction - a function of one number
+func mathFunction(f func(float64) float64) tFunction {
+	return tFunction{1, 1, func(args []interface{}) interface{} {
+		if n, ok := toNumber(args[0]); ok {
+			return checkNumber(f(n))
+		}
+		return undefined
+	}}
+}
+
+// mathFunction2 - a function of two numbers
+func mathFunction2(f func(float64, float64) float64) tFunction {
+	return tFunction{2, 2, func(args []interface{}) interface{} {
+		a, ok_a := toNumber(args[0])
+		b, ok_b := toNumber(args[1])
+		if ok_a && ok_b {
+			return checkNumber(f(a, b))
+		}
+		return undefined
+	}}
+}
+
+// checkNumber - NaN and infinity are undefined in json
+func checkNumber(n float64) interface{} {
+	if math.IsNaN(n) || math.IsInf(n, 0) {
+		return undefined
+	}
+	return n
+}
+
+// stringFunction - a function of one string
+func stringFunction(f func(string) interface{}) tFunction {
+	return tFunction{1, 1, func(args []interface{}) interface{} {
+		if s, ok := args[0].(string); ok {
+			return f(s)
+		}
+		return undefined
+	}}
+}
+
+// stringPredicate - a function of two strings and an optional ignore case flag
+func stringPredicate(f func(string, string) bool) tFunction {
+	return tFunction{2, 3, func(args []interface{}) interface{} {
+		a, ok_a := args[0].(string)
+		b, ok_b := args[1].(string)
+		if !ok_a || !ok_b {
+			return undefined
+		}
+		if len(args) > 2 && args[2] == true {
+			a, b = strings.ToLower(a), strings.ToLower(b)
+		}
+		return f(a, b)
+	}}
+}
+
+// typeFunction - a type check, true or false also for undefined
+func typeFunction(f func(interface{}) bool) tFunction {
+	return tFunction{1, 1, func(args []interface{}) interface{} {
+		return f(args[0])
+	}}
+}
+
+// parseFunction - StringToArray and others, the json of the string must have the type
+func parseFunction(rank int) tFunction {
+	return stringFunction(func(s string) interface{} {
+		var value interface{}
+		if json.Unmarshal([]byte(strings.TrimSpace(s)), &value) != nil || typeRank(value) != rank {
+			return undefined
+		}
+		return value
+	})
+}
+
+func init() {
+	builtinFunctions = map[string]tFunction{
+		//string functions
+		"CONCAT": {2, -1, func(args []interface{}) interface{} {
+			var result strings.Builder
+			for _, arg := range args {
+				s, ok := arg.(string)
+				if !ok {
+					return undefined
+				}
+				result.WriteString(s)
+			}
+			return result.String()
+		}},
+		"CONTAINS":     stringPredicate(strings.Contains),
+		"STARTSWITH":   stringPredicate(strings.HasPrefix),
+		"ENDSWITH":     stringPredicate(strings.HasSuffix),
+		"STRINGEQUALS": stringPredicate(func(a, b string) bool { return a == b }),
+		"INDEX_OF": {2, 3, func(args []interface{}) interface{} {
+			s, ok_s := args[0].(string)
+			sub, ok_sub := args[1].(string)
+			if !ok_s || !ok_sub {
+				return undefined
+			}
+			runes := []rune(s)
+			start := 0
+			if len(args) > 2 {
+				n, ok := toNumber(args[2])
+				if !ok {
+					return undefined
+				}
+				start = int(n)
+			}
+			if start < 0 || start > len(runes) {
+				return -1.0
+			}
+			index := strings.Index(string(runes[start:]), sub)
+			if index < 0 {
+				return -1.0
+			}
+			return float64(start + len([]rune(string(runes[start:])[:index])))
+		}},
+		"LEFT": {2, 2, func(args []interface{}) interface{} {
+			s, ok_s := args[0].(string)
+			n, ok_n := toNumber(args[1])
+			if !ok_s || !ok_n || n < 0 {
+				return undefined
+			}
+			runes := []rune(s)
+			return string(runes[:clampInt(n, 0, len(runes))])
+		}},
+		"RIGHT": {2, 2, func(args []interface{}) interface{} {
+			s, ok_s := args[0].(string)
+			n, ok_n := toNumber(args[1])
+			if !ok_s || !ok_n || n < 0 {
+				return undefined
+			}
+			runes := []rune(s)
+			return string(runes[len(runes)-clampInt(n, 0, len(runes)):])
+		}},
+		"SUBSTRING": {3, 3, func(args []interface{}) interface{} {
+			s, ok_s := args[0].(string)
+			start, ok_start := toNumber(args[1])
+			length, ok_length := toNumber(args[2])
+			if !ok_s || !ok_start || !ok_length {
+				return undefined
+			}
+			runes := []rune(s)
+			from := clampInt(start, 0, len(runes))
+			to := from + clampInt(length, 0, len(runes)-from)
+			return string(runes[from:to])
+		}},
+		"LENGTH": stringFunction(func(s string) interface{} { return float64(len([]rune(s))) }),
+		"LOWER":  stringFunction(func(s string) interface{} { return strings.ToLower(s) }),
+		"UPPER":  stringFunction(func(s string) interface{} { return strings.ToUpper(s) }),
+		"LTRIM":  stringFunction(func(s string) interface{} { return strings.TrimLeft(s, " \t\r\n") }),
+		"RTRIM":  stringFunction(func(s string) interface{} { return strings.TrimRight(s, " \t\r\n") }),
+		"TRIM":   stringFunction(func(s string) interface{} { return strings.Trim(s, " \t\r\n") }),
+		"REVERSE": stringFunction(func(s string) interface{} {
+			runes := []rune(s)
+			for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+				runes[i], runes[j] = runes[j], runes[i]
+			}
+			return string(runes)
+		}),
+		"REPLACE": {3, 3, func(args []interface{}) interface{} {
+			s, ok_s := args[0].(string)
+			old, ok_old := args[1].(string)
+			replacement, ok_new := args[2].(string)
+			if !ok_s || !ok_old || !ok_new {
+				return undefined
+			}
+			return strings.ReplaceAll(s, old, replacement)
+		}},
+		"REPLICATE": {2, 2, func(args []interface{}) interface{} {
+			s, ok_s := args[0].(string)
+			n, ok_n := toNumber(args[1])
+			if !ok_s || !ok_n || n < 0 || n > 10000 {
+				return undefined
+			}
+			return strings.Repeat(s, int(n))
+		}},
+		"TOSTRING": {1, 1, func(args []interface{}) interface{} {
+			switch value := args[0].(type) {
+			case tUndefined:
+				return undefined
+			case string:
+				return value
+			}
+			data, _ := json.Marshal(normalize(args[0]))
+			return string(data)
+		}},
+		"REGEXMATCH": {2, 3, func(args []interface{}) interface{} {
+			s, ok_s := args[0].(string)
+			pattern, ok_pattern := args[1].(string)
+			if !ok_s || !ok_pattern {
+				return undefined
+			}
+			if len(args) > 2 {
+				modifiers, _ := args[2].(string)
+				flags := ""
+				for _, modifier := range modifiers {
+					switch modifier {
+					case 'i', 'm', 's':
+						flags += string(modifier)
+					case 'x':
+						pattern = regexp.MustCompile(`\s+`).ReplaceAllString(pattern, "")
+					}
+				}
+				if flags != "" {
+					pattern = "(?" + flags + ")" + pattern
+				}
+			}
+			matcher, err := regexp.Compile(pattern)
+			if err != nil {
+				return undefined
+			}
+			return matcher.MatchString(s)
+		}},
+		"STRINGTOARRAY":   parseFunction(5),
+		"STRINGTOBOOLEAN": parseFunction(2),
+		"STRINGTONULL":    parseFunction(1),
+		"STRINGTONUMBER":  parseFunction(3),
+		"STRINGTOOBJECT":  parseFunction(6),
+
+		//math functions
+		"ABS":       mathFunction(math.Abs),
+		"ACOS":      mathFunction(math.Acos),
+		"ASIN":      mathFunction(math.Asin),
+		"ATAN":      mathFunction(math.Atan),
+		"ATN2":      mathFunction2(func(x, y float64) float64 { return math.Atan2(y, x) }),
+		"CEILING":   mathFunction(math.Ceil),
+		"COS":       mathFunction(math.Cos),
+		"COT":       mathFunction(func(x float64) float64 { return 1 / math.Tan(x) }),
+		"DEGREES":   mathFunction(func(x float64) float64 { return x * 180 / math.Pi }),
+		"EXP":       mathFunction(math.Exp),
+		"FLOOR":     mathFunction(math.Floor),
+		"LOG10":     mathFunction(math.Log10),
+		"POWER":     mathFunction2(math.Pow),
+		"RADIANS":   mathFunction(func(x float64) float64 { return x * math.Pi / 180 }),
+		"ROUND":     mathFunction(math.Round),
+		"SIN":       mathFunction(math.Sin),
+		"SQRT":      mathFunction(math.Sqrt),
+		"SQUARE":    mathFunction(func(x float64) float64 { return x * x }),
+		"TAN":       mathFunction(math.Tan),
+		"TRUNC":     mathFunction(math.Trunc),
+		"NUMBERBIN": mathFunction2(func(x, bin float64) float64 { return math.Floor(x/bin) * bin }),
+		"SIGN": mathFunction(func(x float64) float64 {
+			switch {
+			case x > 0:
+				return 1
+			case x < 0:
+				return -1
+			}
+			return 0
+		}),
+		"LOG": {1, 2, func(args []interface{}) interface{} {
+			x, ok := toNumber(args[0])
+			if !ok {
+				return undefined
+			}
+			if len(args) > 1 {
+				base, ok := toNumber(args[1])
+				if !ok {
+					return undefined
+				}
+				return checkNumber(math.Log(x) / math.Log(base))
+			}
+			return checkNumber(math.Log(x))
+		}},
+		"PI": {0, 0, func(args []interface{}) interface{} { return math.Pi }},
+
+		//array functions
+		"ARRAY_CONCAT": {2, -1, func(args []interface{}) interface{} {
+			result := []interface{}{}
+			for _, arg := range args {
+				array, ok := arg.([]interface{})
+				if !ok {
+					return undefined
+				}
+				result = append(result, array...)
+			}
+			return result
+		}},
+		"ARRAY_CONTAINS": {2, 3, func(args []interface{}) interface{} {
+			array, ok := args[0].([]interface{})
+			if !ok {
+				return undefined
+			}
+			partial := len(args) > 2 && args[2] == true
+			for _, item := range array {
+				if equal, ok := equalValues(item, args[1]); ok && equal {
+					return true
+				}
+				if partial && partialMatch(item, args[1]) {
+					return true
+				}
+			}
+			return false
+		}},
+		"ARRAY_LENGTH": {1, 1, func(args []interface{}) interface{} {
+			if array, ok := args[0].([]interface{}); ok {
+				return float64(len(array))
+			}
+			return undefined
+		}},
+		"ARRAY_SLICE": {2, 3, func(args []interface{}) interface{} {
+			array, ok_array := args[0].([]interface{})
+			start, ok_start := toNumber(args[1])
+			if !ok_array || !ok_start {
+				return undefined
+			}
+			from := clampInt(start, -len(array), len(array))
+			if from < 0 {
+				from += len(array)
+			}
+			to := len(array)
+			if len(args) > 2 {
+				length, ok := toNumber(args[2])
+				if !ok {
+					return undefined
+				}
+				to = from + clampInt(length, 0, len(array)-from)
+			}
+			return append([]interface{}{}, array[from:to]...)
+		}},
+		"SETINTERSECT": {2, 2, func(args []interface{}) interface{} {
+			a, ok_a := args[0].([]interface{})
+			b, ok_b := args[1].([]interface{})
+			if !ok_a || !ok_b {
+				return undefined
+			}
+			in_b := map[string]bool{}
+			for _, item := range b {
+				in_b[canonical(item)] = true
+			}
+			return distinctValues(a, func(key string) bool { return in_b[key] })
+		}},
+		"SETUNION": {2, 2, func(args []interface{}) interface{} {
+			a, ok_a := args[0].([]interface{})
+			b, ok_b := args[1].([]interface{})
+			if !ok_a || !ok_b {
+				return undefined
+			}
+			return distinctValues(append(append([]interface{}{}, a...), b...), func(string) bool { return true })
+		}},
+
+		//type checking functions
+		"IS_ARRAY":     typeFunction(func(v interface{}) bool { return typeRank(v) == 5 }),
+		"IS_BOOL":      typeFunction(func(v interface{}) bool { return typeRank(v) == 2 }),
+		"IS_DEFINED":   typeFunction(func(v interface{}) bool { return v != undefined }),
+		"IS_NULL":      typeFunction(func(v interface{}) bool { return v == nil }),
+		"IS_NUMBER":    typeFunction(func(v interface{}) bool { return typeRank(v) == 3 }),
+		"IS_OBJECT":    typeFunction(func(v interface{}) bool { return typeRank(v) == 6 }),
+		"IS_PRIMITIVE": typeFunction(func(v interface{}) bool { rank := typeRank(v); return rank >= 1 && rank <= 4 }),
+		"IS_STRING":    typeFunction(func(v interface{}) bool { return typeRank(v) == 4 }),
+
+		//conditional and date functions
+		"IIF": {3, 3, func(args []interface{}) interface{} {
+			if args[0] == true {
+				return args[1]
+			}
+			return args[2]
+		}},
+		"GETCURRENTDATETIME": {0, 0, func(args []interface{}) interface{} {
+			return time.Now().UTC().Format("2006-01-02T15:04:05.0000000Z")
+		}},
+		"GETCURRENTTIMESTAMP": {0, 0, func(args []interface{}) interface{} {
+			return float64(time.Now().UnixMilli())
+		}},
+
+		//spatial functions
+		"ST_DISTANCE": {2, 2, func(args []interface{}) interface{} {
+			lon_a, lat_a, ok_a := point(args[0])
+			lon_b, lat_b, ok_b := point(args[1])
+			if !ok_a || !ok_b {
+				return undefined
+			}
+			return haversine(lon_a, lat_a, lon_b, lat_b)
+		}},
+		"ST_WITHIN": {2, 2, func(args []interface{}) interface{} {
+			rings, ok := polygon(args[1])
+			if !ok {
+				return undefined
+			}
+			positions, ok := positionsOf(args[0])
+			if !ok {
+				return undefined
+			}
+			for _, position := range positions {
+				if !inPolygon(position, rings) {
+					return false
+				}
+			}
+			return true
+		}},
+		"ST_INTERSECTS": {2, 2, func(args []interface{}) interface{} {
+			return intersects(args[0], args[1])
+		}},
+		"ST_ISVALID": {1, 1, func(args []interface{}) interface{} {
+			if _, _, ok := point(args[0]); ok {
+				return true
+			}
+			_, ok := polygon(args[0])
+			return ok
+		}},
+	}
+}
+
+// clampInt - the number truncated to an int in [low, high], without overflow for large numbers
+func clampInt(n float64, low int, high int) int {
+	if !(n > float64(low)) { //NaN, too
+		return low
+	}
+	if n > float64(high) {
+		return high
+	}
+	return int(n)
+}
+
+// partialMatch - the object item holds all properties of the value
+func partialMatch(item interface{}, value interface{}) bool {
+	object, ok_object := item.(map[string]interface{})
+	pattern, ok_pattern := value.(map[string]interface{})
+	if !ok_object || !ok_pattern {
+		return false
+	}
+	for key, expected := range pattern {
+		if equal, ok := equalValues(object[key], expected); !ok || !equal {
+			return false
+		}
+	}
+	return true
+}
+
+// distinctValues - the values without duplicates, filtered by their canonical json
+func distinctValues(values []interface{}, keep func(key string) bool) []interface{} {
+	result := []interface{}{}
+	seen := map[string]bool{}
+	for _, value := range values {
+		key := canonical(value)
+		if !seen[key] && keep(key) {
+			seen[key] = true
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+// earthRadius - mean radius of the earth in meters
+const earthRadius = 6371008.8
+
+// point - longitude and latitude of a GeoJSON point
+func point(value interface{}) (float64, float64, bool) {
+	geometry, ok := value.(map[string]interface{})
+	if !ok || geometry["type"] != "Point" {
+		return 0, 0, false
+	}
+	position, ok := geoPosition(geometry["coordinates"])
+	return position[0], position[1], ok
+}
+
+// geoPosition - [longitude, latitude] in the valid ranges
+func geoPosition(value interface{}) ([2]float64, bool) {
+	coordinates, ok := value.([]interface{})
+	if !ok || len(coordinates) < 2 {
+		return [2]float64{}, false
+	}
+	lon, ok_lon := toNumber(coordinates[0])
+	lat, ok_lat := toNumber(coordinates[1])
+	if !ok_lon || !ok_lat || lon < -180 || lon > 180 || lat < -90 || lat > 90 {
+		return [2]float64{}, false
+	}
+	return [2]float64{lon, lat}, true
+}
+
+// polygon - the closed rings of a GeoJSON polygon, the first is the outer ring
+func polygon(value interface{}) ([][][2]float64, bool) {
+	geometry, ok := value.(map[string]interface{})
+	if !ok || geometry["type"] != "Polygon" {
+		return nil, false
+	}
+	list, ok := geometry["coordinates"].([]interface{})
+	if !ok || len(list) == 0 {
+		return nil, false
+	}
+	var rings [][][2]float64
+	for _, item := range list {
+		coordinates, ok := item.([]interface{})
+		if !ok || len(coordinates) < 4 {
+			return nil, false
+		}
+		var ring [][2]float64
+		for _, coordinate := range coordinates {
+			position, ok := geoPosition(coordinate)
+			if !ok {
+				return nil, false
+			}
+			ring = append(ring, position)
+		}
+		if ring[0] != ring[len(ring)-1] {
+			return nil, false
+		}
+		rings = append(rings, ring)
+	}
+	return rings, true
+}
+
+// positionsOf - the position of a point or the positions of the outer ring of a polygon
+func positionsOf(value interface{}) ([][2]float64, bool) {
+	if lon, lat, ok := point(value); ok {
+		return [][2]float64{{lon, lat}}, true
+	}
+	if rings, ok := polygon(value); ok {
+		return rings[0], true
+	}
+	return nil, false
+}
+
+// haversine - the distance of two positions in meters
+func haversine(lon_a float64, lat_a float64, lon_b float64, lat_b float64) float64 {
+	radians := math.Pi / 180
+	d_lat := (lat_b - lat_a) * radians
+	d_lon := (lon_b - lon_a) * radians
+	h := math.Pow(math.Sin(d_lat/2), 2) + math.Cos(lat_a*radians)*math.Cos(lat_b*radians)*math.Pow(math.Sin(d_lon/2), 2)
+	return 2 * earthRadius * math.Asin(math.Sqrt(h))
+}
+
+// inRing - ray casting in the plane of longitude and latitude
+func inRing(position [2]float64, ring [][2]float64) bool {
+	inside := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		a, b := ring[i], ring[j]
+		if (a[1] > position[1]) != (b[1] > position[1]) &&
+			position[0] < (b[0]-a[0])*(position[1]-a[1])/(b[1]-a[1])+a[0] {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// inPolygon - inside the outer ring and not inside a hole
+func inPolygon(position [2]float64, rings [][][2]float64) bool {
+	if !inRing(position, rings[0]) {
+		return false
+	}
+	for _, hole := range rings[1:] {
+		if inRing(position, hole) {
+			return false
+		}
+	}
+	return true
+}
+
+// intersects - a point or polygon intersects another, polygons if a position of one is inside the other
+func intersects(a interface{}, b interface{}) interface{} {
+	positions_a, ok_a := positionsOf(a)
+	positions_b, ok_b := positionsOf(b)
+	if !ok_a || !ok_b {
+		return undefined
+	}
+	rings_a, polygon_a := polygon(a)
+	rings_b, polygon_b := polygon(b)
+	if !polygon_a && !polygon_b {
+		return positions_a[0] == positions_b[0]
+	}
+	if polygon_b {
+		for _, position := range positions_a {
+			if inPolygon(position, rings_b) {
+				return true
+			}
+		}
+	}
+	if polygon_a {
+		for _, position := range positions_b {
+			if inPolygon(position, rings_a) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,60 @@
+package cosmosfake
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	cosmos "github.com/jankstar/cosmos_db_restapi"
+)
+
+// MemoryEndpointUri - the endpoint of a memory server, the requests never leave the process
+const MemoryEndpointUri = "http://cosmosfake.memory/"
+
+/*
+MemoryServerFactory - creates a fake server without listener, "" for the DefaultMasterKey
+
+the requests of the databases of Database are handled in the process, like
+
+	fake := cosmosfake.MemoryServerFactory("")
+	fake.CreateContainer("db", "user", "/tenant")
+	var database cosmos.DatabaseAPI = fake.Database("db")
+	container := database.Container("user", "a")
+*/
+func MemoryServerFactory(master_key string) *TServer {
+	me := newServer(master_key)
+	me.EndpointUri = MemoryEndpointUri
+	return me
+}
+
+// tInProcess - a http.RoundTripper which calls the handler of the server directly
+type tInProcess struct {
+	server *TServer
+}
+
+func (me tInProcess) RoundTrip(req *http.Request) (*http.Response, error) {
+	recorder := httptest.NewRecorder()
+	me.server.ServeHTTP(recorder, req)
+	res := recorder.Result()
+	res.Request = req
+	return res, nil
+}
+
+// Transport - a http.RoundTripper which serves the requests without network
+func (me *TServer) Transport() http.RoundTripper {
+	return tInProcess{server: me}
+}
+
+/*
+Database - the client of a database of the server with the in process Transport,
+the database is created if it does not exist
+
+returns:
+
+	the database, it implements cosmos.DatabaseAPI and its containers cosmos.ContainerAPI
+*/
+func (me *TServer) Database(database string) *cosmos.TDatabase {
+	me.CreateDatabase(database)
+	result := cosmos.DatabaseFactory(me.EndpointUri, me.MasterKey, database)
+	result.Transport = me.Transport()
+	return &result
+}
@@ -0,0 +1,117 @@
+package cosmosfake_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	cosmos "github.com/jankstar/cosmos_db_restapi"
+	"github.com/jankstar/cosmos_db_restapi/cosmosfake"
+)
+
+// stock - service code which depends only on the interface
+func stock(container cosmos.ContainerAPI, id string, delta int) (int, error) {
+	status, body := container.PatchDocument(id, []cosmos.TPatchOperation{{Op: "incr", Path: "/stock", Value: delta}})
+	if err := cosmos.ParseError(status, body); err != nil {
+		return 0, err
+	}
+	var document struct {
+		Stock int `json:"stock"`
+	}
+	err := json.Unmarshal([]byte(body), &document)
+	return document.Stock, err
+}
+
+func TestMemoryDatabase(t *testing.T) {
+	fake := cosmosfake.MemoryServerFactory("")
+	var database cosmos.DatabaseAPI = fake.Database("shop")
+	if status, _ := database.CreateContainer(cosmos.TContainerProperties{ID: "item", PartitionKey: &cosmos.TPartitionKeyDefinition{Paths: []string{"/tenant"}, Kind: "Hash"}}, 0); status != "201 Created" {
+		t.Fatalf("CreateContainer() = %v", status)
+	}
+	container := database.Container("item", "a")
+
+	if status, _ := container.CreateDocument(false, `{"id":"1","tenant":"a","stock":1,"tags":["x"]}`); status != "201 Created" {
+		t.Fatalf("CreateDocument() = %v", status)
+	}
+	if got, err := stock(container, "1", 2); got != 3 || err != nil {
+		t.Errorf("stock() = %v, %v", got, err)
+	}
+	if _, err := stock(container, "2", 2); err == nil || !strings.Contains(err.Error(), "not_found") {
+		t.Errorf("stock() missing = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		operations []cosmos.TPatchOperation
+		wantStatus string
+		wantBody   string
+	}{
+		{"set and add", []cosmos.TPatchOperation{{Op: "set", Path: "/name", Value: "Zwerg"}, {Op: "add", Path: "/tags/-", Value: "y"}},
+			"200 OK", `"tags":["x","y"]`},
+		{"insert into array", []cosmos.TPatchOperation{{Op: "add", Path: "/tags/0", Value: "w"}}, "200 OK", `"tags":["w","x","y"]`},
+		{"move", []cosmos.TPatchOperation{{Op: "move", From: "/name", Path: "/word"}}, "200 OK", `"word":"Zwerg"`},
+		{"remove", []cosmos.TPatchOperation{{Op: "remove", Path: "/tags/1"}}, "200 OK", `"tags":["w","y"]`},
+		{"replace missing", []cosmos.TPatchOperation{{Op: "replace", Path: "/name", Value: "Riese"}}, "400 Bad Request", ""},
+		{"partition key", []cosmos.TPatchOperation{{Op: "set", Path: "/tenant", Value: "b"}}, "400 Bad Request", ""},
+		{"incr of a string", []cosmos.TPatchOperation{{Op: "incr", Path: "/word", Value: 1}}, "400 Bad Request", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := container.PatchDocument("1", tt.operations)
+			if status != tt.wantStatus || !strings.Contains(body, tt.wantBody) {
+				t.Errorf("PatchDocument() = %v, %v", status, body)
+			}
+		})
+	}
+
+	status, body := container.ReplaceDocument("1", `{"id":"1","tenant":"a","stock":10}`)
+	if status != "200 OK" || strings.Contains(body, "tags") {
+		t.Errorf("ReplaceDocument() = %v, %v", status, body)
+	}
+
+	status, _, results := container.ExecuteBatch([]cosmos.TBatchOperation{
+		{OperationType: cosmos.OperationCreate, Data: `{"id":"2","tenant":"a"}`},
+		{OperationType: cosmos.OperationCreate, Data: `{"id":"1","tenant":"a"}`},
+	}, true)
+	if status != "409 Conflict" {
+		t.Errorf("ExecuteBatch() atomic = %v, %+v", status, results)
+	}
+	if status, _ := container.GetDocumentByID("2"); status != "404 Not Found" {
+		t.Errorf("GetDocumentByID() after rollback = %v", status)
+	}
+	status, _, results = container.ExecuteBatch([]cosmos.TBatchOperation{
+		{OperationType: cosmos.OperationCreate, Data: `{"id":"2","tenant":"a"}`},
+		{OperationType: cosmos.OperationCreate, Data: `{"id":"1","tenant":"a"}`},
+		{OperationType: cosmos.OperationPatch, ID: "1", Patch: []cosmos.TPatchOperation{{Op: "incr", Path: "/stock", Value: -1}}},
+		{OperationType: cosmos.OperationRead, ID: "1"},
+	}, false)
+	if status != "207 Multi-Status" || len(results) != 4 || results[0].StatusCode != http.StatusCreated ||
+		results[1].StatusCode != http.StatusConflict || !strings.Contains(results[3].Body, `"stock":9`) {
+		t.Errorf("ExecuteBatch() = %v, %+v", status, results)
+	}
+
+	container.OpenQuery(1, cosmos.TQuery{Query: "SELECT * FROM c ORDER BY c.id"})
+	pages := 0
+	for {
+		status, body := container.Fetch()
+		if status != "200 OK" {
+			break
+		}
+		if pages += 1; pages > 3 || !strings.Contains(body, `"_count":1`) {
+			t.Fatalf("Fetch() = %v, %v", status, body)
+		}
+	}
+	if pages != 2 {
+		t.Errorf("Fetch() pages = %v", pages)
+	}
+
+	status, body, _ = container.ReadChangeFeed(cosmos.ChangeFeedLatestVersion, "", "", 0)
+	if status != "200 OK" || !strings.Contains(body, `"_count":2`) {
+		t.Errorf("ReadChangeFeed() = %v, %v", status, body)
+	}
+	if status, _ := database.DeleteContainer("item"); status != "204 No Content" {
+		t.Errorf("DeleteContainer() = %v", status)
+	}
+	fake.Close()
+}
@@ -0,0 +1,270 @@
+package cosmosfake
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// MaxPatchOperations - the maximum number of operations of one patch request
+const MaxPatchOperations = 10
+
+// tPatchOperation - an operation of the body of a patch request
+type tPatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value"`
+	From  string      `json:"from"`
+}
+
+// tPatch - the body of a patch request, the condition is i.e. "from c where c.stock > 0"
+type tPatch struct {
+	Operations []tPatchOperation `json:"operations"`
+	Condition  string            `json:"condition"`
+}
+
+// patchItem - applies the operations of the body to a copy of the document and replaces it
+func (me *TServer) patchItem(collection *tCollection, partition_key string, id string, body []byte, if_match string) tResponse {
+	var patch tPatch
+	if err := json.Unmarshal(body, &patch); err != nil || len(patch.Operations) == 0 {
+		return errorResponse(http.StatusBadRequest, "The patch request payload is invalid, it needs operations.")
+	}
+	if len(patch.Operations) > MaxPatchOperations {
+		return errorResponse(http.StatusBadRequest, "The number of patch operations can't exceed %d.", MaxPatchOperations)
+	}
+	document := collection.documents[partition_key+"\x00"+id]
+	if document == nil {
+		return collection.withSession(notFound())
+	}
+	if res := preconditionFailed(if_match, document.body["_etag"].(string)); res != nil {
+		return *res
+	}
+	if patch.Condition != "" {
+		query, err := parseQuery("SELECT * " + patch.Condition)
+		if err != nil {
+			return errorResponse(http.StatusBadRequest, "The patch condition is invalid: %s", err.Error())
+		}
+		list, err := runQuery(&tContext{parameters: map[string]interface{}{}}, query, tEnv{}, []interface{}{document.body})
+		if err != nil {
+			return errorResponse(http.StatusBadRequest, "%s", err.Error())
+		}
+		if len(list) == 0 {
+			return errorResponse(http.StatusPreconditionFailed, "One of the specified pre-condition is not met.")
+		}
+	}
+
+	patched := copyValue(document.body).(map[string]interface{})
+	for _, operation := range patch.Operations {
+		if res := collection.checkPatchPath(operation.Path); res != nil {
+			return *res
+		}
+		if err := applyPatch(patched, operation); err != nil {
+			return errorResponse(http.StatusBadRequest, "%s", err.Error())
+		}
+	}
+	if collection.violatesUniqueKey(partition_key, patched) {
+		return errorResponse(http.StatusConflict, "Unique index constraint violation.")
+	}
+	return me.writeDocument(collection, partition_key, patched, document, http.StatusOK)
+}
+
+// checkPatchPath - the id, the partition key and the system properties can not be patched
+func (me *tCollection) checkPatchPath(path string) *tResponse {
+	if !strings.HasPrefix(path, "/") || path == "/" {
+		res := errorResponse(http.StatusBadRequest, "The patch path %q is invalid.", path)
+		return &res
+	}
+	field := strings.Split(path, "/")[1]
+	if field == "id" || strings.HasPrefix(field, "_") {
+		res := errorResponse(http.StatusBadRequest, "The patch path %q can not be changed.", path)
+		return &res
+	}
+	for _, key := range me.partition_key {
+		if path == key || strings.HasPrefix(path, key+"/") || strings.HasPrefix(key, path+"/") {
+			res := errorResponse(http.StatusBadRequest, "Replacing or adding the partition key %q is not allowed.", key)
+			return &res
+		}
+	}
+	return nil
+}
+
+// applyPatch - applies one operation to the document
+func applyPatch(document map[string]interface{}, operation tPatchOperation) error {
+	segments := strings.Split(operation.Path, "/")[1:]
+	var err error
+	switch operation.Op {
+	case "add", "set", "replace", "remove", "incr":
+		_, err = patchPath(document, segments, func(parent interface{}, key string) (interface{}, error) {
+			return patchLeaf(parent, key, operation.Op, operation.Value)
+		})
+	case "move":
+		if !strings.HasPrefix(operation.From, "/") || strings.HasPrefix(operation.Path, operation.From+"/") {
+			return fmt.Errorf("the patch from %q is invalid for the path %q", operation.From, operation.Path)
+		}
+		var value interface{}
+		_, err = patchPath(document, strings.Split(operation.From, "/")[1:], func(parent interface{}, key string) (interface{}, error) {
+			value, _ = leafValue(parent, key)
+			return patchLeaf(parent, key, "remove", nil)
+		})
+		if err == nil {
+			_, err = patchPath(document, segments, func(parent interface{}, key string) (interface{}, error) {
+				return patchLeaf(parent, key, "set", value)
+			})
+		}
+	default:
+		err = fmt.Errorf("the patch operation %q is not supported", operation.Op)
+	}
+	if err != nil {
+		return fmt.Errorf("%s of %s: %w", operation.Op, operation.Path, err)
+	}
+	return nil
+}
+
+// patchPath - changes the parent of the last segment and returns the changed container
+func patchPath(container interface{}, segments []string, change func(parent interface{}, key string) (interface{}, error)) (interface{}, error) {
+	if len(segments) == 1 {
+		return change(container, segments[0])
+	}
+	switch node := container.(type) {
+	case map[string]interface{}:
+		child, found := node[segments[0]]
+		if !found {
+			return nil, fmt.Errorf("the path does not exist")
+		}
+		changed, err := patchPath(child, segments[1:], change)
+		if err != nil {
+			return nil, err
+		}
+		node[segments[0]] = changed
+		return node, nil
+	case []interface{}:
+		index, err := arrayIndex(segments[0], len(node)-1)
+		if err != nil {
+			return nil, err
+		}
+		changed, err := patchPath(node[index], segments[1:], change)
+		if err != nil {
+			return nil, err
+		}
+		node[index] = changed
+		return node, nil
+	}
+	return nil, fmt.Errorf("the path does not exist")
+}
+
+// patchLeaf - applies the operation to the key of an object or the index of an array
+func patchLeaf(parent interface{}, key string, op string, value interface{}) (interface{}, error) {
+	switch node := parent.(type) {
+	case map[string]interface{}:
+		existing, found := node[key]
+		switch op {
+		case "add", "set":
+			node[key] = value
+		case "replace":
+			if !found {
+				return nil, fmt.Errorf("the path does not exist")
+			}
+			node[key] = value
+		case "remove":
+			if !found {
+				return nil, fmt.Errorf("the path does not exist")
+			}
+			delete(node, key)
+		case "incr":
+			sum, err := increment(existing, found, value)
+			if err != nil {
+				return nil, err
+			}
+			node[key] = sum
+		}
+		return node, nil
+	case []interface{}:
+		if key == "-" && (op == "add" || op == "set") {
+			return append(node, value), nil
+		}
+		last := len(node) - 1
+		if op == "add" || op == "set" {
+			last = len(node)
+		}
+		index, err := arrayIndex(key, last)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case op == "add" || (op == "set" && index == len(node)):
+			return append(node[:index], append([]interface{}{value}, node[index:]...)...), nil
+		case op == "set" || op == "replace":
+			node[index] = value
+		case op == "remove":
+			return append(node[:index], node[index+1:]...), nil
+		case op == "incr":
+			sum, err := increment(node[index], true, value)
+			if err != nil {
+				return nil, err
+			}
+			node[index] = sum
+		}
+		return node, nil
+	}
+	return nil, fmt.Errorf("the parent of the path is not an object or array")
+}
+
+// leafValue - the value of the key of an object or the index of an array
+func leafValue(parent interface{}, key string) (interface{}, bool) {
+	switch node := parent.(type) {
+	case map[string]interface{}:
+		value, found := node[key]
+		return value, found
+	case []interface{}:
+		if index, err := arrayIndex(key, len(node)-1); err == nil {
+			return node[index], true
+		}
+	}
+	return nil, false
+}
+
+// arrayIndex - the index of the segment, at most last
+func arrayIndex(segment string, last int) (int, error) {
+	index, err := strconv.Atoi(segment)
+	if err != nil || index < 0 || index > last {
+		return 0, fmt.Errorf("the array index %q is out of range", segment)
+	}
+	return index, nil
+}
+
+// increment - the sum of a number and the value, a missing number is the value
+func increment(existing interface{}, found bool, value interface{}) (interface{}, error) {
+	delta, ok := toNumber(value)
+	if !ok {
+		return nil, fmt.Errorf("the value of incr must be a number")
+	}
+	if !found {
+		return delta, nil
+	}
+	number, ok := toNumber(existing)
+	if !ok {
+		return nil, fmt.Errorf("the value of the path is not a number")
+	}
+	return number + delta, nil
+}
+
+// copyValue - a deep copy of maps and arrays, the documents are not changed in place
+func copyValue(value interface{}) interface{} {
+	switch node := value.(type) {
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(node))
+		for key, child := range node {
+			result[key] = copyValue(child)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(node))
+		for i, child := range node {
+			result[i] = copyValue(child)
+		}
+		return result
+	}
+	return value
+}
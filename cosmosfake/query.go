@@ -0,0 +1,710 @@
+package cosmosfake
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// token kinds of the query lexer
+const (
+	tokenEOF = iota
+	tokenIdent
+	tokenNumber
+	tokenString
+	tokenParameter
+	tokenSymbol
+)
+
+type tToken struct {
+	kind   int
+	text   string //the identifier, symbol, parameter name or the unquoted string
+	number float64
+	pos    int
+}
+
+// tSyntaxError - a query that can not be parsed, the message is like cosmos db
+type tSyntaxError struct {
+	message string
+}
+
+func (me tSyntaxError) Error() string {
+	return me.message
+}
+
+// symbols of the query language, the longest first
+var symbols = []string{"!=", "<>", "<=", ">=", "||", "??", "(", ")", "[", "]", "{", "}", ",", ".", ":", "*", "+", "-", "/", "%", "=", "<", ">", "?", "~", "&", "|", "^"}
+
+// lex - splits the query into tokens
+func lex(text string) ([]tToken, error) {
+	var tokens []tToken
+	runes := []rune(text)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i += 1
+		case r == '-' && i+1 < len(runes) && runes[i+1] == '-':
+			for i < len(runes) && runes[i] != '\n' {
+				i += 1
+			}
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_') {
+				i += 1
+			}
+			tokens = append(tokens, tToken{kind: tokenIdent, text: string(runes[start:i]), pos: start})
+		case r == '@':
+			start := i
+			i += 1
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_') {
+				i += 1
+			}
+			tokens = append(tokens, tToken{kind: tokenParameter, text: string(runes[start:i]), pos: start})
+		case unicode.IsDigit(r) || (r == '.' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])):
+			start := i
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i += 1
+			}
+			if i < len(runes) && (runes[i] == 'e' || runes[i] == 'E') {
+				i += 1
+				if i < len(runes) && (runes[i] == '+' || runes[i] == '-') {
+					i += 1
+				}
+				for i < len(runes) && unicode.IsDigit(runes[i]) {
+					i += 1
+				}
+			}
+			number, err := strconv.ParseFloat(string(runes[start:i]), 64)
+			if err != nil {
+				return nil, tSyntaxError{fmt.Sprintf("Syntax error, invalid numeric value token '%s'.", string(runes[start:i]))}
+			}
+			tokens = append(tokens, tToken{kind: tokenNumber, text: string(runes[start:i]), number: number, pos: start})
+		case r == '\'' || r == '"':
+			start := i
+			var value strings.Builder
+			for i += 1; ; i += 1 {
+				if i >= len(runes) {
+					return nil, tSyntaxError{"Syntax error, unclosed string literal."}
+				}
+				if runes[i] == r {
+					i += 1
+					break
+				}
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i += 1
+					switch runes[i] {
+					case 'n':
+						value.WriteRune('\n')
+					case 't':
+						value.WriteRune('\t')
+					case 'r':
+						value.WriteRune('\r')
+					case 'b':
+						value.WriteRune('\b')
+					case 'f':
+						value.WriteRune('\f')
+					case 'u':
+						if i+4 < len(runes) {
+							if code, err := strconv.ParseUint(string(runes[i+1:i+5]), 16, 32); err == nil {
+								value.WriteRune(rune(code))
+								i += 4
+								continue
+							}
+						}
+						value.WriteRune('u')
+					default:
+						value.WriteRune(runes[i])
+					}
+					continue
+				}
+				value.WriteRune(runes[i])
+			}
+			tokens = append(tokens, tToken{kind: tokenString, text: value.String(), pos: start})
+		default:
+			found := false
+			for _, symbol := range symbols {
+				if strings.HasPrefix(string(runes[i:minInt(i+2, len(runes))]), symbol) {
+					tokens = append(tokens, tToken{kind: tokenSymbol, text: symbol, pos: i})
+					i += len([]rune(symbol))
+					found = true
+					break
+				}
+			}
+			if !found {
+				return nil, tSyntaxError{fmt.Sprintf("Syntax error, invalid token '%c'.", r)}
+			}
+		}
+	}
+	return append(tokens, tToken{kind: tokenEOF, pos: len(runes)}), nil
+}
+
+func minInt(a int, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// keywords - identifiers which can not be used as alias
+var keywords = map[string]bool{
+	"SELECT": true, "FROM": true, "WHERE": true, "JOIN": true, "IN": true, "AS": true, "AND": true, "OR": true,
+	"NOT": true, "BETWEEN": true, "LIKE": true, "ESCAPE": true, "ORDER": true, "GROUP": true, "BY": true, "OFFSET": true,
+	"LIMIT": true, "TOP": true, "DISTINCT": true, "VALUE": true, "ASC": true, "DESC": true, "EXISTS": true,
+	"ARRAY": true, "TRUE": true, "FALSE": true, "NULL": true, "UNDEFINED": true, "UDF": true,
+}
+
+// tSelect - a parsed query or subquery
+type tSelect struct {
+	distinct   bool
+	top        tExpr
+	value      bool //SELECT VALUE
+	star       bool //SELECT *
+	items      []tSelectItem
+	from       *tFrom
+	joins      []tFrom
+	where      tExpr
+	group_by   []tExpr
+	order_by   []tOrderItem
+	offset     tExpr
+	limit      tExpr
+	aggregates bool //the selection holds aggregate functions
+}
+
+type tSelectItem struct {
+	expr  tExpr
+	alias string
+}
+
+// tFrom - the source of FROM or JOIN, "alias IN source" iterates the array of the source
+type tFrom struct {
+	alias  string
+	in     bool
+	source tExpr
+}
+
+type tOrderItem struct {
+	expr       tExpr
+	descending bool
+}
+
+type tParser struct {
+	tokens     []tToken
+	position   int
+	aggregates int //aggregates parsed in the current selection
+}
+
+// parseQuery - parses a cosmos db sql query
+func parseQuery(text string) (query *tSelect, err error) {
+	tokens, err := lex(text)
+	if err != nil {
+		return nil, err
+	}
+	parser := &tParser{tokens: tokens}
+	defer func() {
+		if r := recover(); r != nil {
+			syntax_error, ok := r.(tSyntaxError)
+			if !ok {
+				panic(r)
+			}
+			query, err = nil, syntax_error
+		}
+	}()
+	query = parser.parseSelect()
+	if parser.peek().kind != tokenEOF {
+		parser.fail()
+	}
+	return query, nil
+}
+
+func (me *tParser) peek() tToken {
+	return me.tokens[me.position]
+}
+
+func (me *tParser) next() tToken {
+	token := me.tokens[me.position]
+	if token.kind != tokenEOF {
+		me.position += 1
+	}
+	return token
+}
+
+// fail - a syntax error at the current token
+func (me *tParser) fail() {
+	token := me.peek()
+	if token.kind == tokenEOF {
+		panic(tSyntaxError{"Syntax error, unexpected end-of-file."})
+	}
+	panic(tSyntaxError{fmt.Sprintf("Syntax error, incorrect syntax near '%s'.", token.text)})
+}
+
+// isKeyword - the current token is the keyword, in any case
+func (me *tParser) isKeyword(keyword string) bool {
+	token := me.peek()
+	return token.kind == tokenIdent && strings.EqualFold(token.text, keyword)
+}
+
+// acceptKeyword - skips the keyword if it is the current token
+func (me *tParser) acceptKeyword(keyword string) bool {
+	if me.isKeyword(keyword) {
+		me.next()
+		return true
+	}
+	return false
+}
+
+func (me *tParser) expectKeyword(keyword string) {
+	if !me.acceptKeyword(keyword) {
+		me.fail()
+	}
+}
+
+func (me *tParser) isSymbol(symbol string) bool {
+	token := me.peek()
+	return token.kind == tokenSymbol && token.text == symbol
+}
+
+func (me *tParser) acceptSymbol(symbol string) bool {
+	if me.isSymbol(symbol) {
+		me.next()
+		return true
+	}
+	return false
+}
+
+func (me *tParser) expectSymbol(symbol string) {
+	if !me.acceptSymbol(symbol) {
+		me.fail()
+	}
+}
+
+// identifier - an identifier which is not a keyword
+func (me *tParser) identifier() string {
+	token := me.peek()
+	if token.kind != tokenIdent || keywords[strings.ToUpper(token.text)] {
+		me.fail()
+	}
+	me.next()
+	return token.text
+}
+
+// optionalAlias - "AS alias" or "alias", else ""
+func (me *tParser) optionalAlias() string {
+	if me.acceptKeyword("AS") {
+		return me.identifier()
+	}
+	if token := me.peek(); token.kind == tokenIdent && !keywords[strings.ToUpper(token.text)] {
+		return me.identifier()
+	}
+	return ""
+}
+
+func (me *tParser) parseSelect() *tSelect {
+	aggregates := me.aggregates
+	me.aggregates = 0
+	defer func() { me.aggregates = aggregates }()
+
+	query := &tSelect{}
+	me.expectKeyword("SELECT")
+	query.distinct = me.acceptKeyword("DISTINCT")
+	if me.acceptKeyword("TOP") {
+		query.top = me.parsePrimary()
+	}
+	switch {
+	case me.acceptSymbol("*"):
+		query.star = true
+	case me.acceptKeyword("VALUE"):
+		query.value = true
+		query.items = []tSelectItem{{expr: me.parseExpression()}}
+	default:
+		for {
+			item := tSelectItem{expr: me.parseExpression()}
+			item.alias = me.optionalAlias()
+			query.items = append(query.items, item)
+			if !me.acceptSymbol(",") {
+				break
+			}
+		}
+	}
+	query.aggregates = me.aggregates > 0
+
+	if me.acceptKeyword("FROM") {
+		from := me.parseFrom()
+		query.from = &from
+		for me.acceptKeyword("JOIN") {
+			query.joins = append(query.joins, me.parseFrom())
+		}
+	}
+	if me.acceptKeyword("WHERE") {
+		query.where = me.parseExpression()
+	}
+	if me.acceptKeyword("GROUP") {
+		me.expectKeyword("BY")
+		for {
+			query.group_by = append(query.group_by, me.parseExpression())
+			if !me.acceptSymbol(",") {
+				break
+			}
+		}
+	}
+	if me.acceptKeyword("ORDER") {
+		me.expectKeyword("BY")
+		for {
+			item := tOrderItem{expr: me.parseExpression()}
+			if me.acceptKeyword("DESC") {
+				item.descending = true
+			} else {
+				me.acceptKeyword("ASC")
+			}
+			query.order_by = append(query.order_by, item)
+			if !me.acceptSymbol(",") {
+				break
+			}
+		}
+	}
+	if me.acceptKeyword("OFFSET") {
+		query.offset = me.parsePrimary()
+		me.expectKeyword("LIMIT")
+		query.limit = me.parsePrimary()
+	}
+	return query
+}
+
+// parseFrom - "source [AS] alias" or "alias IN source"
+func (me *tParser) parseFrom() (from tFrom) {
+	if token := me.peek(); token.kind == tokenIdent && !keywords[strings.ToUpper(token.text)] {
+		next := me.tokens[me.position+1]
+		if next.kind == tokenIdent && strings.EqualFold(next.text, "IN") {
+			from.alias = me.identifier()
+			me.next()
+			from.in = true
+			from.source = me.parsePostfix()
+			return
+		}
+	}
+	from.source = me.parsePostfix()
+	from.alias = me.optionalAlias()
+	if from.alias == "" {
+		from.alias = pathName(from.source)
+		if from.alias == "" {
+			panic(tSyntaxError{"A FROM or JOIN source without an identifier requires an alias."})
+		}
+	}
+	return
+}
+
+// parseExpression - the lowest precedence, the conditional operator
+func (me *tParser) parseExpression() tExpr {
+	condition := me.parseCoalesce()
+	if me.acceptSymbol("?") {
+		then := me.parseExpression()
+		me.expectSymbol(":")
+		return &tConditional{condition: condition, then: then, otherwise: me.parseExpression()}
+	}
+	return condition
+}
+
+func (me *tParser) parseCoalesce() tExpr {
+	left := me.parseOr()
+	for me.acceptSymbol("??") {
+		left = &tBinary{op: "??", left: left, right: me.parseOr()}
+	}
+	return left
+}
+
+func (me *tParser) parseOr() tExpr {
+	left := me.parseAnd()
+	for me.acceptKeyword("OR") {
+		left = &tBinary{op: "OR", left: left, right: me.parseAnd()}
+	}
+	return left
+}
+
+func (me *tParser) parseAnd() tExpr {
+	left := me.parseNot()
+	for me.acceptKeyword("AND") {
+		left = &tBinary{op: "AND", left: left, right: me.parseNot()}
+	}
+	return left
+}
+
+func (me *tParser) parseNot() tExpr {
+	if me.acceptKeyword("NOT") {
+		return &tUnary{op: "NOT", operand: me.parseNot()}
+	}
+	return me.parseComparison()
+}
+
+func (me *tParser) parseComparison() tExpr {
+	left := me.parseBitwise()
+	for {
+		token := me.peek()
+		switch {
+		case token.kind == tokenSymbol && (token.text == "=" || token.text == "!=" || token.text == "<>" ||
+			token.text == "<" || token.text == "<=" || token.text == ">" || token.text == ">="):
+			me.next()
+			op := token.text
+			if op == "<>" {
+				op = "!="
+			}
+			left = &tBinary{op: op, left: left, right: me.parseBitwise()}
+			continue
+		}
+
+		not := false
+		if me.isKeyword("NOT") {
+			next := me.tokens[me.position+1]
+			if next.kind != tokenIdent || !(strings.EqualFold(next.text, "IN") || strings.EqualFold(next.text, "LIKE") || strings.EqualFold(next.text, "BETWEEN")) {
+				return left
+			}
+			me.next()
+			not = true
+		}
+		switch {
+		case me.acceptKeyword("IN"):
+			me.expectSymbol("(")
+			in := &tIn{value: left, not: not}
+			for {
+				in.list = append(in.list, me.parseExpression())
+				if !me.acceptSymbol(",") {
+					break
+				}
+			}
+			me.expectSymbol(")")
+			left = in
+		case me.acceptKeyword("BETWEEN"):
+			between := &tBetween{value: left, not: not, low: me.parseBitwise()}
+			me.expectKeyword("AND")
+			between.high = me.parseBitwise()
+			left = between
+		case me.acceptKeyword("LIKE"):
+			like := &tLike{value: left, not: not, pattern: me.parseBitwise()}
+			if me.acceptKeyword("ESCAPE") {
+				like.escape = me.parseBitwise()
+			}
+			left = like
+		default:
+			return left
+		}
+	}
+}
+
+func (me *tParser) parseBitwise() tExpr {
+	left := me.parseConcat()
+	for me.isSymbol("&") || me.isSymbol("|") || me.isSymbol("^") {
+		op := me.next().text
+		left = &tBinary{op: op, left: left, right: me.parseConcat()}
+	}
+	return left
+}
+
+func (me *tParser) parseConcat() tExpr {
+	left := me.parseAdditive()
+	for me.acceptSymbol("||") {
+		left = &tBinary{op: "||", left: left, right: me.parseAdditive()}
+	}
+	return left
+}
+
+func (me *tParser) parseAdditive() tExpr {
+	left := me.parseMultiplicative()
+	for me.isSymbol("+") || me.isSymbol("-") {
+		op := me.next().text
+		left = &tBinary{op: op, left: left, right: me.parseMultiplicative()}
+	}
+	return left
+}
+
+func (me *tParser) parseMultiplicative() tExpr {
+	left := me.parseUnary()
+	for me.isSymbol("*") || me.isSymbol("/") || me.isSymbol("%") {
+		op := me.next().text
+		left = &tBinary{op: op, left: left, right: me.parseUnary()}
+	}
+	return left
+}
+
+func (me *tParser) parseUnary() tExpr {
+	if me.isSymbol("-") || me.isSymbol("+") || me.isSymbol("~") {
+		op := me.next().text
+		return &tUnary{op: op, operand: me.parseUnary()}
+	}
+	return me.parsePostfix()
+}
+
+// parsePostfix - property access with "." and "[]"
+func (me *tParser) parsePostfix() tExpr {
+	expr := me.parsePrimary()
+	for {
+		switch {
+		case me.acceptSymbol("."):
+			token := me.next()
+			if token.kind != tokenIdent {
+				me.position -= 1
+				me.fail()
+			}
+			expr = &tProperty{object: expr, key: &tLiteral{value: token.text}}
+		case me.acceptSymbol("["):
+			key := me.parseExpression()
+			me.expectSymbol("]")
+			expr = &tProperty{object: expr, key: key}
+		default:
+			return expr
+		}
+	}
+}
+
+func (me *tParser) parsePrimary() tExpr {
+	token := me.peek()
+	switch token.kind {
+	case tokenNumber:
+		me.next()
+		return &tLiteral{value: token.number}
+	case tokenString:
+		me.next()
+		return &tLiteral{value: token.text}
+	case tokenParameter:
+		me.next()
+		return &tParameterRef{name: token.text}
+	case tokenSymbol:
+		switch token.text {
+		case "(":
+			me.next()
+			var expr tExpr
+			if me.isKeyword("SELECT") {
+				expr = &tSubquery{query: me.parseSelect()}
+			} else {
+				expr = me.parseExpression()
+			}
+			me.expectSymbol(")")
+			return expr
+		case "[":
+			me.next()
+			array := &tArrayLiteral{}
+			for !me.isSymbol("]") {
+				array.items = append(array.items, me.parseExpression())
+				if !me.acceptSymbol(",") {
+					break
+				}
+			}
+			me.expectSymbol("]")
+			return array
+		case "{":
+			me.next()
+			object := &tObjectLiteral{}
+			for !me.isSymbol("}") {
+				key := me.next()
+				if key.kind != tokenIdent && key.kind != tokenString {
+					me.position -= 1
+					me.fail()
+				}
+				me.expectSymbol(":")
+				object.keys = append(object.keys, key.text)
+				object.values = append(object.values, me.parseExpression())
+				if !me.acceptSymbol(",") {
+					break
+				}
+			}
+			me.expectSymbol("}")
+			return object
+		}
+	case tokenIdent:
+		name := strings.ToUpper(token.text)
+		switch name {
+		case "TRUE", "FALSE":
+			me.next()
+			return &tLiteral{value: name == "TRUE"}
+		case "NULL":
+			me.next()
+			return &tLiteral{value: nil}
+		case "UNDEFINED":
+			me.next()
+			return &tLiteral{value: undefined}
+		case "EXISTS", "ARRAY":
+			me.next()
+			me.expectSymbol("(")
+			subquery := &tSubquery{query: me.parseSelect(), kind: name}
+			me.expectSymbol(")")
+			return subquery
+		case "UDF":
+			me.next()
+			me.expectSymbol(".")
+			function := me.next()
+			if function.kind != tokenIdent {
+				me.position -= 1
+				me.fail()
+			}
+			return &tCall{name: function.text, udf: true, args: me.parseArguments()}
+		}
+		if keywords[name] {
+			me.fail()
+		}
+		me.next()
+		if me.isSymbol("(") {
+			return me.parseFunction(token.text)
+		}
+		return &tIdentifier{name: token.text}
+	}
+	me.fail()
+	return nil
+}
+
+func (me *tParser) parseArguments() (args []tExpr) {
+	me.expectSymbol("(")
+	for !me.isSymbol(")") {
+		args = append(args, me.parseExpression())
+		if !me.acceptSymbol(",") {
+			break
+		}
+	}
+	me.expectSymbol(")")
+	return
+}
+
+// parseFunction - a built-in or aggregate function
+func (me *tParser) parseFunction(name string) tExpr {
+	upper := strings.ToUpper(name)
+	args := me.parseArguments()
+	if aggregateFunctions[upper] {
+		if len(args) != 1 {
+			panic(tSyntaxError{fmt.Sprintf("The %s function requires 1 argument(s).", upper)})
+		}
+		me.aggregates += 1
+		return &tAggregate{name: upper, arg: args[0]}
+	}
+	function, found := builtinFunctions[upper]
+	if !found {
+		panic(tSyntaxError{fmt.Sprintf("'%s' is not a recognized built-in function name.", name)})
+	}
+	if len(args) < function.min_args || (function.max_args >= 0 && len(args) > function.max_args) {
+		panic(tSyntaxError{fmt.Sprintf("The %s function requires %d argument(s).", upper, function.min_args)})
+	}
+	return &tCall{name: upper, args: args}
+}
+
+// pathName - the name of the last property of a path like c.address.city, else ""
+func pathName(expr tExpr) string {
+	switch e := expr.(type) {
+	case *tIdentifier:
+		return e.name
+	case *tProperty:
+		if key, ok := e.key.(*tLiteral); ok {
+			if name, ok := key.value.(string); ok {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+// rootName - the identifier at the start of a path like c.address.city, else ""
+func rootName(expr tExpr) string {
+	switch e := expr.(type) {
+	case *tIdentifier:
+		return e.name
+	case *tProperty:
+		return rootName(e.object)
+	}
+	return ""
+}
@@ -0,0 +1,118 @@
+package cosmosfake
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+const testDocuments = `[
+	{"id":"1","tenant":"a","name":"Zwerg","age":12,"tags":["small","red"],"address":{"city":"Berlin"},
+	 "location":{"type":"Point","coordinates":[13.405,52.52]}},
+	{"id":"2","tenant":"a","name":"Riese","age":40,"tags":["tall"],"address":{"city":"Hamburg"},
+	 "location":{"type":"Point","coordinates":[9.993,53.551]}},
+	{"id":"3","tenant":"b","name":"Elfe","age":12,"tags":[],"address":{"city":"Berlin"},"children":[{"name":"Fee","age":2}]},
+	{"id":"4","tenant":"b","name":"Troll","nickname":null}
+]`
+
+func TestRunQuery(t *testing.T) {
+	var documents []interface{}
+	if err := json.Unmarshal([]byte(testDocuments), &documents); err != nil {
+		t.Fatal(err)
+	}
+	berlin := `{"type":"Polygon","coordinates":[[[13,52],[14,52],[14,53],[13,53],[13,52]]]}`
+
+	tests := []struct {
+		query      string
+		parameters map[string]interface{}
+		want       string
+		wantErr    bool
+	}{
+		{"SELECT VALUE c.id FROM c", nil, `["1","2","3","4"]`, false},
+		{"select value c.id from c where c.age = 12", nil, `["1","3"]`, false},
+		{"SELECT c.id, c.address.city FROM c WHERE c.tenant = @tenant", map[string]interface{}{"@tenant": "a"},
+			`[{"city":"Berlin","id":"1"},{"city":"Hamburg","id":"2"}]`, false},
+		{"SELECT c.id AS key, c.age * 2 AS double, c.name || '!' FROM c WHERE c.id = '1'", nil,
+			`[{"$1":"Zwerg!","double":24,"key":"1"}]`, false},
+		{"SELECT VALUE c.id FROM c WHERE c.id IN ('2', '4', '9')", nil, `["2","4"]`, false},
+		{"SELECT VALUE c.id FROM c WHERE c.age BETWEEN 10 AND 20 AND NOT c.tenant = 'b'", nil, `["1"]`, false},
+		{"SELECT VALUE c.name FROM c WHERE c.name LIKE '%r%' ORDER BY c.name", nil, `["Troll","Zwerg"]`, false},
+		{"SELECT TOP 2 VALUE c.id FROM c ORDER BY c.age DESC, c.id", nil, `["2","1"]`, false},
+		{"SELECT VALUE c.id FROM c ORDER BY c.id OFFSET 1 LIMIT 2", nil, `["2","3"]`, false},
+		{"SELECT DISTINCT VALUE c.address.city FROM c", nil, `["Berlin","Hamburg"]`, false},
+		{"SELECT c.id, t AS tag FROM c JOIN t IN c.tags WHERE t != 'red'", nil, `[{"id":"1","tag":"small"},{"id":"2","tag":"tall"}]`, false},
+		{"SELECT VALUE ch.name FROM ch IN c.children", nil, `["Fee"]`, false},
+		{"SELECT VALUE COUNT(1) FROM c", nil, `[4]`, false},
+		{"SELECT VALUE COUNT(1) FROM c WHERE c.age > 100", nil, `[0]`, false},
+		{"SELECT c.tenant, COUNT(1) AS n, SUM(c.age) AS total, MAX(c.name) AS last FROM c GROUP BY c.tenant", nil,
+			`[{"last":"Zwerg","n":2,"tenant":"a","total":52},{"last":"Troll","n":2,"tenant":"b","total":12}]`, false},
+		{"SELECT AVG(c.age) AS avg, MIN(c.age) AS min FROM c", nil, `[{"avg":21.333333333333332,"min":12}]`, false},
+		{"SELECT VALUE c.id FROM c WHERE IS_DEFINED(c.nickname) AND IS_NULL(c.nickname)", nil, `["4"]`, false},
+		{"SELECT VALUE c.id FROM c WHERE ARRAY_CONTAINS(c.tags, 'red') OR ARRAY_LENGTH(c.children) > 0", nil, `["1","3"]`, false},
+		{"SELECT VALUE c.id FROM c WHERE ARRAY_CONTAINS(c.children, {name: 'Fee'}, true)", nil, `["3"]`, false},
+		{"SELECT VALUE [UPPER(c.name), LENGTH(c.name), SUBSTRING(c.name, 1, 2), CONTAINS(c.name, 'zw', true)] FROM c WHERE c.id = '1'", nil,
+			`[["ZWERG",5,"we",true]]`, false},
+		{"SELECT VALUE {r: ROUND(2.5), f: FLOOR(-1.5), p: POWER(2, 10), a: ABS(-3)} FROM c WHERE c.id = '1'", nil,
+			`[{"a":3,"f":-2,"p":1024,"r":3}]`, false},
+		{"SELECT VALUE c.id FROM c WHERE ST_WITHIN(c.location, " + berlin + ")", nil, `["1"]`, false},
+		{"SELECT VALUE ROUND(ST_DISTANCE(c.location, {type: 'Point', coordinates: [13.405, 52.52]}) / 1000) FROM c WHERE c.id = '2'", nil,
+			`[255]`, false},
+		{"SELECT VALUE c.id FROM c WHERE c.age ?? 0 = 0", nil, `["4"]`, false},
+		{"SELECT VALUE (c.age > 18 ? 'adult' : 'child') FROM c WHERE IS_NUMBER(c.age)", nil, `["child","adult","child"]`, false},
+		{"SELECT c.id, ARRAY(SELECT VALUE t FROM t IN c.tags WHERE t != 'small') AS other FROM c WHERE c.id = '1'", nil,
+			`[{"id":"1","other":["red"]}]`, false},
+		{"SELECT VALUE c.id FROM c WHERE EXISTS(SELECT VALUE ch FROM ch IN c.children WHERE ch.age < 5)", nil, `["3"]`, false},
+		{"SELECT VALUE c.id FROM Families c WHERE c['name'] = 'Elfe'", nil, `["3"]`, false},
+		{"SELECT * FROM c WHERE c.id = '4'", nil, `[{"id":"4","name":"Troll","nickname":null,"tenant":"b"}]`, false},
+
+		{"SELECT VALUE c.id FROM c ORDER BY c.id OFFSET 3 LIMIT 2147483647", nil, `["4"]`, false},
+		{"SELECT VALUE c.id FROM c ORDER BY c.id OFFSET 2147483647 LIMIT 1", nil, `[]`, false},
+		{"SELECT VALUE c.id FROM c WHERE c.id = '1' AND NOT IS_DEFINED(c.tags[1e20]) AND NOT IS_DEFINED(c.tags[-1])", nil, `["1"]`, false},
+		{"SELECT VALUE [LEFT(c.name, 1e20), RIGHT(c.name, 1e20), LEFT(c.name, -1), SUBSTRING(c.name, 1e20, 1), SUBSTRING(c.name, -1e20, 2), SUBSTRING(c.name, 1, 1e20)] FROM c WHERE c.id = '1'", nil,
+			`[["Zwerg","Zwerg","","Zw","werg"]]`, false},
+		{"SELECT VALUE [ARRAY_SLICE(c.tags, 0, 1e20), ARRAY_SLICE(c.tags, -1e20), ARRAY_SLICE(c.tags, 1e20), ARRAY_SLICE(c.tags, -1, 1e20), ARRAY_SLICE(c.tags, 0, -1e20)] FROM c WHERE c.id = '1'", nil,
+			`[[["small","red"],["small","red"],[],["red"],[]]]`, false},
+
+		{"SELECT TOP 1e20 VALUE c.id FROM c", nil, ``, true},
+		{"SELECT TOP 1.5 VALUE c.id FROM c", nil, ``, true},
+		{"SELECT VALUE c.id FROM c OFFSET 1e20 LIMIT 1", nil, ``, true},
+		{"SELECT VALUE c.id FROM c OFFSET 0 LIMIT 1e20", nil, ``, true},
+		{"SELECT * FROM c JOIN t IN c.tags", nil, ``, true},
+		{"SELECT VALUE c.id FROM c WHERE c.id = @missing", nil, ``, true},
+		{"SELECT VALUE c.id FROM c WHERE NOSUCHFUNCTION(c.id)", nil, ``, true},
+		{"SELECT VALUE c.id FROM c WHERE", nil, ``, true},
+		{"SELECT VALUE x.id FROM c", nil, ``, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			query, err := parseQuery(tt.query)
+			var results []interface{}
+			if err == nil {
+				results, err = runQuery(&tContext{parameters: tt.parameters}, query, tEnv{}, documents)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := canonical(results); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPanicIsAnswered(t *testing.T) {
+	server := newServer("")
+	res := server.locked(func() tResponse {
+		var values []interface{}
+		return tResponse{body: values[1]}
+	})
+	if res.status_code != 500 {
+		t.Errorf("locked() status = %v, want 500", res.status_code)
+	}
+	if !server.mutex.TryLock() {
+		t.Fatal("the mutex is still locked after the panic")
+	}
+	server.mutex.Unlock()
+}
@@ -0,0 +1,324 @@
+package cosmosfake
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+)
+
+// tDatabase - a database with its collections
+type tDatabase struct {
+	properties  map[string]interface{}
+	collections map[string]*tCollection
+	order       []string //collection ids in order of creation
+}
+
+// tCollection - a collection with its documents
+type tCollection struct {
+	properties    map[string]interface{}
+	partition_key []string   //paths of the partition key, empty for a collection without partitions
+	unique_keys   [][]string //paths of each unique key
+	documents     map[string]*tDocument
+	lsn           int64                                        //logical sequence number of the last write
+	scripts       map[string]map[string]map[string]interface{} //"sprocs", "triggers" and "udfs" by id
+	changes       []tChange                                    //the change log in order of the lsn
+	ranges        []*tRange                                    //the partition key ranges, split on demand
+	gone          map[string]bool                              //ids of split ranges
+}
+
+// CreateDatabase - creates a database if it does not exist
+func (me *TServer) CreateDatabase(database string) {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	me.createDatabase(database)
+}
+
+/*
+CreateContainer - creates a collection and its database if they do not exist
+
+parameters:
+
+	database - id of the database
+	container - id of the collection
+	partition_key_paths - i.e. "/tenant", none for a collection without partitions
+*/
+func (me *TServer) CreateContainer(database string, container string, partition_key_paths ...string) {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	db := me.createDatabase(database)
+	if db.collections[container] != nil {
+		return
+	}
+	properties := map[string]interface{}{"id": container}
+	if len(partition_key_paths) > 0 {
+		kind := "Hash"
+		if len(partition_key_paths) > 1 {
+			kind = "MultiHash"
+		}
+		properties["partitionKey"] = map[string]interface{}{"paths": partition_key_paths, "kind": kind, "version": 2}
+	}
+	me.createCollection(db, properties)
+}
+
+func (me *TServer) createDatabase(id string) *tDatabase {
+	if db := me.databases[id]; db != nil {
+		return db
+	}
+	rid := me.nextRid()
+	db := &tDatabase{
+		properties: map[string]interface{}{
+			"id":     id,
+			"_rid":   rid,
+			"_self":  "dbs/" + rid + "/",
+			"_etag":  me.etag(),
+			"_ts":    me.Now().Unix(),
+			"_colls": "colls/",
+			"_users": "users/",
+		},
+		collections: map[string]*tCollection{},
+	}
+	me.databases[id] = db
+	me.order = append(me.order, id)
+	return db
+}
+
+// createCollection - the properties must hold a new id
+func (me *TServer) createCollection(db *tDatabase, properties map[string]interface{}) *tCollection {
+	rid := me.nextRid()
+	properties["_rid"] = rid
+	properties["_self"] = db.properties["_self"].(string) + "colls/" + rid + "/"
+	properties["_docs"] = "docs/"
+	properties["_sprocs"] = "sprocs/"
+	properties["_triggers"] = "triggers/"
+	properties["_udfs"] = "udfs/"
+	properties["_conflicts"] = "conflicts/"
+	if properties["indexingPolicy"] == nil {
+		properties["indexingPolicy"] = map[string]interface{}{
+			"indexingMode":  "consistent",
+			"automatic":     true,
+			"includedPaths": []interface{}{map[string]interface{}{"path": "/*"}},
+			"excludedPaths": []interface{}{map[string]interface{}{"path": "/\"_etag\"/?"}},
+		}
+	}
+	collection := &tCollection{
+		documents: map[string]*tDocument{},
+		ranges:    []*tRange{{id: "0", min: "", max: "FF", parents: []string{}}},
+		gone:      map[string]bool{},
+	}
+	me.setCollectionProperties(collection, properties)
+
+	id := properties["id"].(string)
+	db.collections[id] = collection
+	db.order = append(db.order, id)
+	return collection
+}
+
+// setCollectionProperties - stores the properties with a new etag and _ts
+func (me *TServer) setCollectionProperties(collection *tCollection, properties map[string]interface{}) {
+	properties["_etag"] = me.etag()
+	properties["_ts"] = me.Now().Unix()
+	collection.properties = properties
+
+	var definition struct {
+		PartitionKey struct {
+			Paths []string `json:"paths"`
+		} `json:"partitionKey"`
+		UniqueKeyPolicy struct {
+			UniqueKeys []struct {
+				Paths []string `json:"paths"`
+			} `json:"uniqueKeys"`
+		} `json:"uniqueKeyPolicy"`
+	}
+	data, _ := json.Marshal(properties)
+	_ = json.Unmarshal(data, &definition)
+	collection.partition_key = definition.PartitionKey.Paths
+	collection.unique_keys = nil
+	for _, unique_key := range definition.UniqueKeyPolicy.UniqueKeys {
+		collection.unique_keys = append(collection.unique_keys, unique_key.Paths)
+	}
+}
+
+// decodeResource - the body as json object with a string id
+func decodeResource(body []byte) (map[string]interface{}, *tResponse) {
+	var resource map[string]interface{}
+	if err := json.Unmarshal(body, &resource); err != nil || resource == nil {
+		res := errorResponse(http.StatusBadRequest, "The request payload is invalid. Ensure to provide a valid request payload.")
+		return nil, &res
+	}
+	if id, ok := resource["id"].(string); !ok || id == "" {
+		res := errorResponse(http.StatusBadRequest, "The input content is invalid because the required properties - 'id; ' - are missing")
+		return nil, &res
+	}
+	return resource, nil
+}
+
+// handleDatabases - dbs and dbs/{db}
+func (me *TServer) handleDatabases(req tRequest) tResponse {
+	if len(req.segments) == 1 {
+		switch req.verb {
+		case "GET":
+			var list []interface{}
+			for _, id := range me.order {
+				list = append(list, me.databases[id].properties)
+			}
+			return feedResponse(req, "", "Databases", list, 1)
+		case "POST":
+			resource, res := decodeResource(req.body)
+			if res != nil {
+				return *res
+			}
+			id := resource["id"].(string)
+			if me.databases[id] != nil {
+				return errorResponse(http.StatusConflict, "Entity with the specified id already exists in the system.")
+			}
+			return tResponse{status_code: http.StatusCreated, body: me.createDatabase(id).properties, charge: 5}
+		}
+		return errorResponse(http.StatusMethodNotAllowed, "%s is not allowed on dbs", req.verb)
+	}
+
+	id := req.segments[1]
+	db := me.databases[id]
+	if db == nil {
+		return errorResponse(http.StatusNotFound, "Resource Not Found. Learn more: https://aka.ms/cosmosdb-tsg-not-found")
+	}
+	switch req.verb {
+	case "GET":
+		return tResponse{status_code: http.StatusOK, body: db.properties, charge: 1}
+	case "DELETE":
+		delete(me.databases, id)
+		me.order = remove(me.order, id)
+		return tResponse{status_code: http.StatusNoContent, charge: 5}
+	}
+	return errorResponse(http.StatusMethodNotAllowed, "%s is not allowed on a database", req.verb)
+}
+
+// handleCollections - dbs/{db}/colls and dbs/{db}/colls/{coll}
+func (me *TServer) handleCollections(req tRequest) tResponse {
+	db := me.databases[req.segments[1]]
+	if db == nil {
+		return errorResponse(http.StatusNotFound, "Resource Not Found. Learn more: https://aka.ms/cosmosdb-tsg-not-found")
+	}
+	if len(req.segments) == 3 {
+		switch req.verb {
+		case "GET":
+			var list []interface{}
+			for _, id := range db.order {
+				list = append(list, db.collections[id].properties)
+			}
+			return feedResponse(req, db.properties["_rid"].(string), "DocumentCollections", list, 1)
+		case "POST":
+			properties, res := decodeResource(req.body)
+			if res != nil {
+				return *res
+			}
+			if db.collections[properties["id"].(string)] != nil {
+				return errorResponse(http.StatusConflict, "Entity with the specified id already exists in the system.")
+			}
+			return tResponse{status_code: http.StatusCreated, body: me.createCollection(db, properties).properties, charge: 5}
+		}
+		return errorResponse(http.StatusMethodNotAllowed, "%s is not allowed on colls", req.verb)
+	}
+
+	id := req.segments[3]
+	collection := db.collections[id]
+	if collection == nil {
+		return errorResponse(http.StatusNotFound, "Resource Not Found. Learn more: https://aka.ms/cosmosdb-tsg-not-found")
+	}
+	switch req.verb {
+	case "GET":
+		res := tResponse{status_code: http.StatusOK, body: collection.properties, charge: 1, header: http.Header{}}
+		if req.header.Get("x-ms-documentdb-populatequotainfo") != "" {
+			count := strconv.Itoa(len(collection.documents))
+			res.header.Set("x-ms-resource-quota", "collectionSize=26214400;documentsCount=-1;")
+			res.header.Set("x-ms-resource-usage", "collectionSize=0;documentsCount="+count+";")
+			res.header.Set("x-ms-documentdb-collection-index-transformation-progress", "100")
+		}
+		return res
+	case "PUT":
+		properties, res := decodeResource(req.body)
+		if res != nil {
+			return *res
+		}
+		if properties["id"] != id {
+			return errorResponse(http.StatusBadRequest, "The id of the collection can not be changed.")
+		}
+		old_key, _ := json.Marshal(collection.properties["partitionKey"])
+		new_key, _ := json.Marshal(properties["partitionKey"])
+		if string(old_key) != string(new_key) {
+			return errorResponse(http.StatusBadRequest, "Document collection partition key cannot be changed.")
+		}
+		for _, key := range []string{"_rid", "_self", "_docs", "_sprocs", "_triggers", "_udfs", "_conflicts"} {
+			properties[key] = collection.properties[key]
+		}
+		if properties["uniqueKeyPolicy"] == nil {
+			properties["uniqueKeyPolicy"] = collection.properties["uniqueKeyPolicy"]
+		}
+		me.setCollectionProperties(collection, properties)
+		return tResponse{status_code: http.StatusOK, body: collection.properties, charge: 5}
+	case "DELETE":
+		delete(db.collections, id)
+		db.order = remove(db.order, id)
+		return tResponse{status_code: http.StatusNoContent, charge: 5}
+	}
+	return errorResponse(http.StatusMethodNotAllowed, "%s is not allowed on a collection", req.verb)
+}
+
+// collection - the collection of the path dbs/{db}/colls/{coll}/...
+func (me *TServer) collection(req tRequest) (*tCollection, *tResponse) {
+	if db := me.databases[req.segments[1]]; db != nil {
+		if collection := db.collections[req.segments[3]]; collection != nil {
+			return collection, nil
+		}
+	}
+	res := errorResponse(http.StatusNotFound, "Resource Not Found. Learn more: https://aka.ms/cosmosdb-tsg-not-found")
+	return nil, &res
+}
+
+/*
+feedResponse - a page of the items with x-ms-max-item-count and x-ms-continuation
+
+the continuation is the offset of the next page
+*/
+func feedResponse(req tRequest, rid string, name string, items []interface{}, charge float64) tResponse {
+	max_item_count := DefaultMaxItemCount
+	if value, err := strconv.Atoi(req.header.Get("x-ms-max-item-count")); err == nil && value > 0 {
+		max_item_count = value
+	}
+	offset := 0
+	if continuation := req.header.Get("x-ms-continuation"); continuation != "" {
+		value, err := strconv.Atoi(continuation)
+		if err != nil || value < 0 || value > len(items) {
+			return errorResponse(http.StatusBadRequest, "Invalid Continuation Token")
+		}
+		offset = value
+	}
+
+	end := offset + max_item_count
+	if end > len(items) {
+		end = len(items)
+	}
+	page := items[offset:end]
+	if page == nil {
+		page = []interface{}{}
+	}
+	res := tResponse{
+		status_code: http.StatusOK,
+		header:      http.Header{},
+		body:        map[string]interface{}{"_rid": rid, name: page, "_count": len(page)},
+		charge:      charge,
+	}
+	res.header.Set("x-ms-item-count", strconv.Itoa(len(page)))
+	if end < len(items) {
+		res.header.Set("x-ms-continuation", strconv.Itoa(end))
+	}
+	return res
+}
+
+func remove(list []string, value string) []string {
+	for i, item := range list {
+		if item == value {
+			return append(list[:i:i], list[i+1:]...)
+		}
+	}
+	return list
+}
@@ -0,0 +1,62 @@
+package cosmosfake
+
+import "strings"
+
+/*
+resolveRids - translates a path by _rid to the ids, i.e. "dbs/AAAAAAAAAAE=/colls/AAAAAAAAAAI=/docs/AAAAAAAAAAM="
+
+a request by _rid is signed with the _rid of the resource in lower case, so the resource link
+of the request is replaced, too, a database name wins over a _rid and unknown _rids are kept
+*/
+func (me *TServer) resolveRids(req *tRequest) {
+	count := len(req.segments)
+	if count < 2 || req.segments[0] != "dbs" || me.databases[req.segments[1]] != nil {
+		return
+	}
+	var db *tDatabase
+	for _, database := range me.databases {
+		if database.properties["_rid"] == req.segments[1] {
+			db = database
+		}
+	}
+	if db == nil {
+		return
+	}
+
+	//the resource itself or the parent of a feed
+	if count%2 == 0 {
+		req.resource_link = strings.ToLower(req.segments[count-1])
+	} else {
+		req.resource_link = strings.ToLower(req.segments[count-2])
+	}
+
+	segments := append([]string{}, req.segments...)
+	segments[1] = db.properties["id"].(string)
+	if count < 4 {
+		req.segments = segments
+		return
+	}
+	var collection *tCollection
+	for id, candidate := range db.collections {
+		if candidate.properties["_rid"] == segments[3] {
+			collection, segments[3] = candidate, id
+		}
+	}
+	if collection != nil && count >= 6 {
+		switch segments[4] {
+		case "docs":
+			for _, document := range collection.documents {
+				if document.body["_rid"] == segments[5] {
+					segments[5] = document.body["id"].(string)
+				}
+			}
+		case "sprocs", "triggers", "udfs":
+			for id, script := range collection.scripts[segments[4]] {
+				if script["_rid"] == segments[5] {
+					segments[5] = id
+				}
+			}
+		}
+	}
+	req.segments = segments
+}
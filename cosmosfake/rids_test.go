@@ -0,0 +1,98 @@
+package cosmosfake_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	cosmos "github.com/jankstar/cosmos_db_restapi"
+	"github.com/jankstar/cosmos_db_restapi/cosmosfake"
+)
+
+func TestResourceLinks(t *testing.T) {
+	fake := cosmosfake.ServerFactory("")
+	defer fake.Close()
+	fake.CreateContainer("Shop", "Order Items", "/tenant")
+
+	database := cosmos.DatabaseFactory(fake.EndpointUri, fake.MasterKey, "Shop")
+	container := cosmos.ContainerFactory(database, "Order Items", "a")
+
+	ids := []string{"Zwerg", "zwerg", "a b", "50%", "Bär", "ünïcödé €"}
+	for _, id := range ids {
+		t.Run(id, func(t *testing.T) {
+			data, _ := json.Marshal(map[string]string{"id": id, "tenant": "a"})
+			if status, body := container.CreateDocument(false, string(data)); status != "201 Created" {
+				t.Fatalf("CreateDocument() = %v, %v", status, body)
+			}
+			status, body := container.GetDocumentByID(id)
+			var document map[string]interface{}
+			_ = json.Unmarshal([]byte(body), &document)
+			if status != "200 OK" || document["id"] != id {
+				t.Errorf("GetDocumentByID() = %v, %v", status, body)
+			}
+		})
+	}
+	lower_case := cosmos.ContainerFactory(database, "order items", "a")
+	if status, _ := lower_case.GetDocumentByID("Zwerg"); status != "404 Not Found" {
+		t.Errorf("the names are case sensitive, GetDocumentByID() = %v", status)
+	}
+
+	//the same document by _rid
+	_, _, properties := database.ReadContainer("Order Items")
+	_, body := container.GetDocumentByID("a b")
+	var document struct {
+		Rid string `json:"_rid"`
+	}
+	_ = json.Unmarshal([]byte(body), &document)
+	database_rid := strings.Split(properties.Self, "/")[1] //"dbs/{database_rid}/colls/{rid}/"
+
+	by_rid := cosmos.ContainerByRidFactory(database, database_rid, properties.Rid, "a")
+	if status, body := by_rid.GetDocumentByID(document.Rid); status != "200 OK" {
+		t.Errorf("GetDocumentByID() by _rid = %v, %v", status, body)
+	}
+	if status, body := by_rid.CreateDocument(false, `{"id":"by rid","tenant":"a"}`); status != "201 Created" {
+		t.Errorf("CreateDocument() by _rid = %v, %v", status, body)
+	}
+	if status, _, _ := by_rid.ExecuteQuerry(0, "", cosmos.TQuery{Query: "SELECT * FROM c"}); status != "200 OK" {
+		t.Errorf("ExecuteQuerry() by _rid = %v", status)
+	}
+}
+
+func TestRidsWithSlash(t *testing.T) {
+	fake := cosmosfake.MemoryServerFactory("")
+	fake.CreateContainer("db", "user", "/tenant")
+	database := fake.Database("db")
+	container := cosmos.ContainerFactory(*database, "user", "a")
+
+	//the 1008th rid is "AAAAAAAAA/A=" in standard base64
+	var rids []string
+	for i := 0; i < 1010; i++ {
+		status, body := container.CreateDocument(false, fmt.Sprintf(`{"id":"%d","tenant":"a"}`, i))
+		var document struct {
+			Rid string `json:"_rid"`
+		}
+		if err := json.Unmarshal([]byte(body), &document); status != "201 Created" || err != nil {
+			t.Fatalf("CreateDocument() = %v, %v", status, body)
+		}
+		rids = append(rids, document.Rid)
+	}
+	with_dash := ""
+	for _, rid := range rids {
+		if strings.Contains(rid, "/") {
+			t.Fatalf("the rid %q contains a slash", rid)
+		}
+		if strings.Contains(rid, "-") {
+			with_dash = rid
+		}
+	}
+	if with_dash == "" {
+		t.Fatalf("no rid with a dash")
+	}
+
+	_, _, properties := database.ReadContainer("user")
+	by_rid := cosmos.ContainerByRidFactory(*database, strings.Split(properties.Self, "/")[1], properties.Rid, "a")
+	if status, body := by_rid.GetDocumentByID(with_dash); status != "200 OK" || !strings.Contains(body, with_dash) {
+		t.Errorf("GetDocumentByID(%q) by _rid = %v, %v", with_dash, status, body)
+	}
+}
@@ -0,0 +1,616 @@
+package cosmosfake
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dop251/goja"
+)
+
+// ScriptTimeout - the execution time of a stored procedure, trigger or user defined function
+var ScriptTimeout = 5 * time.Second
+
+// scriptFeeds - the name of the feed of each script resource type
+var scriptFeeds = map[string]string{
+	"sprocs":   "StoredProcedures",
+	"triggers": "Triggers",
+	"udfs":     "UserDefinedFunctions",
+}
+
+// tSnapshot - the state of a collection to roll back a script
+type tSnapshot struct {
+	documents map[string]*tDocument
+	lsn       int64
+	changes   []tChange
+}
+
+// snapshot - the documents are not changed in place, a copy of the map is enough
+func (me *tCollection) snapshot() tSnapshot {
+	documents := make(map[string]*tDocument, len(me.documents))
+	for key, document := range me.documents {
+		documents[key] = document
+	}
+	return tSnapshot{documents: documents, lsn: me.lsn, changes: me.changes}
+}
+
+func (me *tCollection) restore(snapshot tSnapshot) {
+	me.documents = snapshot.documents
+	me.lsn = snapshot.lsn
+	me.changes = snapshot.changes
+}
+
+// handleScripts - sprocs, triggers and udfs of a collection, POST on a sproc executes it
+func (me *TServer) handleScripts(req tRequest) tResponse {
+	collection, res := me.collection(req)
+	if res != nil {
+		return *res
+	}
+	if collection.scripts == nil {
+		collection.scripts = map[string]map[string]map[string]interface{}{}
+	}
+	scripts := collection.scripts[req.resource_type]
+	if scripts == nil {
+		scripts = map[string]map[string]interface{}{}
+		collection.scripts[req.resource_type] = scripts
+	}
+
+	if len(req.segments) == 5 {
+		switch req.verb {
+		case "GET":
+			var ids []string
+			for id := range scripts {
+				ids = append(ids, id)
+			}
+			sort.Strings(ids)
+			var list []interface{}
+			for _, id := range ids {
+				list = append(list, scripts[id])
+			}
+			return feedResponse(req, collection.properties["_rid"].(string), scriptFeeds[req.resource_type], list, 1)
+		case "POST":
+			properties, res := decodeScript(req)
+			if res != nil {
+				return *res
+			}
+			id := properties["id"].(string)
+			if scripts[id] != nil {
+				return errorResponse(http.StatusConflict, "Entity with the specified id already exists in the system.")
+			}
+			me.setScriptProperties(collection, req.resource_type, properties)
+			scripts[id] = properties
+			return tResponse{status_code: http.StatusCreated, header: etagHeader(properties["_etag"].(string)), body: properties, charge: 5}
+		}
+		return errorResponse(http.StatusMethodNotAllowed, "%s is not allowed on %s", req.verb, req.resource_type)
+	}
+
+	id := req.segments[5]
+	script := scripts[id]
+	if script == nil {
+		return errorResponse(http.StatusNotFound, "Resource Not Found. Learn more: https://aka.ms/cosmosdb-tsg-not-found")
+	}
+	switch {
+	case req.verb == "GET":
+		return tResponse{status_code: http.StatusOK, header: etagHeader(script["_etag"].(string)), body: script, charge: 1}
+	case req.verb == "PUT":
+		if res := preconditionFailed(req.header.Get("If-Match"), script["_etag"].(string)); res != nil {
+			return *res
+		}
+		properties, res := decodeScript(req)
+		if res != nil {
+			return *res
+		}
+		if properties["id"] != id {
+			return errorResponse(http.StatusBadRequest, "The id of the resource in the request body does not match the id of the resource link.")
+		}
+		properties["_rid"] = script["_rid"]
+		me.setScriptProperties(collection, req.resource_type, properties)
+		scripts[id] = properties
+		return tResponse{status_code: http.StatusOK, header: etagHeader(properties["_etag"].(string)), body: properties, charge: 5}
+	case req.verb == "DELETE":
+		delete(scripts, id)
+		return tResponse{status_code: http.StatusNoContent, charge: 5}
+	case req.verb == "POST" && req.resource_type == "sprocs":
+		return me.executeStoredProcedure(collection, req, script)
+	}
+	return errorResponse(http.StatusMethodNotAllowed, "%s is not allowed on %s", req.verb, req.resource_type)
+}
+
+// decodeScript - a script resource with id and body, a trigger also with type and operation
+func decodeScript(req tRequest) (map[string]interface{}, *tResponse) {
+	properties, res := decodeResource(req.body)
+	if res != nil {
+		return nil, res
+	}
+	if _, ok := properties["body"].(string); !ok {
+		res := errorResponse(http.StatusBadRequest, "The input content is invalid because the required properties - 'body; ' - are missing")
+		return nil, &res
+	}
+	if req.resource_type == "triggers" {
+		trigger_type, _ := properties["triggerType"].(string)
+		operation, _ := properties["triggerOperation"].(string)
+		if trigger_type != "Pre" && trigger_type != "Post" {
+			res := errorResponse(http.StatusBadRequest, "The triggerType must be 'Pre' or 'Post'.")
+			return nil, &res
+		}
+		switch operation {
+		case "All", "Create", "Replace", "Delete", "Upsert":
+		default:
+			res := errorResponse(http.StatusBadRequest, "The triggerOperation must be 'All', 'Create', 'Replace', 'Delete' or 'Upsert'.")
+			return nil, &res
+		}
+	}
+	return properties, nil
+}
+
+func (me *TServer) setScriptProperties(collection *tCollection, resource_type string, properties map[string]interface{}) {
+	if properties["_rid"] == nil {
+		properties["_rid"] = me.nextRid()
+	}
+	properties["_self"] = collection.properties["_self"].(string) + resource_type + "/" + properties["_rid"].(string) + "/"
+	properties["_etag"] = me.etag()
+	properties["_ts"] = me.Now().Unix()
+}
+
+// executeStoredProcedure - runs the sproc in the partition of the request, all changes are rolled back on an exception
+func (me *TServer) executeStoredProcedure(collection *tCollection, req tRequest, sproc map[string]interface{}) tResponse {
+	me.purgeExpired(collection)
+	partition_key, has_key, key_res := collection.requestPartitionKey(req)
+	if key_res != nil {
+		return *key_res
+	}
+	if !has_key {
+		return errorResponse(http.StatusBadRequest, "PartitionKey value must be supplied for this operation.")
+	}
+	var params []interface{}
+	if len(strings.TrimSpace(string(req.body))) > 0 {
+		if err := json.Unmarshal(req.body, &params); err != nil {
+			return errorResponse(http.StatusBadRequest, "The parameters of a stored procedure must be a json array.")
+		}
+	}
+
+	snapshot := collection.snapshot()
+	script := me.newScript(collection, partition_key)
+	_, err := script.run(sproc["body"].(string), params)
+	res := tResponse{status_code: http.StatusOK, header: http.Header{}, charge: script.charge + 1}
+	if strings.EqualFold(req.header.Get("x-ms-documentdb-script-enable-logging"), "true") && len(script.log) > 0 {
+		res.header.Set("x-ms-documentdb-script-log-results", strings.Join(script.log, "\n"))
+	}
+	if err != nil {
+		collection.restore(snapshot)
+		failed := errorResponse(http.StatusBadRequest, "Encountered exception while executing function. Exception = %s", err.Error())
+		failed.header = res.header
+		return collection.withSession(failed)
+	}
+	if script.response_body != undefined {
+		res.body = script.response_body
+	}
+	return collection.withSession(res)
+}
+
+/*
+withTriggers - runs the write with the triggers of the request
+
+pre-triggers may change the body, an exception in a trigger or a failed write rolls back all changes
+*/
+func (me *TServer) withTriggers(collection *tCollection, req tRequest, partition_key string, operation string, body map[string]interface{}, write func(body map[string]interface{}) tResponse) tResponse {
+	pre_triggers := splitNames(req.header.Get("x-ms-documentdb-pre-trigger-include"))
+	post_triggers := splitNames(req.header.Get("x-ms-documentdb-post-trigger-include"))
+	if len(pre_triggers) == 0 && len(post_triggers) == 0 {
+		return write(body)
+	}
+
+	snapshot := collection.snapshot()
+	failed := func(res tResponse) tResponse {
+		collection.restore(snapshot)
+		return res
+	}
+	for _, name := range pre_triggers {
+		trigger, res := collection.trigger(name, "Pre", operation)
+		if res != nil {
+			return failed(*res)
+		}
+		script := me.newScript(collection, partition_key)
+		script.operation = operation
+		script.request_body = body
+		if _, err := script.run(trigger["body"].(string), nil); err != nil {
+			return failed(errorResponse(http.StatusBadRequest, "Encountered exception while executing Javascript. Exception = %s", err.Error()))
+		}
+		if body != nil {
+			changed, ok := script.request_body.(map[string]interface{})
+			if !ok {
+				return failed(errorResponse(http.StatusBadRequest, "The pre-trigger %s did not set a document as request body.", name))
+			}
+			if id, ok := changed["id"].(string); !ok || id == "" {
+				return failed(errorResponse(http.StatusBadRequest, "The pre-trigger %s removed the id of the document.", name))
+			}
+			body = changed
+		}
+	}
+
+	res := write(body)
+	if res.status_code >= 300 {
+		return failed(res)
+	}
+	for _, name := range post_triggers {
+		trigger, trigger_res := collection.trigger(name, "Post", operation)
+		if trigger_res != nil {
+			return failed(*trigger_res)
+		}
+		script := me.newScript(collection, partition_key)
+		script.operation = operation
+		script.request_body = body
+		script.response_body = res.body
+		if res.body == nil {
+			script.response_body = undefined
+		}
+		if _, err := script.run(trigger["body"].(string), nil); err != nil {
+			return failed(errorResponse(http.StatusBadRequest, "Post-trigger %s failed, the write was rolled back. Exception = %s", name, err.Error()))
+		}
+	}
+	return res
+}
+
+// trigger - the trigger with the type for the operation
+func (me *tCollection) trigger(name string, trigger_type string, operation string) (map[string]interface{}, *tResponse) {
+	trigger := me.scripts["triggers"][name]
+	if trigger == nil {
+		res := errorResponse(http.StatusNotFound, "The trigger %s does not exist.", name)
+		return nil, &res
+	}
+	if trigger["triggerType"] != trigger_type {
+		res := errorResponse(http.StatusBadRequest, "The trigger %s is not a %s-trigger.", name, strings.ToLower(trigger_type))
+		return nil, &res
+	}
+	if trigger["triggerOperation"] != "All" && trigger["triggerOperation"] != operation {
+		res := errorResponse(http.StatusBadRequest, "The trigger %s does not apply to the operation %s.", name, operation)
+		return nil, &res
+	}
+	return trigger, nil
+}
+
+// udfFunction - calls the user defined functions of the collection in a query
+func (me *TServer) udfFunction(collection *tCollection, partition_key string) func(name string, args []interface{}) (interface{}, error) {
+	var script *tScript
+	functions := map[string]goja.Callable{}
+	return func(name string, args []interface{}) (interface{}, error) {
+		udf := collection.scripts["udfs"][name]
+		if udf == nil {
+			return nil, fmt.Errorf("The user defined function %s does not exist.", name)
+		}
+		if script == nil {
+			script = me.newScript(collection, partition_key)
+		}
+		function := functions[name]
+		if function == nil {
+			var err error
+			if function, err = script.compile(udf["body"].(string)); err != nil {
+				return nil, err
+			}
+			functions[name] = function
+		}
+		return script.call(function, args)
+	}
+}
+
+func splitNames(header string) (names []string) {
+	for _, name := range strings.Split(header, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return
+}
+
+// tScript - a javascript runtime with the server side api of cosmos db for one collection and partition
+type tScript struct {
+	server        *TServer
+	collection    *tCollection
+	partition_key string
+	vm            *goja.Runtime
+
+	operation     string      //of the request of a trigger i.e. "Create"
+	request_body  interface{} //of the request of a trigger
+	response_body interface{} //set with getResponse().setBody(), undefined if not set
+	charge        float64     //request charge of the collection operations
+	log           []string    //console.log output
+}
+
+// newScript - a runtime with getContext() and console.log
+func (me *TServer) newScript(collection *tCollection, partition_key string) *tScript {
+	script := &tScript{
+		server:        me,
+		collection:    collection,
+		partition_key: partition_key,
+		vm:            goja.New(),
+		response_body: undefined,
+	}
+	vm := script.vm
+
+	self_link := strings.TrimSuffix(collection.properties["_self"].(string), "/")
+	api := vm.NewObject()
+	_ = api.Set("getSelfLink", func() string { return self_link })
+	_ = api.Set("createDocument", script.writeFunction("Create"))
+	_ = api.Set("upsertDocument", script.writeFunction("Upsert"))
+	_ = api.Set("replaceDocument", script.writeFunction("Replace"))
+	_ = api.Set("deleteDocument", script.writeFunction("Delete"))
+	_ = api.Set("readDocument", script.readDocument)
+	_ = api.Set("readDocuments", script.readDocuments)
+	_ = api.Set("queryDocuments", script.queryDocuments)
+
+	request := vm.NewObject()
+	_ = request.Set("getBody", func() goja.Value { return script.toJS(script.request_body) })
+	_ = request.Set("setBody", func(value goja.Value) { script.request_body = script.fromJS(value) })
+	_ = request.Set("getOperationType", func() string { return script.operation })
+
+	response := vm.NewObject()
+	_ = response.Set("getBody", func() goja.Value { return script.toJS(script.response_body) })
+	_ = response.Set("setBody", func(value goja.Value) { script.response_body = script.fromJS(value) })
+
+	context := vm.NewObject()
+	_ = context.Set("getCollection", func() goja.Value { return api })
+	_ = context.Set("getRequest", func() goja.Value { return request })
+	_ = context.Set("getResponse", func() goja.Value { return response })
+	_ = vm.Set("getContext", func() goja.Value { return context })
+
+	console := vm.NewObject()
+	_ = console.Set("log", func(call goja.FunctionCall) goja.Value {
+		var parts []string
+		for _, arg := range call.Arguments {
+			parts = append(parts, arg.String())
+		}
+		script.log = append(script.log, strings.Join(parts, " "))
+		return goja.Undefined()
+	})
+	_ = vm.Set("console", console)
+	return script
+}
+
+// compile - the function of a script body like "function (a, b) {...}"
+func (me *tScript) compile(body string) (goja.Callable, error) {
+	value, err := me.vm.RunString("(" + body + ")")
+	if err != nil {
+		return nil, scriptError(err)
+	}
+	function, ok := goja.AssertFunction(value)
+	if !ok {
+		return nil, fmt.Errorf("the body of the script is not a function")
+	}
+	return function, nil
+}
+
+// run - compiles and calls the script body with the parameters
+func (me *tScript) run(body string, params []interface{}) (interface{}, error) {
+	function, err := me.compile(body)
+	if err != nil {
+		return nil, err
+	}
+	return me.call(function, params)
+}
+
+// call - calls the function with a timeout
+func (me *tScript) call(function goja.Callable, args []interface{}) (interface{}, error) {
+	values := make([]goja.Value, len(args))
+	for i, arg := range args {
+		values[i] = me.toJS(arg)
+	}
+	timer := time.AfterFunc(ScriptTimeout, func() { me.vm.Interrupt("the script exceeded the execution time") })
+	defer timer.Stop()
+	result, err := function(goja.Undefined(), values...)
+	if err != nil {
+		me.vm.ClearInterrupt()
+		return nil, scriptError(err)
+	}
+	return me.fromJS(result), nil
+}
+
+// scriptError - the message of a javascript exception i.e. "Error: no name"
+func scriptError(err error) error {
+	if exception, ok := err.(*goja.Exception); ok {
+		return fmt.Errorf("%s", exception.Value().String())
+	}
+	return err
+}
+
+// toJS - a json value as javascript value
+func (me *tScript) toJS(value interface{}) goja.Value {
+	if value == undefined {
+		return goja.Undefined()
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return goja.Undefined()
+	}
+	parse, _ := goja.AssertFunction(me.vm.Get("JSON").ToObject(me.vm).Get("parse"))
+	result, err := parse(goja.Undefined(), me.vm.ToValue(string(data)))
+	if err != nil {
+		return goja.Undefined()
+	}
+	return result
+}
+
+// fromJS - a javascript value as json value, undefined for undefined and functions
+func (me *tScript) fromJS(value goja.Value) interface{} {
+	if value == nil || goja.IsUndefined(value) {
+		return undefined
+	}
+	stringify, _ := goja.AssertFunction(me.vm.Get("JSON").ToObject(me.vm).Get("stringify"))
+	text, err := stringify(goja.Undefined(), value)
+	if err != nil || goja.IsUndefined(text) {
+		return undefined
+	}
+	var result interface{}
+	if json.Unmarshal([]byte(text.String()), &result) != nil {
+		return undefined
+	}
+	return result
+}
+
+// arguments - the optional options and the callback after the required arguments
+func (me *tScript) arguments(call goja.FunctionCall, required int) (options map[string]interface{}, callback goja.Callable) {
+	for _, arg := range call.Arguments[minInt(required, len(call.Arguments)):] {
+		if function, ok := goja.AssertFunction(arg); ok {
+			callback = function
+			break
+		}
+		if object, ok := me.fromJS(arg).(map[string]interface{}); ok {
+			options = object
+		}
+	}
+	return
+}
+
+// ifMatch - the etag of the options {etag: ...} or {accessCondition: {type: "IfMatch", condition: ...}}
+func ifMatch(options map[string]interface{}) string {
+	if etag, ok := options["etag"].(string); ok {
+		return etag
+	}
+	if condition, ok := options["accessCondition"].(map[string]interface{}); ok && condition["type"] == "IfMatch" {
+		etag, _ := condition["condition"].(string)
+		return etag
+	}
+	return ""
+}
+
+/*
+complete - calls the callback with (error, resource, options), without callback
+a failed operation throws the error
+*/
+func (me *tScript) complete(callback goja.Callable, res tResponse, resource interface{}) goja.Value {
+	me.charge += res.charge
+	var err_value goja.Value = goja.Null()
+	if res.status_code >= 300 {
+		message := ""
+		if body, ok := res.body.(tError); ok {
+			message = body.Message
+		}
+		error_object := me.vm.NewObject()
+		_ = error_object.Set("number", res.status_code)
+		_ = error_object.Set("body", message)
+		_ = error_object.Set("message", message)
+		if callback == nil {
+			panic(error_object)
+		}
+		err_value = error_object
+		resource = undefined
+	}
+	if callback != nil {
+		if _, err := callback(goja.Undefined(), err_value, me.toJS(resource), me.vm.NewObject()); err != nil {
+			panic(err)
+		}
+	}
+	return me.vm.ToValue(true)
+}
+
+// documentOfLink - the document of a link like "dbs/db/colls/coll/docs/id" or its _self link
+func (me *tScript) documentOfLink(link string) *tDocument {
+	parts := strings.Split(strings.Trim(link, "/"), "/")
+	if len(parts) < 2 || parts[len(parts)-2] != "docs" {
+		return nil
+	}
+	id := parts[len(parts)-1]
+	if document := me.collection.documents[me.partition_key+"\x00"+id]; document != nil {
+		return document
+	}
+	for _, document := range me.collection.documents {
+		if document.partition_key == me.partition_key && document.body["_rid"] == id {
+			return document
+		}
+	}
+	return nil
+}
+
+// writeFunction - createDocument, upsertDocument, replaceDocument or deleteDocument
+func (me *tScript) writeFunction(operation string) func(call goja.FunctionCall) goja.Value {
+	return func(call goja.FunctionCall) goja.Value {
+		link := call.Argument(0).String()
+		if operation == "Delete" {
+			options, callback := me.arguments(call, 1)
+			document := me.documentOfLink(link)
+			if document == nil {
+				return me.complete(callback, notFound(), nil)
+			}
+			return me.complete(callback, me.server.deleteItem(me.collection, me.partition_key, document.body["id"].(string), ifMatch(options)), nil)
+		}
+
+		options, callback := me.arguments(call, 2)
+		body, ok := me.fromJS(call.Argument(1)).(map[string]interface{})
+		if !ok {
+			return me.complete(callback, errorResponse(http.StatusBadRequest, "The document must be an object."), nil)
+		}
+		if id, ok := body["id"].(string); !ok || id == "" {
+			if operation == "Replace" {
+				return me.complete(callback, errorResponse(http.StatusBadRequest, "The document requires an id."), nil)
+			}
+			body["id"] = newID()
+		}
+		var res tResponse
+		if operation == "Replace" {
+			document := me.documentOfLink(link)
+			if document == nil {
+				return me.complete(callback, notFound(), nil)
+			}
+			res = me.server.replaceItem(me.collection, me.partition_key, document.body["id"].(string), body, ifMatch(options))
+		} else {
+			res = me.server.createItem(me.collection, me.partition_key, body, operation == "Upsert", ifMatch(options))
+		}
+		return me.complete(callback, res, res.body)
+	}
+}
+
+func (me *tScript) readDocument(call goja.FunctionCall) goja.Value {
+	_, callback := me.arguments(call, 1)
+	document := me.documentOfLink(call.Argument(0).String())
+	if document == nil {
+		return me.complete(callback, notFound(), nil)
+	}
+	return me.complete(callback, tResponse{status_code: http.StatusOK, charge: 1}, document.body)
+}
+
+func (me *tScript) readDocuments(call goja.FunctionCall) goja.Value {
+	_, callback := me.arguments(call, 1)
+	list := []interface{}{}
+	for _, document := range me.collection.sortedDocuments(me.partition_key, false) {
+		list = append(list, document.body)
+	}
+	return me.complete(callback, tResponse{status_code: http.StatusOK, charge: 1}, list)
+}
+
+// queryDocuments - the query is a string or {query, parameters}
+func (me *tScript) queryDocuments(call goja.FunctionCall) goja.Value {
+	_, callback := me.arguments(call, 2)
+	var query tQuery
+	switch value := me.fromJS(call.Argument(1)).(type) {
+	case string:
+		query.Query = value
+	case map[string]interface{}:
+		data, _ := json.Marshal(value)
+		_ = json.Unmarshal(data, &query)
+	}
+	parsed, err := parseQuery(query.Query)
+	if err != nil {
+		return me.complete(callback, errorResponse(http.StatusBadRequest, "%s", err.Error()), nil)
+	}
+	ctx := &tContext{parameters: map[string]interface{}{}, udf: me.server.udfFunction(me.collection, me.partition_key)}
+	for _, parameter := range query.Parameters {
+		ctx.parameters[parameter.Name] = parameter.Value
+	}
+	documents := []interface{}{}
+	for _, document := range me.collection.sortedDocuments(me.partition_key, false) {
+		documents = append(documents, document.body)
+	}
+	list, err := runQuery(ctx, parsed, tEnv{}, documents)
+	if err != nil {
+		return me.complete(callback, errorResponse(http.StatusBadRequest, "%s", err.Error()), nil)
+	}
+	return me.complete(callback, tResponse{status_code: http.StatusOK, charge: 2.5}, list)
+}
+
+// newID - a random id for documents created without id
+func newID() string {
+	now := time.Now().UnixNano()
+	return fmt.Sprintf("%08x-%04x-4%03x-8%03x-%012x", now&0xffffffff, (now>>32)&0xffff, now&0xfff, (now>>12)&0xfff, now&0xffffffffffff)
+}
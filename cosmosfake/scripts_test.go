@@ -0,0 +1,130 @@
+package cosmosfake_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	cosmos "github.com/jankstar/cosmos_db_restapi"
+	"github.com/jankstar/cosmos_db_restapi/cosmosfake"
+)
+
+const transferSproc = `function (from, to, amount) {
+	var collection = getContext().getCollection();
+	var link = collection.getSelfLink();
+	collection.readDocument(link + "/docs/" + from, {}, function (err, source) {
+		if (err) throw new Error("no account " + from);
+		source.balance -= amount;
+		collection.replaceDocument(source._self, source, function (err) {
+			if (err) throw err;
+			collection.readDocument(link + "/docs/" + to, {}, function (err, target) {
+				if (err) throw new Error("no account " + to);
+				if (source.balance < 0) throw new Error("insufficient funds");
+				target.balance += amount;
+				collection.replaceDocument(target._self, target, function (err) {
+					if (err) throw err;
+					getContext().getResponse().setBody({from: source.balance, to: target.balance});
+				});
+			});
+		});
+	});
+}`
+
+func TestStoredProcedure(t *testing.T) {
+	fake := cosmosfake.ServerFactory("")
+	defer fake.Close()
+	fake.CreateContainer("db", "account", "/bank")
+
+	container := cosmos.ContainerFactory(cosmos.DatabaseFactory(fake.EndpointUri, fake.MasterKey, "db"), "account", "b1")
+	container.CreateDocument(false, `{"id":"anna","bank":"b1","balance":100}`)
+	container.CreateDocument(false, `{"id":"ben","bank":"b1","balance":10}`)
+	if status, body := container.CreateStoredProcedure(cosmos.TStoredProcedure{ID: "transfer", Body: transferSproc}); status != "201 Created" {
+		t.Fatalf("CreateStoredProcedure() = %v, %v", status, body)
+	}
+
+	balance := func(id string) float64 {
+		_, body := container.GetDocumentByID(id)
+		var account struct {
+			Balance float64 `json:"balance"`
+		}
+		json.Unmarshal([]byte(body), &account)
+		return account.Balance
+	}
+
+	tests := []struct {
+		name       string
+		to         string
+		amount     int
+		wantStatus string
+		wantBody   string
+		wantAnna   float64
+	}{
+		{"transfer", "ben", 30, "200 OK", `{"from":70,"to":40}`, 70},
+		{"insufficient funds rolls back", "ben", 500, "400 Bad Request", "insufficient funds", 70},
+		{"missing account rolls back", "carl", 10, "400 Bad Request", "no account carl", 70},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := container.ExecuteStoredProcedure("transfer", "anna", tt.to, tt.amount)
+			if status != tt.wantStatus || !strings.Contains(body, tt.wantBody) || balance("anna") != tt.wantAnna {
+				t.Errorf("ExecuteStoredProcedure() = %v, %v, anna %v", status, body, balance("anna"))
+			}
+		})
+	}
+
+	status, body := container.ExecuteStoredProcedure("missing")
+	if status != "404 Not Found" {
+		t.Errorf("ExecuteStoredProcedure() missing = %v, %v", status, body)
+	}
+
+	timeout := cosmosfake.ScriptTimeout
+	cosmosfake.ScriptTimeout = 50 * time.Millisecond
+	defer func() { cosmosfake.ScriptTimeout = timeout }()
+	container.CreateStoredProcedure(cosmos.TStoredProcedure{ID: "loop", Body: "function () { while (true) {} }"})
+	status, body = container.ExecuteStoredProcedure("loop")
+	if status != "400 Bad Request" || !strings.Contains(body, "execution time") {
+		t.Errorf("ExecuteStoredProcedure() loop = %v, %v", status, body)
+	}
+}
+
+func TestTriggersAndUDF(t *testing.T) {
+	fake := cosmosfake.ServerFactory("")
+	defer fake.Close()
+	fake.CreateContainer("db", "order", "/customer")
+
+	container := cosmos.ContainerFactory(cosmos.DatabaseFactory(fake.EndpointUri, fake.MasterKey, "db"), "order", "c1")
+	container.CreateTrigger(cosmos.TTrigger{ID: "stamp", TriggerType: cosmos.TriggerTypePre, TriggerOperation: cosmos.TriggerOperationCreate,
+		Body: `function () {
+			var request = getContext().getRequest();
+			var order = request.getBody();
+			order.status = "new";
+			request.setBody(order);
+		}`})
+	container.CreateTrigger(cosmos.TTrigger{ID: "limit", TriggerType: cosmos.TriggerTypePost, TriggerOperation: cosmos.TriggerOperationAll,
+		Body: `function () {
+			var order = getContext().getResponse().getBody();
+			if (order.total > 1000) throw new Error("order too large");
+		}`})
+	container.CreateUserDefinedFunction(cosmos.TUserDefinedFunction{ID: "gross", Body: `function (net) { return Math.round(net * 119) / 100; }`})
+
+	container.Options.PreTriggers = []string{"stamp"}
+	container.Options.PostTriggers = []string{"limit"}
+	status, body := container.CreateDocument(false, `{"id":"1","customer":"c1","total":100}`)
+	if status != "201 Created" || !strings.Contains(body, `"status":"new"`) {
+		t.Errorf("CreateDocument() with triggers = %v, %v", status, body)
+	}
+	status, body = container.CreateDocument(false, `{"id":"2","customer":"c1","total":5000}`)
+	if status != "400 Bad Request" || !strings.Contains(body, "order too large") {
+		t.Errorf("CreateDocument() post-trigger exception = %v, %v", status, body)
+	}
+	container.Options = cosmos.TRequestOptions{}
+	if status, _ := container.GetDocumentByID("2"); status != "404 Not Found" {
+		t.Errorf("GetDocumentByID() after rollback = %v", status)
+	}
+
+	status, body, _ = container.ExecuteQuerry(0, "", cosmos.TQuery{Query: "SELECT VALUE udf.gross(c.total) FROM c"})
+	if status != "200 OK" || !strings.Contains(body, `"Documents":[119]`) {
+		t.Errorf("ExecuteQuerry() with udf = %v, %v", status, body)
+	}
+}
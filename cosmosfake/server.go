@@ -0,0 +1,310 @@
+/*
+package cosmosfake
+In-memory cosmos db rest api server for offline tests
+
+	fake := cosmosfake.ServerFactory("")
+	defer fake.Close()
+	fake.CreateContainer("db", "user", "/tenant")
+	database := cosmos_db_restapi.DatabaseFactory(fake.EndpointUri, fake.MasterKey, "db")
+
+MemoryServerFactory creates a server without listener for unit tests, its Database uses an in process transport
+*/
+package cosmosfake
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultMasterKey - the well known key of the cosmos db emulator
+const DefaultMasterKey = "C2y6yDjf5/R+ob0N8A7Cgv30VRDJIWEHLM+4QDU9DE2nQ9nDuVTqobD4b8mGGyPMbIZnqyMsEcaGQy67XIw/Jw=="
+
+// DefaultMaxItemCount - page size of feeds and queries without x-ms-max-item-count
+const DefaultMaxItemCount = 100
+
+// MaxClockSkew - requests with an x-ms-date further off the server time are rejected
+const MaxClockSkew = 15 * time.Minute
+
+// TServer - a cosmos db account in memory, served via httptest or without network via Transport
+type TServer struct {
+	EndpointUri string           `json:"endpoint_uri"` //i.e. "http://127.0.0.1:34567/"
+	MasterKey   string           `json:"master_key"`   //requests must be signed with this key
+	Now         func() time.Time `json:"-"`            //the server time, for _ts, ttl and the date check
+
+	server    *httptest.Server
+	mutex     sync.Mutex
+	databases map[string]*tDatabase
+	order     []string //database ids in order of creation
+	sequence  uint64   //source of etags and the order of documents
+	rids      uint64   //source of resource ids
+}
+
+// ServerFactory - starts a fake server, "" for the DefaultMasterKey
+func ServerFactory(master_key string) *TServer {
+	me := newServer(master_key)
+	me.server = httptest.NewServer(me)
+	me.EndpointUri = me.server.URL + "/"
+	return me
+}
+
+func newServer(master_key string) *TServer {
+	if master_key == "" {
+		master_key = DefaultMasterKey
+	}
+	return &TServer{
+		MasterKey: master_key,
+		Now:       time.Now,
+		databases: map[string]*tDatabase{},
+	}
+}
+
+// Close - stops the server, a memory server has nothing to stop
+func (me *TServer) Close() {
+	if me.server != nil {
+		me.server.Close()
+	}
+}
+
+// tRequest - a parsed request
+type tRequest struct {
+	verb          string
+	resource_type string   //i.e. "docs"
+	resource_link string   //the signed link i.e. "dbs/db/colls/user" for the docs feed
+	segments      []string //the path i.e. ["dbs", "db", "colls", "user", "docs"]
+	header        http.Header
+	body          []byte
+}
+
+// tResponse - a response before it is written
+type tResponse struct {
+	status_code int
+	header      http.Header
+	body        interface{} //marshalled to json, nil for no content
+	charge      float64
+}
+
+// tError - the error body of cosmos db
+type tError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// errorResponse - an error response like cosmos db, the code is the status text without blanks
+func errorResponse(status_code int, format string, args ...interface{}) tResponse {
+	return tResponse{
+		status_code: status_code,
+		body: tError{
+			Code:    strings.ReplaceAll(http.StatusText(status_code), " ", ""),
+			Message: fmt.Sprintf(format, args...),
+		},
+	}
+}
+
+// parseRequest - resource type and link of the path, as used for the signature
+func parseRequest(r *http.Request, body []byte) (req tRequest) {
+	req.verb = r.Method
+	req.header = r.Header
+	req.body = body
+	path := strings.Trim(r.URL.Path, "/")
+	if path == "" {
+		return
+	}
+	req.segments = strings.Split(path, "/")
+	count := len(req.segments)
+	switch {
+	case req.segments[0] == "media":
+		req.resource_type = "media"
+		req.resource_link = strings.ToLower(req.segments[count-1]) //signed with the lower case _rid
+	case count%2 == 0:
+		req.resource_type = req.segments[count-2]
+		req.resource_link = path
+	default:
+		req.resource_type = req.segments[count-1]
+		req.resource_link = strings.Join(req.segments[:count-1], "/")
+	}
+	return
+}
+
+// authorize - verifies the master key signature and the date of the request
+func (me *TServer) authorize(req tRequest) *tResponse {
+	date := req.header.Get("x-ms-date")
+	if date == "" {
+		date = req.header.Get("Date")
+	}
+	signed_at, err := parseDate(date)
+	if err != nil {
+		res := errorResponse(http.StatusUnauthorized, "Required Header authorization or x-ms-date is missing or invalid.")
+		return &res
+	}
+	if now := me.Now(); signed_at.Before(now.Add(-MaxClockSkew)) || signed_at.After(now.Add(MaxClockSkew)) {
+		res := errorResponse(http.StatusUnauthorized,
+			"The authorization token is not valid at the current time. Please create another token and retry "+
+				"(token start time: %s, token expiry time: %s, current server time: %s).",
+			signed_at.UTC().Format(http.TimeFormat), signed_at.Add(MaxClockSkew).UTC().Format(http.TimeFormat),
+			now.UTC().Format(http.TimeFormat))
+		return &res
+	}
+
+	//type=master&ver=1.0&sig=..., the signature may contain a "+" which is not a blank here
+	token, _ := url.QueryUnescape(req.header.Get("authorization"))
+	values := map[string]string{}
+	for _, field := range strings.Split(token, "&") {
+		if name, value, found := strings.Cut(field, "="); found {
+			values[name] = value
+		}
+	}
+	key, _ := base64.StdEncoding.DecodeString(me.MasterKey)
+	payload := strings.ToLower(req.verb) + "\n" +
+		strings.ToLower(req.resource_type) + "\n" +
+		req.resource_link + "\n" +
+		strings.ToLower(date) + "\n" +
+		"" + "\n"
+	hash := hmac.New(sha256.New, key)
+	_, _ = hash.Write([]byte(payload))
+	signature, _ := base64.StdEncoding.DecodeString(values["sig"])
+	if values["type"] != "master" || !hmac.Equal(signature, hash.Sum(nil)) {
+		res := errorResponse(http.StatusUnauthorized,
+			"The input authorization token can't serve the request. The wrong key is being used or the expected payload "+
+				"is not built as per the protocol. Server used the following payload to sign: '%s'",
+			strings.ReplaceAll(payload, "\n", "\\n"))
+		return &res
+	}
+	return nil
+}
+
+// parseDate - a http date in any case, the client signs the date in lower case
+func parseDate(date string) (time.Time, error) {
+	fields := strings.Fields(date)
+	for i, field := range fields {
+		switch i {
+		case 0, 2:
+			fields[i] = strings.ToUpper(field[:1]) + strings.ToLower(field[1:])
+		case 5:
+			fields[i] = strings.ToUpper(field)
+		}
+	}
+	return http.ParseTime(strings.Join(fields, " "))
+}
+
+// ServeHTTP - handles a cosmos db rest api request
+func (me *TServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		me.write(w, errorResponse(http.StatusBadRequest, "%s", err.Error()), start)
+		return
+	}
+	req := parseRequest(r, body)
+	if res := me.locked(func() tResponse { me.resolveRids(&req); return tResponse{} }); res.status_code != 0 {
+		me.write(w, res, start)
+		return
+	}
+
+	if res := me.authorize(req); res != nil {
+		me.write(w, *res, start)
+		return
+	}
+
+	me.write(w, me.locked(func() tResponse { return me.route(req) }), start)
+}
+
+/*
+locked - runs the handler under the mutex of the server
+
+a panic of the handler, i.e. a bug in the query evaluation, is answered with
+500 Internal Server Error, the mutex is unlocked for the next requests
+*/
+func (me *TServer) locked(handler func() tResponse) (res tResponse) {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			res = errorResponse(http.StatusInternalServerError, "The fake server failed: %v", recovered)
+		}
+	}()
+	return handler()
+}
+
+// route - the handler of the resource type
+func (me *TServer) route(req tRequest) tResponse {
+	count := len(req.segments)
+	switch {
+	case count == 0 && req.verb == "GET":
+		return me.readAccount()
+	case req.resource_type == "dbs":
+		return me.handleDatabases(req)
+	case req.resource_type == "colls" && count >= 3:
+		return me.handleCollections(req)
+	case req.resource_type == "pkranges" && count == 5:
+		return me.handlePartitionKeyRanges(req)
+	case req.resource_type == "docs" && count >= 5:
+		return me.handleDocuments(req)
+	case scriptFeeds[req.resource_type] != "" && count >= 5:
+		return me.handleScripts(req)
+	}
+	return errorResponse(http.StatusBadRequest, "%s %s is not supported by the fake server", req.verb, strings.Join(req.segments, "/"))
+}
+
+// write - writes the response with the common headers
+func (me *TServer) write(w http.ResponseWriter, res tResponse, start time.Time) {
+	header := w.Header()
+	for key, values := range res.header {
+		header[key] = values
+	}
+	header.Set("Content-Type", "application/json")
+	header.Set("Date", me.Now().UTC().Format(http.TimeFormat))
+	header.Set("x-ms-activity-id", me.activityID())
+	header.Set("x-ms-request-charge", strconv.FormatFloat(res.charge, 'f', 2, 64))
+	header.Set("x-ms-request-duration-ms", strconv.FormatFloat(float64(time.Since(start).Microseconds())/1000, 'f', 3, 64))
+	if res.status_code >= 400 && header.Get("x-ms-substatus") == "" {
+		header.Set("x-ms-substatus", "0")
+	}
+	w.WriteHeader(res.status_code)
+	if res.body != nil && res.status_code != http.StatusNoContent && res.status_code != http.StatusNotModified {
+		data, _ := json.Marshal(res.body)
+		_, _ = w.Write(data)
+	}
+}
+
+// readAccount - the database account with the server as its only region
+func (me *TServer) readAccount() tResponse {
+	location := map[string]string{"name": "Local", "databaseAccountEndpoint": me.EndpointUri}
+	return tResponse{status_code: http.StatusOK, body: map[string]interface{}{
+		"id":                           "cosmosfake",
+		"_rid":                         "cosmosfake",
+		"writableLocations":            []interface{}{location},
+		"readableLocations":            []interface{}{location},
+		"enableMultipleWriteLocations": false,
+		"userConsistencyPolicy":        map[string]interface{}{"defaultConsistencyLevel": "Session"},
+	}}
+}
+
+// nextRid - a new resource id, base64 with "-" instead of "/" like the rids of cosmos db
+func (me *TServer) nextRid() string {
+	me.rids += 1
+	data := make([]byte, 8)
+	binary.BigEndian.PutUint64(data, me.rids)
+	return strings.ReplaceAll(base64.StdEncoding.EncodeToString(data), "/", "-")
+}
+
+// etag - a new etag
+func (me *TServer) etag() string {
+	me.sequence += 1
+	return fmt.Sprintf("\"%08x-0000-0000-0000-%012x\"", me.sequence>>48, me.sequence)
+}
+
+func (me *TServer) activityID() string {
+	return fmt.Sprintf("%08x-0000-4000-8000-%012x", time.Now().UnixNano()&0xffffffff, time.Now().UnixNano()&0xffffffffffff)
+}
@@ -0,0 +1,341 @@
+package cosmosfake_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	cosmos "github.com/jankstar/cosmos_db_restapi"
+	"github.com/jankstar/cosmos_db_restapi/cosmosfake"
+)
+
+func TestDocuments(t *testing.T) {
+	fake := cosmosfake.ServerFactory("")
+	defer fake.Close()
+	fake.CreateContainer("db", "user", "/tenant")
+
+	container := cosmos.ContainerFactory(cosmos.DatabaseFactory(fake.EndpointUri, fake.MasterKey, "db"), "user", "a")
+
+	status, body := container.CreateDocument(false, `{"id":"1","tenant":"a","name":"Zwerg"}`)
+	if status != "201 Created" || container.Meta.ETag == "" || !strings.Contains(body, `"_ts"`) {
+		t.Fatalf("CreateDocument() = %v, %v", status, body)
+	}
+	etag := container.Meta.ETag
+
+	status, _ = container.CreateDocument(false, `{"id":"1","tenant":"a"}`)
+	if status != "409 Conflict" || container.Error == nil || container.Error.Kind != cosmos.ErrorKindDuplicateID {
+		t.Errorf("CreateDocument() duplicate = %v, %+v", status, container.Error)
+	}
+
+	status, _ = container.CreateDocument(false, `{"id":"2","tenant":"b"}`)
+	if status != "400 Bad Request" {
+		t.Errorf("CreateDocument() other partition = %v", status)
+	}
+
+	status, body = container.GetDocumentByID("1")
+	if status != "200 OK" || container.Meta.ETag != etag || !strings.Contains(body, "Zwerg") || container.Meta.SessionToken == "" {
+		t.Errorf("GetDocumentByID() = %v, %v, %+v", status, body, container.Meta)
+	}
+
+	status, _ = container.CreateDocument(true, `{"id":"1","tenant":"a","name":"Riese"}`)
+	if status != "200 OK" || container.Meta.ETag == etag {
+		t.Errorf("CreateDocument() upsert = %v, etag %v", status, container.Meta.ETag)
+	}
+
+	status, _ = container.DeleteDocumentByID("1")
+	if status != "204 No Content" {
+		t.Errorf("DeleteDocumentByID() = %v", status)
+	}
+	status, _ = container.GetDocumentByID("1")
+	if status != "404 Not Found" {
+		t.Errorf("GetDocumentByID() deleted = %v", status)
+	}
+}
+
+func TestQueryPaging(t *testing.T) {
+	fake := cosmosfake.ServerFactory("")
+	defer fake.Close()
+	fake.CreateContainer("db", "user", "/tenant")
+
+	database := cosmos.DatabaseFactory(fake.EndpointUri, fake.MasterKey, "db")
+	for _, data := range []string{
+		`{"id":"1","tenant":"a"}`, `{"id":"2","tenant":"a"}`, `{"id":"3","tenant":"b"}`,
+		`{"id":"4","tenant":"a"}`, `{"id":"5","tenant":"b"}`,
+	} {
+		var document struct {
+			Tenant string `json:"tenant"`
+		}
+		json.Unmarshal([]byte(data), &document)
+		container := cosmos.ContainerFactory(database, "user", document.Tenant)
+		if status, body := container.CreateDocument(false, data); status != "201 Created" {
+			t.Fatalf("CreateDocument() = %v, %v", status, body)
+		}
+	}
+
+	tests := []struct {
+		name         string
+		partitionkey string
+		wantIDs      string
+		wantPages    int
+	}{
+		{"cross partition", "", "1,2,3,4,5", 3},
+		{"partition", "a", "1,2,4", 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			container := cosmos.ContainerFactory(database, "user", tt.partitionkey)
+			container.OpenQuery(2, cosmos.TQuery{Query: "SELECT * FROM c"})
+			var ids []string
+			pages := 0
+			for status, body := container.Fetch(); status == "200 OK"; status, body = container.Fetch() {
+				pages += 1
+				var page cosmos.TBody
+				json.Unmarshal([]byte(body), &page)
+				for _, document := range page.Documents {
+					ids = append(ids, document.(map[string]interface{})["id"].(string))
+				}
+			}
+			if strings.Join(ids, ",") != tt.wantIDs || pages != tt.wantPages {
+				t.Errorf("ids = %v, pages = %v", ids, pages)
+			}
+		})
+	}
+}
+
+func TestAuthorization(t *testing.T) {
+	fake := cosmosfake.ServerFactory("")
+	defer fake.Close()
+	fake.CreateContainer("db", "user", "/tenant")
+
+	wrong_key := "bm90IHRoZSBrZXk="
+	container := cosmos.ContainerFactory(cosmos.DatabaseFactory(fake.EndpointUri, wrong_key, "db"), "user", "a")
+	status, body := container.GetDocumentByID("1")
+	if status != "401 Unauthorized" || !strings.Contains(body, "payload to sign") {
+		t.Errorf("wrong key = %v, %v", status, body)
+	}
+
+	fake.Now = func() time.Time { return time.Now().Add(time.Hour) }
+	database := cosmos.DatabaseFactory(fake.EndpointUri, fake.MasterKey, "db")
+	database.Clock = nil //no correction of the clock skew
+	container = cosmos.ContainerFactory(database, "user", "a")
+	status, body = container.GetDocumentByID("1")
+	if status != "401 Unauthorized" || !strings.Contains(body, "not valid at the current time") {
+		t.Errorf("clock skew = %v, %v", status, body)
+	}
+}
+
+func TestClockSkew(t *testing.T) {
+	fake := cosmosfake.ServerFactory("")
+	defer fake.Close()
+	fake.CreateDatabase("db")
+	fake.CreateContainer("db", "user", "/tenant")
+
+	tests := []struct {
+		name  string
+		skew  time.Duration
+		fixed bool
+	}{
+		{"server ahead", time.Hour, true},
+		{"server behind", -40 * time.Minute, true},
+		{"within the max skew", 5 * time.Minute, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake.Now = func() time.Time { return time.Now().Add(tt.skew) }
+			database := cosmos.DatabaseFactory(fake.EndpointUri, fake.MasterKey, "db")
+			container := cosmos.ContainerFactory(database, "user", "a")
+
+			if status, body := container.CreateDocument(false, `{"id":"1","tenant":"a"}`); status != "201 Created" && status != "409 Conflict" {
+				t.Fatalf("CreateDocument() = %v, %v", status, body)
+			}
+			clock := database.Clock.Export()
+			if clock.Measured < tt.skew-2*time.Second || clock.Measured > tt.skew+2*time.Second {
+				t.Errorf("Measured = %v, want about %v", clock.Measured, tt.skew)
+			}
+			if fixed := clock.Corrections == 1; fixed != tt.fixed {
+				t.Errorf("Corrections = %v, want fixed %v", clock.Corrections, tt.fixed)
+			}
+			if status, _ := container.GetDocumentByID("1"); status != "200 OK" {
+				t.Errorf("GetDocumentByID() = %v", status)
+			}
+			if corrections := database.Clock.Export().Corrections; corrections != clock.Corrections {
+				t.Errorf("Corrections after the correction = %v, want %v", corrections, clock.Corrections)
+			}
+		})
+	}
+}
+
+func TestContainerAndTTL(t *testing.T) {
+	fake := cosmosfake.ServerFactory("")
+	defer fake.Close()
+	fake.CreateDatabase("db")
+	now := time.Now()
+	fake.Now = func() time.Time { return now }
+
+	database := cosmos.DatabaseFactory(fake.EndpointUri, fake.MasterKey, "db")
+	status, body := database.CreateContainer(cosmos.TContainerProperties{
+		ID:           "session",
+		PartitionKey: &cosmos.TPartitionKeyDefinition{Paths: []string{"/user"}, Kind: "Hash"},
+		DefaultTtl:   cosmos.TTL(60),
+	}, 0)
+	if status != "201 Created" {
+		t.Fatalf("CreateContainer() = %v, %v", status, body)
+	}
+	status, _, properties := database.ReadContainer("session")
+	if status != "200 OK" || properties.DefaultTtl == nil || *properties.DefaultTtl != 60 || properties.Rid == "" {
+		t.Errorf("ReadContainer() = %v, %+v", status, properties)
+	}
+	if _, progress := database.ReadIndexTransformationProgress("session"); progress != 100 {
+		t.Errorf("ReadIndexTransformationProgress() = %v", progress)
+	}
+
+	container := cosmos.ContainerFactory(database, "session", "u1")
+	container.CreateDocument(false, `{"id":"short","user":"u1","ttl":10}`)
+	container.CreateDocument(false, `{"id":"default","user":"u1"}`)
+	container.CreateDocument(false, `{"id":"never","user":"u1","ttl":-1}`)
+
+	now = now.Add(30 * time.Second)
+	tests := []struct {
+		id      string
+		advance time.Duration
+		want    string
+	}{
+		{"short", 0, "404 Not Found"},
+		{"default", 0, "200 OK"},
+		{"default", 31 * time.Second, "404 Not Found"},
+		{"never", 10 * time.Minute, "200 OK"}, //within MaxClockSkew of the client
+	}
+	for _, tt := range tests {
+		now = now.Add(tt.advance)
+		if got, _ := container.GetDocumentByID(tt.id); got != tt.want {
+			t.Errorf("GetDocumentByID(%v) = %v, want %v", tt.id, got, tt.want)
+		}
+	}
+
+	if status, _ := database.DeleteContainer("session"); status != "204 No Content" {
+		t.Errorf("DeleteContainer() = %v", status)
+	}
+}
+
+func TestTTLHelpersExpire(t *testing.T) {
+	fake := cosmosfake.ServerFactory("")
+	defer fake.Close()
+	fake.CreateDatabase("db")
+	now := time.Now()
+	fake.Now = func() time.Time { return now }
+
+	database := cosmos.DatabaseFactory(fake.EndpointUri, fake.MasterKey, "db")
+	database.CreateContainer(cosmos.TContainerProperties{
+		ID:           "session",
+		PartitionKey: &cosmos.TPartitionKeyDefinition{Paths: []string{"/user"}, Kind: "Hash"},
+		DefaultTtl:   cosmos.TTL(60),
+	}, 0)
+	container := cosmos.ContainerFactory(database, "session", "u1")
+
+	//SetTTL and ClearTTL on the json of the documents
+	short, _ := cosmos.SetTTL(`{"id":"set","user":"u1"}`, 10)
+	cleared, _ := cosmos.ClearTTL(`{"id":"cleared","user":"u1","ttl":10}`)
+	never, _ := cosmos.SetTTL(`{"id":"never","user":"u1"}`, cosmos.TTLNever)
+	for _, data := range []string{short, cleared, never, `{"id":"patched","user":"u1"}`, `{"id":"unpatched","user":"u1","ttl":10}`} {
+		if status, body := container.CreateDocument(false, data); status != "201 Created" {
+			t.Fatalf("CreateDocument(%v) = %v, %v", data, status, body)
+		}
+	}
+	//the patch operations
+	if status, body := container.PatchDocument("patched", []cosmos.TPatchOperation{cosmos.SetTTLPatch(10)}); status != "200 OK" {
+		t.Fatalf("PatchDocument(SetTTLPatch) = %v, %v", status, body)
+	}
+	if status, body := container.PatchDocument("unpatched", []cosmos.TPatchOperation{cosmos.ClearTTLPatch()}); status != "200 OK" {
+		t.Fatalf("PatchDocument(ClearTTLPatch) = %v, %v", status, body)
+	}
+
+	tests := []struct {
+		advance time.Duration
+		want    map[string]string
+	}{
+		{5 * time.Second, map[string]string{"set": "200 OK", "patched": "200 OK", "cleared": "200 OK", "unpatched": "200 OK"}},
+		{10 * time.Second, map[string]string{"set": "404 Not Found", "patched": "404 Not Found", "cleared": "200 OK", "unpatched": "200 OK"}},
+		{50 * time.Second, map[string]string{"cleared": "404 Not Found", "unpatched": "404 Not Found", "never": "200 OK"}},
+	}
+	for _, tt := range tests {
+		now = now.Add(tt.advance)
+		for id, want := range tt.want {
+			if got, _ := container.GetDocumentByID(id); got != want {
+				t.Errorf("after %v GetDocumentByID(%v) = %v, want %v", tt.advance, id, got, want)
+			}
+		}
+	}
+}
+
+func TestPartitionKeyRanges(t *testing.T) {
+	fake := cosmosfake.ServerFactory("")
+	defer fake.Close()
+	fake.CreateContainer("db", "user", "/tenant")
+
+	date := strings.ToLower(time.Now().UTC().Format(http.TimeFormat))
+	req, _ := http.NewRequest("GET", fake.EndpointUri+"dbs/db/colls/user/pkranges", nil)
+	req.Header.Set("x-ms-date", date)
+	req.Header.Set("authorization", cosmos.GetAuthorizationTokenUsingMasterKey("GET", "pkranges", "dbs/db/colls/user", date, fake.MasterKey))
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	var feed struct {
+		PartitionKeyRanges []struct {
+			ID           string `json:"id"`
+			MaxExclusive string `json:"maxExclusive"`
+		} `json:"PartitionKeyRanges"`
+	}
+	json.NewDecoder(res.Body).Decode(&feed)
+	if res.StatusCode != http.StatusOK || len(feed.PartitionKeyRanges) != 1 || feed.PartitionKeyRanges[0].MaxExclusive != "FF" {
+		t.Errorf("pkranges = %v, %+v", res.Status, feed)
+	}
+}
+
+func TestReadMany(t *testing.T) {
+	fake := cosmosfake.ServerFactory("")
+	defer fake.Close()
+	fake.CreateContainer("db", "user", "/tenant")
+
+	database := cosmos.DatabaseFactory(fake.EndpointUri, fake.MasterKey, "db")
+	container := cosmos.ContainerFactory(database, "user", "a")
+	container.CreateDocument(false, `{"id":"1","tenant":"a"}`)
+	container.CreateDocument(false, `{"id":"2","tenant":"a"}`)
+
+	results, charge := container.ReadMany([]cosmos.TItemIdentity{
+		{ID: "1", PartitionKey: "a"}, {ID: "2", PartitionKey: "a"}, {ID: "3", PartitionKey: "a"}, {ID: "1", PartitionKey: "b"},
+	}, 2)
+	var found []bool
+	for _, result := range results {
+		found = append(found, result.Found)
+	}
+	if len(results) != 4 || !found[0] || !found[1] || found[2] || found[3] || charge == 0 {
+		t.Errorf("ReadMany() = %+v, %v", results, charge)
+	}
+}
+
+func TestDo(t *testing.T) {
+	fake := cosmosfake.ServerFactory("")
+	defer fake.Close()
+	fake.CreateContainer("db", "user", "/tenant")
+	database := cosmos.DatabaseFactory(fake.EndpointUri, fake.MasterKey, "db")
+
+	header := http.Header{}
+	header.Set("x-ms-documentdb-partitionkey", `["a"]`)
+	if res := database.Do(context.Background(), "POST", "docs", "dbs/db/colls/user", header, []byte(`{"id":"Zwerg 1","tenant":"a"}`)); res.Status != "201 Created" {
+		t.Errorf("Do() create = %v, %v", res.Status, res.Body)
+	}
+	if res := database.Do(context.Background(), "GET", "docs", "dbs/db/colls/user/docs/Zwerg 1", header, nil); res.Status != "200 OK" || res.Meta.ETag == "" {
+		t.Errorf("Do() read = %v, %v", res.Status, res.Body)
+	}
+	if res := database.Do(context.Background(), "GET", "pkranges", "dbs/db/colls/user", nil, nil); res.Status != "200 OK" || !strings.Contains(res.Body, "PartitionKeyRanges") {
+		t.Errorf("Do() pkranges = %v, %v", res.Status, res.Body)
+	}
+	if res := database.Do(context.Background(), "GET", "colls", "dbs/db/colls/missing", nil, nil); res.Error == nil || res.Error.Kind != cosmos.ErrorKindNotFound {
+		t.Errorf("Do() missing = %v, %v", res.Status, res.Error)
+	}
+}
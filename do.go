@@ -0,0 +1,171 @@
+package cosmos_db_restapi
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultMaxRetries - retries of Do for throttled and transient responses
+const DefaultMaxRetries = 9
+
+// DefaultMaxRetryWait - the maximum time Do waits for retries in total
+const DefaultMaxRetryWait = 30 * time.Second
+
+// ResourceTypes - the resource types of the cosmos db rest api, Do accepts other types, too
+var ResourceTypes = []string{"dbs", "colls", "docs", "sprocs", "udfs", "triggers", "users", "permissions",
+	"offers", "pkranges", "conflicts", "attachments", "media"}
+
+// ridResourceTypes - resources which are always addressed by _rid
+var ridResourceTypes = map[string]bool{"offers": true, "media": true}
+
+// TRequestDiagnostics - diagnostics of a request sent with Do, over all attempts
+type TRequestDiagnostics struct {
+	Attempts      int           `json:"attempts"`       //requests sent, 1 without retries
+	Retried       []int         `json:"retried"`        //status codes of the retried attempts, 0 for network errors
+	RequestCharge float64       `json:"request_charge"` //RU of all attempts
+	ActivityIDs   []string      `json:"activity_ids"`   //x-ms-activity-id of each attempt
+	Waited        time.Duration `json:"waited"`         //time waited for retries
+	Duration      time.Duration `json:"duration"`       //client side duration including the waits
+	KeyInUse      string        `json:"key_in_use"`     //KeyPrimary, KeySecondary or KeyMaster
+	ClockOffset   time.Duration `json:"clock_offset"`   //offset of the local time used to sign
+}
+
+// TRawResponse - response of Do
+type TRawResponse struct {
+	Status      string              `json:"status"`      //i.e. "200 OK"
+	Body        string              `json:"body"`        //the response body as string
+	Header      http.Header         `json:"header"`      //the response headers
+	Meta        TResponseMeta       `json:"meta"`        //metadata of the last attempt
+	Diagnostics TRequestDiagnostics `json:"diagnostics"` //all attempts
+	Error       *TCosmosError       `json:"error"`       //error of the response, nil on success
+}
+
+/*
+ParseResourceLink - the resource link of a string i.e. "dbs/db/colls/coll/docs/id"
+
+the link alternates resource types and ids, the ids must pass ValidateID,
+offers and media are addressed by _rid, i.e. "offers/AbCd"
+*/
+func ParseResourceLink(resource_link string) (Link TResourceLink, err error) {
+	resource_link = strings.Trim(resource_link, "/")
+	if resource_link == "" {
+		return
+	}
+	segments := strings.Split(resource_link, "/")
+	if len(segments)%2 != 0 {
+		return Link, fmt.Errorf("the resource link %q must alternate resource types and ids", resource_link)
+	}
+	Link.by_rid = ridResourceTypes[segments[0]]
+	for i := 0; i < len(segments); i += 2 {
+		Link = Link.Child(segments[i], segments[i+1])
+	}
+	return Link, Link.Err()
+}
+
+/*
+Do - signs and sends a request for any resource of the cosmos db, for endpoints this package does not wrap
+
+parameters:
+
+	ctx - context of the request and of the waits between retries
+	verb - http method i.e. "GET", "POST", "PUT", "PATCH" or "DELETE"
+	resource_type - type of the resource i.e. "dbs", "colls", "docs", "users", "permissions" or "offers", see ResourceTypes
+	resource_link - link of the resource i.e. "dbs/db/users/user", for a feed the link of the parent, i.e. "dbs/db" to list or create users
+	header - additional request headers i.e. x-ms-documentdb-partitionkey or nil
+	body - request body or nil
+
+returns:
+
+	Response - status, body, headers and metadata of the response, the diagnostics of all attempts
+
+the common headers, the session token, endpoint routing, key rotation and clock skew are handled
+like for all operations of the package, responses 429 Too Many Requests and 449 Retry With, and
+network errors of reads, are retried after x-ms-retry-after-ms up to MaxRetries times and MaxRetryWait
+*/
+func (me *TDatabase) Do(ctx context.Context, verb string, resource_type string, resource_link string, header http.Header, body []byte) (Response TRawResponse) {
+	start := time.Now()
+	var res tResponse
+	defer func() {
+		Response.Status, Response.Body, Response.Header, Response.Meta = res.Status, res.Body, res.Header, res.Meta
+		Response.Error = responseError(res)
+		Response.Diagnostics.Duration = time.Since(start)
+		Response.Diagnostics.KeyInUse = me.KeyInUse()
+		Response.Diagnostics.ClockOffset = me.Clock.Offset()
+	}()
+
+	verb = strings.ToUpper(verb)
+	link, err := ParseResourceLink(resource_link)
+	if err == nil && resource_type == "" {
+		err = fmt.Errorf("the resource type is required")
+	}
+	if err != nil {
+		res = errorResponse(http.StatusBadRequest, "BadRequest", err)
+		return
+	}
+	path := link.Path()
+	if link.ResourceType() != resource_type {
+		path = link.Feed(resource_type)
+	}
+	if header == nil {
+		header = http.Header{}
+	}
+
+	max_retries, max_wait := me.retryLimits()
+	for {
+		res = me.send(ctx, verb, resource_type, link.Link(), path, header, body)
+		Response.Diagnostics.Attempts += 1
+		Response.Diagnostics.RequestCharge += res.Meta.RequestCharge
+		if res.Header != nil {
+			Response.Diagnostics.ActivityIDs = append(Response.Diagnostics.ActivityIDs, res.Header.Get("x-ms-activity-id"))
+		}
+
+		wait, retry := retryAfter(verb, header, res)
+		if !retry || ctx.Err() != nil || len(Response.Diagnostics.Retried) >= max_retries || Response.Diagnostics.Waited+wait > max_wait {
+			return
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		Response.Diagnostics.Retried = append(Response.Diagnostics.Retried, res.Meta.StatusCode)
+		Response.Diagnostics.Waited += wait
+	}
+}
+
+// retryLimits - MaxRetries and MaxRetryWait or the defaults
+func (me *TDatabase) retryLimits() (int, time.Duration) {
+	max_retries, max_wait := me.MaxRetries, me.MaxRetryWait
+	if max_retries == 0 {
+		max_retries = DefaultMaxRetries
+	}
+	if max_wait == 0 {
+		max_wait = DefaultMaxRetryWait
+	}
+	return max_retries, max_wait
+}
+
+/*
+retryAfter - the wait before a response is retried
+
+429 and 449 are retried after x-ms-retry-after-ms, network errors only for reads,
+as a write may have been executed
+*/
+func retryAfter(verb string, header http.Header, res tResponse) (time.Duration, bool) {
+	wait := time.Duration(res.Meta.RetryAfterInMs) * time.Millisecond
+	switch {
+	case res.Meta.StatusCode == http.StatusTooManyRequests, res.Meta.StatusCode == 449:
+		if wait == 0 {
+			wait = 100 * time.Millisecond
+		}
+		return wait, true
+	case res.Meta.StatusCode == 0 && res.Err != nil:
+		return 100 * time.Millisecond, isReadRequest(verb, header)
+	}
+	return 0, false
+}
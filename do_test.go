@@ -0,0 +1,141 @@
+package cosmos_db_restapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDo(t *testing.T) {
+	key := "a2V5a2V5aw=="
+	var mutex sync.Mutex
+	var signed_link, path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		path = r.URL.EscapedPath()
+		token, _ := AuthorizationToken(r.Method, r.Header.Get("x-test-type"), signed_link, r.Header.Get("x-ms-date"), key)
+		if r.Header.Get("authorization") != token {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("x-ms-request-charge", "1.5")
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+	database := DatabaseFactory(server.URL+"/", key, "db")
+
+	tests := []struct {
+		verb          string
+		resource_type string
+		resource_link string
+		wantLink      string
+		wantPath      string
+	}{
+		{"GET", "dbs", "", "", "/dbs"},
+		{"GET", "dbs", "dbs/Shop", "dbs/Shop", "/dbs/Shop"},
+		{"POST", "colls", "dbs/Shop", "dbs/Shop", "/dbs/Shop/colls"},
+		{"GET", "docs", "dbs/Shop/colls/Order/docs/a b", "dbs/Shop/colls/Order/docs/a b", "/dbs/Shop/colls/Order/docs/a%20b"},
+		{"GET", "users", "dbs/Shop", "dbs/Shop", "/dbs/Shop/users"},
+		{"PUT", "permissions", "dbs/Shop/users/Anna/permissions/read", "dbs/Shop/users/Anna/permissions/read", "/dbs/Shop/users/Anna/permissions/read"},
+		{"GET", "offers", "", "", "/offers"},
+		{"PUT", "offers", "offers/AbCd", "abcd", "/offers/AbCd"},
+		{"GET", "pkranges", "/dbs/Shop/colls/Order/", "dbs/Shop/colls/Order", "/dbs/Shop/colls/Order/pkranges"},
+		{"post", "udfs", "dbs/Shop/colls/Order", "dbs/Shop/colls/Order", "/dbs/Shop/colls/Order/udfs"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.verb+" "+tt.resource_type+" "+tt.resource_link, func(t *testing.T) {
+			mutex.Lock()
+			signed_link = tt.wantLink
+			mutex.Unlock()
+			header := http.Header{}
+			header.Set("x-test-type", tt.resource_type)
+			res := database.Do(context.Background(), tt.verb, tt.resource_type, tt.resource_link, header, nil)
+			if res.Status != "200 OK" || path != tt.wantPath || res.Error != nil {
+				t.Errorf("Do() = %v, path %v", res.Status, path)
+			}
+			if res.Diagnostics.Attempts != 1 || res.Diagnostics.RequestCharge != 1.5 || res.Diagnostics.KeyInUse != KeyMaster {
+				t.Errorf("Diagnostics = %+v", res.Diagnostics)
+			}
+		})
+	}
+
+	for _, resource_link := range []string{"dbs/Shop/colls", "dbs/Shop/colls/a?b", "dbs//colls/x"} {
+		if res := database.Do(context.Background(), "GET", "colls", resource_link, nil, nil); res.Status != "400 Bad Request" || res.Diagnostics.Attempts != 0 {
+			t.Errorf("Do(%q) = %v, attempts %v", resource_link, res.Status, res.Diagnostics.Attempts)
+		}
+	}
+}
+
+func TestDoRetries(t *testing.T) {
+	var mutex sync.Mutex
+	throttled := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		if throttled > 0 {
+			throttled -= 1
+			w.Header().Set("x-ms-retry-after-ms", "5")
+			w.Header().Set("x-ms-activity-id", "throttled")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Header().Set("x-ms-activity-id", "ok")
+		w.Write([]byte(`{"id":"1"}`))
+	}))
+	defer server.Close()
+	throttle := func(count int) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		throttled = count
+	}
+
+	tests := []struct {
+		name         string
+		throttled    int
+		max_retries  int
+		max_wait     time.Duration
+		wantStatus   string
+		wantAttempts int
+	}{
+		{"no throttling", 0, 0, 0, "200 OK", 1},
+		{"retried", 2, 0, 0, "200 OK", 3},
+		{"max retries", 5, 2, 0, "429 Too Many Requests", 3},
+		{"no retries", 1, -1, 0, "429 Too Many Requests", 1},
+		{"max wait", 5, 0, 12 * time.Millisecond, "429 Too Many Requests", 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			throttle(tt.throttled)
+			database := DatabaseFactory(server.URL+"/", "a2V5", "db")
+			database.MaxRetries, database.MaxRetryWait = tt.max_retries, tt.max_wait
+			res := database.Do(context.Background(), "GET", "docs", "dbs/db/colls/coll/docs/1", nil, nil)
+			if res.Status != tt.wantStatus || res.Diagnostics.Attempts != tt.wantAttempts || len(res.Diagnostics.ActivityIDs) != tt.wantAttempts {
+				t.Errorf("Do() = %v, diagnostics %+v", res.Status, res.Diagnostics)
+			}
+			if len(res.Diagnostics.Retried) != tt.wantAttempts-1 || res.Diagnostics.Waited != time.Duration(tt.wantAttempts-1)*5*time.Millisecond {
+				t.Errorf("Retried = %v, Waited = %v", res.Diagnostics.Retried, res.Diagnostics.Waited)
+			}
+		})
+	}
+
+	//the context ends the waits
+	throttle(100)
+	database := DatabaseFactory(server.URL+"/", "a2V5", "db")
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if res := database.Do(ctx, "GET", "docs", "dbs/db/colls/coll/docs/1", nil, nil); res.Meta.StatusCode == http.StatusOK || res.Diagnostics.Duration > time.Second {
+		t.Errorf("Do() with timeout = %v, %v", res.Status, res.Diagnostics.Duration)
+	}
+
+	//a write is not retried after a network error, it may have been executed
+	server.Close()
+	res := database.Do(context.Background(), "POST", "docs", "dbs/db/colls/coll", nil, []byte(`{"id":"1"}`))
+	if res.Diagnostics.Attempts != 1 || res.Error == nil || !strings.Contains(res.Body, "connect") {
+		t.Errorf("Do() POST = %v, %v, attempts %v", res.Status, res.Body, res.Diagnostics.Attempts)
+	}
+}
@@ -0,0 +1,114 @@
+package cosmos_db_restapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// TErrorKind - classification of a cosmos db error response
+type TErrorKind string
+
+const (
+	ErrorKindRequest            TErrorKind = "request"              //no response, i.e. network error
+	ErrorKindBadRequest         TErrorKind = "bad_request"          //400
+	ErrorKindUnauthorized       TErrorKind = "unauthorized"         //401
+	ErrorKindForbidden          TErrorKind = "forbidden"            //403
+	ErrorKindNotFound           TErrorKind = "not_found"            //404
+	ErrorKindDuplicateID        TErrorKind = "duplicate_id"         //409, a document with the id exists
+	ErrorKindUniqueKeyViolation TErrorKind = "unique_key_violation" //409, a unique key of the container is violated
+	ErrorKindConflict           TErrorKind = "conflict"             //409, other conflicts
+	ErrorKindPreconditionFailed TErrorKind = "precondition_failed"  //412, etag mismatch
+	ErrorKindTooLarge           TErrorKind = "too_large"            //413
+	ErrorKindThrottled          TErrorKind = "throttled"            //429
+	ErrorKindServer             TErrorKind = "server"               //5xx and others
+)
+
+// TCosmosError - an error response of the cosmos db
+type TCosmosError struct {
+	StatusCode    int        `json:"status_code"`
+	SubStatusCode int        `json:"sub_status_code"`
+	Code          string     `json:"code"`    //i.e. "Conflict"
+	Message       string     `json:"message"` //message of the response body
+	Kind          TErrorKind `json:"kind"`
+}
+
+// Error - "409 Conflict (unique_key_violation): message"
+func (me *TCosmosError) Error() string {
+	text := strconv.Itoa(me.StatusCode) + " " + http.StatusText(me.StatusCode)
+	if me.StatusCode == 0 {
+		text = "request failed"
+	}
+	return text + " (" + string(me.Kind) + "): " + me.Message
+}
+
+/*
+ParseError - the error of a response of an operation
+
+parameters:
+
+	status - response status i.e. "409 Conflict"
+	body - response body as string
+
+returns:
+
+	nil for a successful response, else the error with its kind
+*/
+func ParseError(status string, body string) *TCosmosError {
+	code, _ := strconv.Atoi(strings.SplitN(status, " ", 2)[0])
+	if code >= 200 && code < 300 {
+		return nil
+	}
+	return newCosmosError(code, 0, body)
+}
+
+// newCosmosError - the error of a status code and a response body
+func newCosmosError(status_code int, sub_status_code int, body string) *TCosmosError {
+	var content TBody
+	err := &TCosmosError{StatusCode: status_code, SubStatusCode: sub_status_code, Message: body}
+	if json.Unmarshal([]byte(body), &content) == nil && (content.Code != "" || content.Message != "") {
+		err.Code = content.Code
+		err.Message = content.Message
+	}
+
+	switch status_code {
+	case 0:
+		err.Kind = ErrorKindRequest
+	case http.StatusBadRequest:
+		err.Kind = ErrorKindBadRequest
+	case http.StatusUnauthorized:
+		err.Kind = ErrorKindUnauthorized
+	case http.StatusForbidden:
+		err.Kind = ErrorKindForbidden
+	case http.StatusNotFound:
+		err.Kind = ErrorKindNotFound
+	case http.StatusConflict:
+		message := strings.ToLower(err.Message)
+		switch {
+		case strings.Contains(message, "unique index constraint"):
+			err.Kind = ErrorKindUniqueKeyViolation
+		case strings.Contains(message, "id already exists"):
+			err.Kind = ErrorKindDuplicateID
+		default:
+			err.Kind = ErrorKindConflict
+		}
+	case http.StatusPreconditionFailed:
+		err.Kind = ErrorKindPreconditionFailed
+	case http.StatusRequestEntityTooLarge:
+		err.Kind = ErrorKindTooLarge
+	case http.StatusTooManyRequests:
+		err.Kind = ErrorKindThrottled
+	default:
+		err.Kind = ErrorKindServer
+	}
+	return err
+}
+
+// responseError - the error of a response sent via the pipeline, nil on success
+func responseError(res tResponse) *TCosmosError {
+	if res.Meta.StatusCode >= 200 && res.Meta.StatusCode < 300 {
+		return nil
+	}
+	return newCosmosError(res.Meta.StatusCode, res.Meta.SubStatusCode, res.Body)
+}
@@ -0,0 +1,76 @@
+package cosmos_db_restapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseError(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   string
+		body     string
+		wantNil  bool
+		wantKind TErrorKind
+	}{
+		{"created", "201 Created", `{"id":"1"}`, true, ""},
+		{"duplicate id", "409 Conflict", `{"code":"Conflict","message":"Entity with the specified id already exists in the system., RequestStartTime: ..."}`, false, ErrorKindDuplicateID},
+		{"unique key", "409 Conflict", `{"code":"Conflict","message":"Message: {\"Errors\":[\"Unique index constraint violation.\"]}"}`, false, ErrorKindUniqueKeyViolation},
+		{"other conflict", "409 Conflict", `{"code":"Conflict","message":"Resource with specified id or name already exists."}`, false, ErrorKindConflict},
+		{"not found", "404 Not Found", `{"code":"NotFound","message":"Entity with the specified id does not exist in the system."}`, false, ErrorKindNotFound},
+		{"throttled", "429 Too Many Requests", ``, false, ErrorKindThrottled},
+		{"network error", "", `dial tcp: connection refused`, false, ErrorKindRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseError(tt.status, tt.body)
+			if (got == nil) != tt.wantNil {
+				t.Fatalf("ParseError() = %v, want nil %v", got, tt.wantNil)
+			}
+			if got != nil && got.Kind != tt.wantKind {
+				t.Errorf("ParseError().Kind = %v, want %v", got.Kind, tt.wantKind)
+			}
+		})
+	}
+}
+
+func TestCreateDocumentConflictKind(t *testing.T) {
+	message := ""
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if message == "" {
+			w.WriteHeader(http.StatusCreated)
+			return
+		}
+		w.WriteHeader(http.StatusConflict)
+		w.Write([]byte(`{"code":"Conflict","message":"` + message + `"}`))
+	}))
+	defer server.Close()
+
+	container := ContainerFactory(DatabaseFactory(server.URL+"/", "", "db"), "user", "tenant")
+
+	tests := []struct {
+		name     string
+		message  string
+		wantKind TErrorKind
+	}{
+		{"created", "", ""},
+		{"duplicate id", "Entity with the specified id already exists in the system.", ErrorKindDuplicateID},
+		{"unique key", "Unique index constraint violation.", ErrorKindUniqueKeyViolation},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			message = tt.message
+			container.CreateDocument(false, `{"id":"1","email":"a@b.c"}`)
+			if tt.wantKind == "" {
+				if container.Error != nil {
+					t.Errorf("Error = %v, want nil", container.Error)
+				}
+				return
+			}
+			if container.Error == nil || container.Error.Kind != tt.wantKind {
+				t.Errorf("Error = %v, want kind %v", container.Error, tt.wantKind)
+			}
+		})
+	}
+}
@@ -0,0 +1,262 @@
+package cosmos_db_restapi
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DefaultFailureThreshold - consecutive failures after which an endpoint is unhealthy
+const DefaultFailureThreshold = 3
+
+// DefaultProbeInterval - interval for probing an unhealthy endpoint
+const DefaultProbeInterval = 30 * time.Second
+
+// TEndpointEventKind - kind of a TEndpointEvent
+type TEndpointEventKind string
+
+const (
+	EndpointUnavailable TEndpointEventKind = "unavailable" //the endpoint was marked unhealthy
+	EndpointFailover    TEndpointEventKind = "failover"    //a request was sent to the next endpoint
+	EndpointRecovered   TEndpointEventKind = "recovered"   //a probe succeeded, the endpoint is used again
+)
+
+// TEndpointEvent - a change of the health of a regional endpoint
+type TEndpointEvent struct {
+	Kind     TEndpointEventKind `json:"kind"`
+	Endpoint string             `json:"endpoint"`
+	Next     string             `json:"next"` //the endpoint used instead, for failover events
+	Time     time.Time          `json:"time"`
+	Err      error              `json:"-"` //the last error, if any
+}
+
+// tEndpointState - health of one endpoint
+type tEndpointState struct {
+	failures  int
+	unhealthy bool
+	probing   bool //a probe runs for the endpoint
+	since     time.Time
+}
+
+// tEndpointHealth - health of all endpoints, shared by copies of a TDatabase
+type tEndpointHealth struct {
+	mutex     sync.Mutex
+	endpoints map[string]*tEndpointState
+	ctx       context.Context    //ends with Close, created on demand
+	cancel    context.CancelFunc //cancels the probes
+	closed    bool
+}
+
+// context - the context of the probes, done after Close
+func (me *tEndpointHealth) context() context.Context {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	if me.ctx == nil {
+		me.ctx, me.cancel = context.WithCancel(context.Background())
+		if me.closed {
+			me.cancel()
+		}
+	}
+	return me.ctx
+}
+
+func (me *tEndpointHealth) state(endpoint string) *tEndpointState {
+	if me.endpoints == nil {
+		me.endpoints = map[string]*tEndpointState{}
+	}
+	state := me.endpoints[endpoint]
+	if state == nil {
+		state = &tEndpointState{}
+		me.endpoints[endpoint] = state
+	}
+	return state
+}
+
+// healthy - false for an endpoint marked unhealthy
+func (me *tEndpointHealth) healthy(endpoint string) bool {
+	if me == nil {
+		return true
+	}
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	state := me.endpoints[endpoint]
+	return state == nil || !state.unhealthy
+}
+
+// UnhealthyEndpoints - the endpoints currently marked unhealthy
+func (me *TDatabase) UnhealthyEndpoints() (Endpoints []string) {
+	if me.health == nil {
+		return
+	}
+	me.health.mutex.Lock()
+	defer me.health.mutex.Unlock()
+	for endpoint, state := range me.health.endpoints {
+		if state.unhealthy {
+			Endpoints = append(Endpoints, endpoint)
+		}
+	}
+	return
+}
+
+// isEndpointFailure - network errors and unavailable services, which justify trying another region
+func isEndpointFailure(res tResponse) bool {
+	if res.Meta.StatusCode == 0 {
+		return res.Err != nil
+	}
+	return res.Meta.StatusCode == http.StatusServiceUnavailable ||
+		(res.Meta.StatusCode == http.StatusForbidden && res.Meta.SubStatusCode == 1008) //account not found in the region
+}
+
+/*
+recordResult - counts consecutive failures of an endpoint, after
+FailureThreshold failures the endpoint is marked unhealthy and probed
+in the background until it responds again
+*/
+func (me *TDatabase) recordResult(endpoint string, res tResponse) (Failed bool) {
+	Failed = isEndpointFailure(res)
+	if me.health == nil {
+		return
+	}
+	threshold := me.FailureThreshold
+	if threshold <= 0 {
+		threshold = DefaultFailureThreshold
+	}
+
+	me.health.mutex.Lock()
+	state := me.health.state(endpoint)
+	if !Failed {
+		state.failures = 0
+		me.health.mutex.Unlock()
+		return
+	}
+	state.failures += 1
+	mark := !state.unhealthy && state.failures >= threshold
+	if mark {
+		state.unhealthy = true
+		state.since = time.Now()
+	}
+	probe := state.unhealthy && !state.probing && !me.health.closed
+	if probe {
+		state.probing = true
+	}
+	me.health.mutex.Unlock()
+
+	if mark {
+		me.emitEndpointEvent(TEndpointEvent{Kind: EndpointUnavailable, Endpoint: endpoint, Err: res.Err})
+	}
+	if probe {
+		go me.probeEndpoint(endpoint)
+	}
+	return
+}
+
+/*
+probeEndpoint - reads the database account via the endpoint until it succeeds, then fails back
+
+one probe runs per endpoint, it ends when the endpoint is healthy again,
+when it is no longer one of the Endpoints or with Close
+*/
+func (me *TDatabase) probeEndpoint(endpoint string) {
+	interval := me.ProbeInterval
+	if interval <= 0 {
+		interval = DefaultProbeInterval
+	}
+	done := me.health.context()
+	probe := *me
+	probe.Charge = nil //the probes are not requests of the application
+	defer func() {
+		me.health.mutex.Lock()
+		me.health.state(endpoint).probing = false
+		me.health.mutex.Unlock()
+	}()
+
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+	for {
+		select {
+		case <-done.Done():
+			return
+		case <-timer.C:
+		}
+		if me.health.healthy(endpoint) {
+			return
+		}
+		if !me.knowsEndpoint(endpoint) {
+			me.health.mutex.Lock()
+			delete(me.health.endpoints, endpoint)
+			me.health.mutex.Unlock()
+			return
+		}
+		ctx, cancel := context.WithTimeout(done, interval)
+		res := probe.sendTo(ctx, endpoint, "GET", "", "", "", http.Header{}, nil)
+		cancel()
+		if res.Meta.StatusCode == http.StatusOK {
+			break
+		}
+		timer.Reset(interval)
+	}
+
+	me.health.mutex.Lock()
+	state := me.health.state(endpoint)
+	state.unhealthy = false
+	state.failures = 0
+	me.health.mutex.Unlock()
+
+	me.emitEndpointEvent(TEndpointEvent{Kind: EndpointRecovered, Endpoint: endpoint})
+}
+
+// knowsEndpoint - true for the endpoint of the account and the current regional endpoints
+func (me *TDatabase) knowsEndpoint(endpoint string) bool {
+	if endpoint == me.EndpointUri {
+		return true
+	}
+	read, write := me.Endpoints()
+	for _, known := range append(read, write...) {
+		if known == endpoint {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+Close - stops the probes of unhealthy endpoints, the probes of all copies of the database
+
+the database can still be used, unhealthy endpoints stay at the end of the endpoints
+*/
+func (me *TDatabase) Close() {
+	if me.health == nil {
+		return
+	}
+	me.health.mutex.Lock()
+	defer me.health.mutex.Unlock()
+	me.health.closed = true
+	if me.health.cancel != nil {
+		me.health.cancel()
+	}
+}
+
+func (me *TDatabase) emitEndpointEvent(event TEndpointEvent) {
+	if me.OnEndpointEvent == nil {
+		return
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	me.OnEndpointEvent(event)
+}
+
+// preferHealthy - the healthy endpoints first, the order is kept otherwise
+func (me *TDatabase) preferHealthy(endpoints []string) []string {
+	healthy := make([]string, 0, len(endpoints))
+	var unhealthy []string
+	for _, endpoint := range endpoints {
+		if me.health.healthy(endpoint) {
+			healthy = append(healthy, endpoint)
+		} else {
+			unhealthy = append(unhealthy, endpoint)
+		}
+	}
+	return append(healthy, unhealthy...)
+}
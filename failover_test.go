@@ -0,0 +1,167 @@
+package cosmos_db_restapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRegionalFailover(t *testing.T) {
+	var north_down int32 = 1
+	var mutex sync.Mutex
+	hits := map[string]int{}
+	count := func(name string) {
+		mutex.Lock()
+		hits[name] += 1
+		mutex.Unlock()
+	}
+	north := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		count("north")
+		if atomic.LoadInt32(&north_down) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer north.Close()
+	west := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		count("west")
+	}))
+	defer west.Close()
+
+	account := `{"writableLocations":[{"name":"West Europe","databaseAccountEndpoint":"` + west.URL + `/"}],
+		"readableLocations":[{"name":"North Europe","databaseAccountEndpoint":"` + north.URL + `/"},
+			{"name":"West Europe","databaseAccountEndpoint":"` + west.URL + `/"}]}`
+	global := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(account))
+	}))
+	defer global.Close()
+
+	events := make(chan TEndpointEvent, 10)
+	database := DatabaseFactory(global.URL+"/", "", "db")
+	database.EnableEndpointDiscovery = true
+	database.FailureThreshold = 2
+	database.ProbeInterval = 10 * time.Millisecond
+	database.OnEndpointEvent = func(event TEndpointEvent) { events <- event }
+	container := ContainerFactory(database, "coll", "pk")
+
+	//two reads fail over to west, then north is unhealthy
+	for i := 0; i < 2; i++ {
+		if gotStatus, _ := container.GetDocumentByID("1"); gotStatus != "200 OK" {
+			t.Fatalf("GetDocumentByID() gotStatus = %v, want 200 OK", gotStatus)
+		}
+	}
+	wantKinds := []TEndpointEventKind{EndpointFailover, EndpointUnavailable, EndpointFailover}
+	for _, want := range wantKinds {
+		if event := <-events; event.Kind != want {
+			t.Errorf("event = %v, want %v", event.Kind, want)
+		}
+	}
+	if unhealthy := database.UnhealthyEndpoints(); len(unhealthy) != 1 || unhealthy[0] != north.URL+"/" {
+		t.Errorf("UnhealthyEndpoints() = %v", unhealthy)
+	}
+
+	//the region recovers, the probe fails back
+	atomic.StoreInt32(&north_down, 0)
+	select {
+	case event := <-events:
+		if event.Kind != EndpointRecovered || event.Endpoint != north.URL+"/" {
+			t.Errorf("event = %+v, want recovered north", event)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no recovered event")
+	}
+	if read, _ := database.Endpoints(); read[0] != north.URL+"/" {
+		t.Errorf("Endpoints() read = %v, want north first", read)
+	}
+}
+
+func TestProbeEnds(t *testing.T) {
+	var probes int32
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&probes, 1)
+		w.Header().Set("x-ms-request-charge", "1")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+	var mutex sync.Mutex
+	account := `{"readableLocations":[{"name":"North Europe","databaseAccountEndpoint":"` + down.URL + `/"}]}`
+	global := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		w.Write([]byte(account))
+	}))
+	defer global.Close()
+
+	probing := func(database TDatabase) bool {
+		database.health.mutex.Lock()
+		defer database.health.mutex.Unlock()
+		state := database.health.endpoints[down.URL+"/"]
+		return state != nil && state.probing
+	}
+	waitFor := func(name string, done func() bool) {
+		for deadline := time.Now().Add(2 * time.Second); !done(); time.Sleep(5 * time.Millisecond) {
+			if time.Now().After(deadline) {
+				t.Fatalf("%s: timeout", name)
+			}
+		}
+	}
+
+	//without endpoint discovery the failures are not counted
+	database := DatabaseFactory(down.URL+"/", "", "db")
+	database.FailureThreshold = 1
+	database.ProbeInterval = 5 * time.Millisecond
+	container := ContainerFactory(database, "coll", "pk")
+	container.GetDocumentByID("1")
+	container.GetDocumentByID("1")
+	if unhealthy := database.UnhealthyEndpoints(); len(unhealthy) != 0 || probing(database) {
+		t.Errorf("UnhealthyEndpoints() without discovery = %v", unhealthy)
+	}
+
+	//Close stops the probe, the probes are not charged
+	database = DatabaseFactory(global.URL+"/", "", "db")
+	database.EnableEndpointDiscovery = true
+	database.FailureThreshold = 1
+	database.ProbeInterval = 5 * time.Millisecond
+	if err := database.RefreshTopology(); err != nil {
+		t.Fatalf("RefreshTopology() = %v", err)
+	}
+	container = ContainerFactory(database, "coll", "pk")
+	atomic.StoreInt32(&probes, 0)
+	container.GetDocumentByID("1")
+	waitFor("probes", func() bool { return atomic.LoadInt32(&probes) > 3 })
+	database.Close()
+	waitFor("closed", func() bool { return !probing(database) })
+	count := atomic.LoadInt32(&probes)
+	time.Sleep(30 * time.Millisecond)
+	if atomic.LoadInt32(&probes) != count {
+		t.Errorf("probes after Close() = %v, want %v", atomic.LoadInt32(&probes), count)
+	}
+	if total := database.Charge.Total(); total != 1 {
+		t.Errorf("Charge.Total() = %v, want the charge of the read only", total)
+	}
+
+	//an endpoint no longer in the topology is not probed
+	database = DatabaseFactory(global.URL+"/", "", "db")
+	database.EnableEndpointDiscovery = true
+	database.FailureThreshold = 1
+	database.ProbeInterval = 5 * time.Millisecond
+	defer database.Close()
+	if err := database.RefreshTopology(); err != nil {
+		t.Fatalf("RefreshTopology() = %v", err)
+	}
+	container = ContainerFactory(database, "coll", "pk")
+	container.GetDocumentByID("1")
+	waitFor("unhealthy", func() bool { return probing(database) })
+	mutex.Lock()
+	account = `{"readableLocations":[{"name":"West Europe","databaseAccountEndpoint":"` + global.URL + `/"}]}`
+	mutex.Unlock()
+	if err := database.RefreshTopology(); err != nil {
+		t.Fatalf("RefreshTopology() = %v", err)
+	}
+	waitFor("removed", func() bool { return !probing(database) })
+	if unhealthy := database.UnhealthyEndpoints(); len(unhealthy) != 0 {
+		t.Errorf("UnhealthyEndpoints() = %v, want none", unhealthy)
+	}
+}
@@ -0,0 +1,304 @@
+package cosmos_db_restapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// TFaultKind - the fault a rule injects
+type TFaultKind string
+
+const (
+	FaultThrottle        TFaultKind = "throttle"         //429 with x-ms-retry-after-ms
+	FaultUnavailable     TFaultKind = "unavailable"      //503
+	FaultTimeout         TFaultKind = "timeout"          //408
+	FaultGone            TFaultKind = "gone"             //410 with the sub status of the rule, default 1002
+	FaultConnectionReset TFaultKind = "connection_reset" //the request fails with a connection reset by peer
+	FaultLatency         TFaultKind = "latency"          //the request is sent after the latency of the rule
+	FaultTruncatedBody   TFaultKind = "truncated_body"   //the response body ends after TruncateAt bytes
+)
+
+/*
+TFaultRule - a fault and the requests it is injected into
+
+the empty conditions match all requests, i.e. a rule with only the kind FaultUnavailable
+and the Endpoint of a region simulates an outage of the region
+*/
+type TFaultRule struct {
+	Name string     `json:"name"` //name for the hit counter, unique within the injector
+	Kind TFaultKind `json:"kind"`
+
+	Operation    string  `json:"operation"`     //i.e. "Query docs" or only the verb "Query", see operationName
+	ResourceLink string  `json:"resource_link"` //prefix of the resource link i.e. "dbs/db/colls/user"
+	PartitionKey string  `json:"partition_key"` //value of the partition key header i.e. "a"
+	Endpoint     string  `json:"endpoint"`      //prefix of the url i.e. the endpoint of a region
+	Probability  float64 `json:"probability"`   //0 < p < 1 injects the fault by chance, else always
+	MaxHits      int     `json:"max_hits"`      //the rule is ignored after the hits, 0 for unlimited
+
+	SubStatus  int           `json:"sub_status"`  //x-ms-substatus of the status faults
+	RetryAfter time.Duration `json:"retry_after"` //x-ms-retry-after-ms of FaultThrottle, default 100ms
+	Latency    time.Duration `json:"latency"`     //delay of FaultLatency
+	TruncateAt int           `json:"truncate_at"` //bytes of the body kept by FaultTruncatedBody
+}
+
+// tFaultRule - a rule with its hits
+type tFaultRule struct {
+	TFaultRule
+	hits int
+}
+
+/*
+TFaultInjector - a http.RoundTripper which injects faults into the requests of the client, safe for concurrent use
+
+	injector := FaultInjectorFactory(nil)
+	injector.AddRule(TFaultRule{Name: "throttle", Kind: FaultThrottle, Operation: "Query"})
+	database.Transport = injector
+
+the first matching rule is applied, requests without a rule are sent via Transport
+*/
+type TFaultInjector struct {
+	Transport http.RoundTripper //the transport of the requests, default http.DefaultTransport
+	Random    func() float64    //random numbers [0, 1) for the probability, default rand.Float64
+
+	mutex    sync.Mutex
+	rules    []*tFaultRule
+	hits     map[string]int
+	requests int
+}
+
+// FaultInjectorFactory - creates a fault injector without rules, transport nil for http.DefaultTransport
+func FaultInjectorFactory(transport http.RoundTripper) *TFaultInjector {
+	return &TFaultInjector{Transport: transport, hits: map[string]int{}}
+}
+
+// AddRule - adds a rule after the existing rules, a rule with the same name is replaced
+func (me *TFaultInjector) AddRule(rule TFaultRule) {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	for _, existing := range me.rules {
+		if existing.Name == rule.Name {
+			existing.TFaultRule = rule
+			return
+		}
+	}
+	me.rules = append(me.rules, &tFaultRule{TFaultRule: rule})
+}
+
+// RemoveRule - removes the rule, the hits are kept
+func (me *TFaultInjector) RemoveRule(name string) {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	for i, rule := range me.rules {
+		if rule.Name == name {
+			me.rules = append(me.rules[:i:i], me.rules[i+1:]...)
+			return
+		}
+	}
+}
+
+// Clear - removes all rules and resets the counters
+func (me *TFaultInjector) Clear() {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	me.rules = nil
+	me.hits = map[string]int{}
+	me.requests = 0
+}
+
+// Hits - the number of requests the rule injected its fault into
+func (me *TFaultInjector) Hits(name string) int {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	return me.hits[name]
+}
+
+// Requests - the number of requests seen by the injector, with and without fault
+func (me *TFaultInjector) Requests() int {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	return me.requests
+}
+
+// RoundTrip - applies the first matching rule to the request
+func (me *TFaultInjector) RoundTrip(req *http.Request) (*http.Response, error) {
+	rule := me.match(req)
+	transport := me.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	if rule == nil {
+		return transport.RoundTrip(req)
+	}
+
+	switch rule.Kind {
+	case FaultLatency:
+		timer := time.NewTimer(rule.Latency)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-req.Context().Done():
+			closeBody(req)
+			return nil, req.Context().Err()
+		}
+		return transport.RoundTrip(req)
+	case FaultConnectionReset:
+		closeBody(req)
+		return nil, &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}
+	case FaultTruncatedBody:
+		res, err := transport.RoundTrip(req)
+		if err != nil {
+			return res, err
+		}
+		data, _ := io.ReadAll(res.Body)
+		res.Body.Close()
+		if rule.TruncateAt < len(data) {
+			data = data[:rule.TruncateAt]
+		}
+		res.Body = &tTruncatedBody{Reader: bytes.NewReader(data)}
+		return res, nil
+	case FaultThrottle:
+		retry_after := rule.RetryAfter
+		if retry_after == 0 {
+			retry_after = 100 * time.Millisecond
+		}
+		res := faultResponse(req, http.StatusTooManyRequests, rule.SubStatus,
+			"Request rate is large. More Request Units may be needed, so no changes were made. Please retry this request later.")
+		res.Header.Set("x-ms-retry-after-ms", strconv.FormatInt(retry_after.Milliseconds(), 10))
+		return res, nil
+	case FaultUnavailable:
+		return faultResponse(req, http.StatusServiceUnavailable, rule.SubStatus, "The service is currently unavailable."), nil
+	case FaultTimeout:
+		return faultResponse(req, http.StatusRequestTimeout, rule.SubStatus, "The request timed out."), nil
+	case FaultGone:
+		sub_status := rule.SubStatus
+		if sub_status == 0 {
+			sub_status = subStatusPartitionKeyRangeGone
+		}
+		return faultResponse(req, http.StatusGone, sub_status, "The requested resource is no longer available at the server."), nil
+	}
+	closeBody(req)
+	return nil, fmt.Errorf("unknown fault kind %q of rule %q", rule.Kind, rule.Name)
+}
+
+// match - the first rule of the request, its hit is counted
+func (me *TFaultInjector) match(req *http.Request) *tFaultRule {
+	resource_type, resource_link := resourceOfPath(req.URL.Path)
+	operation := operationName(req.Method, resource_type, req.Header)
+
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	me.requests += 1
+	for _, rule := range me.rules {
+		if rule.MaxHits > 0 && rule.hits >= rule.MaxHits {
+			continue
+		}
+		if rule.Operation != "" && rule.Operation != operation && !strings.HasPrefix(operation, rule.Operation+" ") {
+			continue
+		}
+		if rule.ResourceLink != "" && !strings.HasPrefix(resource_link, strings.Trim(rule.ResourceLink, "/")) {
+			continue
+		}
+		if rule.PartitionKey != "" && !matchPartitionKey(req.Header.Get("x-ms-documentdb-partitionkey"), rule.PartitionKey) {
+			continue
+		}
+		if rule.Endpoint != "" && !strings.HasPrefix(req.URL.String(), rule.Endpoint) {
+			continue
+		}
+		if rule.Probability > 0 && rule.Probability < 1 {
+			random := me.Random
+			if random == nil {
+				random = rand.Float64
+			}
+			if random() >= rule.Probability {
+				continue
+			}
+		}
+		rule.hits += 1
+		if me.hits == nil {
+			me.hits = map[string]int{}
+		}
+		me.hits[rule.Name] += 1
+		return rule
+	}
+	return nil
+}
+
+// resourceOfPath - resource type and link of an url path as used for the signature
+func resourceOfPath(path string) (resource_type string, resource_link string) {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return "", ""
+	}
+	segments := strings.Split(path, "/")
+	count := len(segments)
+	switch {
+	case segments[0] == "media":
+		return "media", segments[count-1]
+	case count%2 == 0:
+		return segments[count-2], path
+	}
+	return segments[count-1], strings.Join(segments[:count-1], "/")
+}
+
+// matchPartitionKey - the partition key header is the value, i.e. [ "a" ] for "a"
+func matchPartitionKey(header string, value string) bool {
+	var values []interface{}
+	if err := json.Unmarshal([]byte(header), &values); err != nil || len(values) != 1 {
+		return header == value
+	}
+	return fmt.Sprint(values[0]) == value
+}
+
+// faultResponse - a response of the cosmos db with an error body
+func faultResponse(req *http.Request, status_code int, sub_status int, message string) *http.Response {
+	closeBody(req)
+	body, _ := json.Marshal(TBody{Code: strings.ReplaceAll(http.StatusText(status_code), " ", ""), Message: message})
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	header.Set("x-ms-substatus", strconv.Itoa(sub_status))
+	header.Set("x-ms-request-charge", "0")
+	return &http.Response{
+		Status:        strconv.Itoa(status_code) + " " + http.StatusText(status_code),
+		StatusCode:    status_code,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+func closeBody(req *http.Request) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+}
+
+// tTruncatedBody - the kept bytes of a body, then io.ErrUnexpectedEOF like a broken connection
+type tTruncatedBody struct {
+	*bytes.Reader
+}
+
+func (me *tTruncatedBody) Read(p []byte) (int, error) {
+	n, err := me.Reader.Read(p)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+func (me *tTruncatedBody) Close() error {
+	return nil
+}
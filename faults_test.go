@@ -0,0 +1,107 @@
+package cosmos_db_restapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestFaultInjector(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id":"1","name":"Zwerg"}`))
+	}))
+	defer server.Close()
+
+	injector := FaultInjectorFactory(nil)
+	database := DatabaseFactory(server.URL+"/", "", "db")
+	database.Transport = injector
+	container := ContainerFactory(database, "user", "a")
+	other := ContainerFactory(database, "user", "b")
+
+	tests := []struct {
+		name       string
+		rule       TFaultRule
+		call       func() (string, string)
+		wantStatus string
+		wantSub    int
+		wantHits   int
+	}{
+		{"throttle reads", TFaultRule{Kind: FaultThrottle, Operation: "Read", RetryAfter: time.Second},
+			func() (string, string) { return container.GetDocumentByID("1") }, "429 Too Many Requests", 0, 1},
+		{"throttle other operation", TFaultRule{Kind: FaultThrottle, Operation: "Delete docs"},
+			func() (string, string) { return container.GetDocumentByID("1") }, "200 OK", 0, 0},
+		{"unavailable container", TFaultRule{Kind: FaultUnavailable, ResourceLink: "dbs/db/colls/user"},
+			func() (string, string) { return container.CreateDocument(false, `{"id":"1"}`) }, "503 Service Unavailable", 0, 1},
+		{"timeout of other container", TFaultRule{Kind: FaultTimeout, ResourceLink: "dbs/db/colls/word"},
+			func() (string, string) { return container.GetDocumentByID("1") }, "200 OK", 0, 0},
+		{"gone of partition key", TFaultRule{Kind: FaultGone, PartitionKey: "a"},
+			func() (string, string) { return container.GetDocumentByID("1") }, "410 Gone", 1002, 1},
+		{"gone of other partition key", TFaultRule{Kind: FaultGone, PartitionKey: "a", SubStatus: 1007},
+			func() (string, string) { return other.GetDocumentByID("1") }, "200 OK", 0, 0},
+		{"timeout by chance", TFaultRule{Kind: FaultTimeout, Probability: 0.3},
+			func() (string, string) { return container.GetDocumentByID("1") }, "408 Request Timeout", 0, 1},
+		{"no timeout by chance", TFaultRule{Kind: FaultTimeout, Probability: 0.2},
+			func() (string, string) { return container.GetDocumentByID("1") }, "200 OK", 0, 0},
+		{"regional outage", TFaultRule{Kind: FaultUnavailable, Endpoint: server.URL, MaxHits: 1},
+			func() (string, string) { return container.GetDocumentByID("1") }, "503 Service Unavailable", 0, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			injector.Clear()
+			injector.Random = func() float64 { return 0.25 }
+			tt.rule.Name = tt.name
+			injector.AddRule(tt.rule)
+			status, _ := tt.call()
+			if status != tt.wantStatus || (tt.wantSub != 0 && container.Meta.SubStatusCode != tt.wantSub) {
+				t.Errorf("status = %v, sub status = %v, want %v, %v", status, container.Meta.SubStatusCode, tt.wantStatus, tt.wantSub)
+			}
+			if got := injector.Hits(tt.name); got != tt.wantHits || injector.Requests() != 1 {
+				t.Errorf("Hits() = %v, Requests() = %v, want %v", got, injector.Requests(), tt.wantHits)
+			}
+		})
+	}
+
+	injector.Clear()
+	injector.AddRule(TFaultRule{Name: "throttle", Kind: FaultThrottle, RetryAfter: 2 * time.Second})
+	container.GetDocumentByID("1")
+	if container.Meta.RetryAfterInMs != 2000 || container.Error == nil || container.Error.Kind != ErrorKindThrottled {
+		t.Errorf("throttle meta = %+v, %v", container.Meta, container.Error)
+	}
+
+	injector.Clear()
+	injector.AddRule(TFaultRule{Name: "reset", Kind: FaultConnectionReset, MaxHits: 1})
+	res := database.send(context.Background(), "GET", "docs", "dbs/db/colls/user/docs/1", "dbs/db/colls/user/docs/1", nil, nil)
+	if !errors.Is(res.Err, syscall.ECONNRESET) {
+		t.Errorf("connection reset = %v", res.Err)
+	}
+	if status, _ := container.GetDocumentByID("1"); status != "200 OK" || injector.Hits("reset") != 1 {
+		t.Errorf("after MaxHits = %v, %v", status, injector.Hits("reset"))
+	}
+
+	injector.Clear()
+	injector.AddRule(TFaultRule{Name: "truncate", Kind: FaultTruncatedBody, TruncateAt: 5})
+	res = database.send(context.Background(), "GET", "docs", "dbs/db/colls/user/docs/1", "dbs/db/colls/user/docs/1", nil, nil)
+	if res.Body != `{"id"` || res.Err == nil {
+		t.Errorf("truncated body = %v, %v", res.Body, res.Err)
+	}
+
+	injector.Clear()
+	injector.AddRule(TFaultRule{Name: "latency", Kind: FaultLatency, Latency: 50 * time.Millisecond})
+	if status, _ := container.GetDocumentByID("1"); status != "200 OK" || container.Meta.Duration < 50*time.Millisecond {
+		t.Errorf("latency = %v, %v", status, container.Meta.Duration)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	res = database.send(ctx, "GET", "docs", "dbs/db/colls/user/docs/1", "dbs/db/colls/user/docs/1", nil, nil)
+	if !errors.Is(res.Err, context.DeadlineExceeded) {
+		t.Errorf("latency with deadline = %v", res.Err)
+	}
+	injector.RemoveRule("latency")
+	if status, _ := container.GetDocumentByID("1"); status != "200 OK" || injector.Hits("latency") != 2 {
+		t.Errorf("after RemoveRule = %v, %v", status, injector.Hits("latency"))
+	}
+}
@@ -0,0 +1,147 @@
+package cosmos_db_restapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	IndexingModeConsistent = "consistent"
+	IndexingModeNone       = "none"
+)
+
+// TIndexPath - an included or excluded path, i.e. "/*" or "/snippet/?"
+type TIndexPath struct {
+	Path string `json:"path"`
+}
+
+// TCompositePath - a path of a composite index with "ascending" or "descending" order
+type TCompositePath struct {
+	Path  string `json:"path"`
+	Order string `json:"order,omitempty"`
+}
+
+// TSpatialIndex - spatial index of a path with the types "Point", "LineString", "Polygon" and "MultiPolygon"
+type TSpatialIndex struct {
+	Path  string   `json:"path"`
+	Types []string `json:"types"`
+}
+
+// TVectorIndex - vector index of a path with the type "flat", "quantizedFlat" or "diskANN"
+type TVectorIndex struct {
+	Path string `json:"path"`
+	Type string `json:"type"`
+}
+
+/*
+TIndexingPolicy - the indexing policy of a container
+
+	{
+		"indexingMode": "consistent",
+		"includedPaths": [{"path": "/*"}],
+		"excludedPaths": [{"path": "/snippet/?"}],
+		"compositeIndexes": [[{"path": "/word", "order": "ascending"}, {"path": "/created_at", "order": "descending"}]]
+	}
+*/
+type TIndexingPolicy struct {
+	IndexingMode     string             `json:"indexingMode,omitempty"`
+	Automatic        *bool              `json:"automatic,omitempty"`
+	IncludedPaths    []TIndexPath       `json:"includedPaths,omitempty"`
+	ExcludedPaths    []TIndexPath       `json:"excludedPaths,omitempty"`
+	CompositeIndexes [][]TCompositePath `json:"compositeIndexes,omitempty"`
+	SpatialIndexes   []TSpatialIndex    `json:"spatialIndexes,omitempty"`
+	VectorIndexes    []TVectorIndex     `json:"vectorIndexes,omitempty"`
+}
+
+// TVectorEmbedding - a vector path of the container, required for vector indexes
+type TVectorEmbedding struct {
+	Path             string `json:"path"`
+	DataType         string `json:"dataType"`         //i.e. "float32"
+	DistanceFunction string `json:"distanceFunction"` //"cosine", "dotproduct" or "euclidean"
+	Dimensions       int    `json:"dimensions"`
+}
+
+// TVectorEmbeddingPolicy - the vector paths of a container
+type TVectorEmbeddingPolicy struct {
+	VectorEmbeddings []TVectorEmbedding `json:"vectorEmbeddings"`
+}
+
+/*
+ReplaceContainer - replace the properties of a container via rest api, i.e. a new indexing policy
+
+the id and the partition key can not be changed, a changed indexing policy
+starts an index transformation in the background
+
+returns:
+
+	Status - response status i.e. 200 ok
+	Body - response body as string
+*/
+func (me *TDatabase) ReplaceContainer(properties TContainerProperties) (Status string, Body string) {
+	data, err := json.Marshal(properties)
+	if err != nil {
+		res := errorResponse(http.StatusBadRequest, "BadRequest", err)
+		return res.Status, res.Body
+	}
+	res := me.sendResource(context.Background(), "PUT", me.databaseLink().Child("colls", properties.ID), nil, data)
+	return res.Status, res.Body
+}
+
+/*
+ReadIndexTransformationProgress - read the progress of the index transformation of a container
+
+returns:
+
+	Status - response status i.e. 200 ok
+	Progress - 0 to 100 percent, 100 if no transformation is running, -1 if unknown
+*/
+func (me *TDatabase) ReadIndexTransformationProgress(container string) (Status string, Progress int) {
+	header := http.Header{}
+	header.Set("x-ms-documentdb-populatequotainfo", "True")
+	res := me.sendResource(context.Background(), "GET", me.databaseLink().Child("colls", container), header, nil)
+	Progress = -1
+	if res.Header != nil {
+		if value, err := strconv.Atoi(res.Header.Get("x-ms-documentdb-collection-index-transformation-progress")); err == nil {
+			Progress = value
+		}
+	}
+	return res.Status, Progress
+}
+
+/*
+WaitForIndexTransformation - polls the progress until the index transformation is complete
+
+parameters:
+
+	ctx - context with the maximum time to wait
+	container - name of the container
+	poll_interval - time between two reads of the progress, else 5 seconds
+	on_progress - optional, called with each progress value
+*/
+func (me *TDatabase) WaitForIndexTransformation(ctx context.Context, container string, poll_interval time.Duration, on_progress func(progress int)) error {
+	if poll_interval <= 0 {
+		poll_interval = 5 * time.Second
+	}
+	for {
+		status, progress := me.ReadIndexTransformationProgress(container)
+		if !strings.HasPrefix(status, "200") {
+			return fmt.Errorf("read index transformation progress: %s", status)
+		}
+		if on_progress != nil {
+			on_progress(progress)
+		}
+		if progress >= 100 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(poll_interval):
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package cosmos_db_restapi
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestReplaceContainerIndexingPolicy(t *testing.T) {
+	var got_method string
+	var got TContainerProperties
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got_method = r.Method
+		data, _ := ioutil.ReadAll(r.Body)
+		json.Unmarshal(data, &got)
+		w.Write(data)
+	}))
+	defer server.Close()
+
+	database := DatabaseFactory(server.URL+"/", "", "db")
+	status, _ := database.ReplaceContainer(TContainerProperties{
+		ID: "dictionary",
+		IndexingPolicy: &TIndexingPolicy{
+			IndexingMode:  IndexingModeConsistent,
+			IncludedPaths: []TIndexPath{{Path: "/*"}},
+			ExcludedPaths: []TIndexPath{{Path: "/snippet/?"}},
+			CompositeIndexes: [][]TCompositePath{{
+				{Path: "/word", Order: "ascending"},
+				{Path: "/created_at", Order: "descending"},
+			}},
+			SpatialIndexes: []TSpatialIndex{{Path: "/location/*", Types: []string{"Point"}}},
+			VectorIndexes:  []TVectorIndex{{Path: "/embedding", Type: "flat"}},
+		},
+		VectorEmbeddingPolicy: &TVectorEmbeddingPolicy{VectorEmbeddings: []TVectorEmbedding{
+			{Path: "/embedding", DataType: "float32", DistanceFunction: "cosine", Dimensions: 3},
+		}},
+	})
+	if status != "200 OK" || got_method != "PUT" {
+		t.Fatalf("ReplaceContainer() = %v, method %v", status, got_method)
+	}
+	policy := got.IndexingPolicy
+	if policy == nil || policy.CompositeIndexes[0][1].Order != "descending" || policy.SpatialIndexes[0].Types[0] != "Point" ||
+		policy.VectorIndexes[0].Type != "flat" || got.VectorEmbeddingPolicy.VectorEmbeddings[0].Dimensions != 3 {
+		t.Errorf("indexing policy = %+v", policy)
+	}
+}
+
+func TestWaitForIndexTransformation(t *testing.T) {
+	progress := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("x-ms-documentdb-populatequotainfo") != "True" {
+			t.Errorf("missing x-ms-documentdb-populatequotainfo")
+		}
+		progress += 50
+		w.Header().Set("x-ms-documentdb-collection-index-transformation-progress", strconv.Itoa(progress))
+		w.Write([]byte(`{"id":"dictionary"}`))
+	}))
+	defer server.Close()
+
+	database := DatabaseFactory(server.URL+"/", "", "db")
+	var seen []int
+	err := database.WaitForIndexTransformation(context.Background(), "dictionary", time.Millisecond, func(progress int) {
+		seen = append(seen, progress)
+	})
+	if err != nil || len(seen) != 2 || seen[1] != 100 {
+		t.Errorf("WaitForIndexTransformation() = %v, progress %v", err, seen)
+	}
+
+	progress = -1000 //never complete
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := database.WaitForIndexTransformation(ctx, "dictionary", time.Millisecond, nil); err != context.DeadlineExceeded {
+		t.Errorf("WaitForIndexTransformation() = %v, want deadline exceeded", err)
+	}
+}
@@ -0,0 +1,204 @@
+package cosmos_db_restapi
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// names of the keys of a TKeyProvider
+const (
+	KeyPrimary   = "primary"
+	KeySecondary = "secondary"
+	KeyMaster    = "master" //the MasterKey of a database without key provider
+)
+
+// DefaultKeyWatchInterval - interval of WatchFiles to read the key files
+const DefaultKeyWatchInterval = 30 * time.Second
+
+/*
+TKeyProvider - the primary and secondary key of the account, safe for concurrent use
+
+requests are signed with the key in use, on 401 Unauthorized the request is sent once more
+with the other key, which is used from then on if it is accepted, so the keys can be rotated
+without downtime: regenerate the secondary key, set it, regenerate the primary key, set it
+*/
+type TKeyProvider struct {
+	OnKeyChange func(name string) `json:"-"` //optional, called when the key in use changes
+	OnError     func(err error)   `json:"-"` //optional, errors of WatchFiles
+
+	mutex     sync.RWMutex
+	primary   string
+	secondary string
+	in_use    string
+}
+
+/*
+KeyProviderFactory - creates a key provider which uses the primary key
+
+parameters:
+
+	primary - the primary key, base64 encoded
+	secondary - the secondary key or ""
+
+returns an error if a key is not base64 encoded
+*/
+func KeyProviderFactory(primary string, secondary string) (*TKeyProvider, error) {
+	me := &TKeyProvider{}
+	if err := me.SetKeys(primary, secondary); err != nil {
+		return nil, err
+	}
+	return me, nil
+}
+
+/*
+SetKeys - replaces the keys while requests are sent
+
+the key in use is kept if it is still set, else the other key is used
+*/
+func (me *TKeyProvider) SetKeys(primary string, secondary string) error {
+	primary, secondary = strings.TrimSpace(primary), strings.TrimSpace(secondary)
+	if primary == "" && secondary == "" {
+		return fmt.Errorf("a primary or secondary key is required")
+	}
+	for name, key := range map[string]string{KeyPrimary: primary, KeySecondary: secondary} {
+		if _, err := base64.StdEncoding.DecodeString(key); err != nil {
+			return fmt.Errorf("the %s key is not base64 encoded: %w", name, err)
+		}
+	}
+	me.mutex.Lock()
+	me.primary, me.secondary = primary, secondary
+	previous := me.in_use
+	if me.in_use == "" || me.key(me.in_use) == "" {
+		me.in_use = KeyPrimary
+		if primary == "" {
+			me.in_use = KeySecondary
+		}
+	}
+	changed := me.in_use != previous && previous != ""
+	me.mutex.Unlock()
+	if changed && me.OnKeyChange != nil {
+		me.OnKeyChange(me.InUse())
+	}
+	return nil
+}
+
+// Use - signs the requests with the key KeyPrimary or KeySecondary
+func (me *TKeyProvider) Use(name string) error {
+	me.mutex.Lock()
+	if name != KeyPrimary && name != KeySecondary {
+		me.mutex.Unlock()
+		return fmt.Errorf("unknown key %q", name)
+	}
+	if me.key(name) == "" {
+		me.mutex.Unlock()
+		return fmt.Errorf("the %s key is not set", name)
+	}
+	changed := me.in_use != name
+	me.in_use = name
+	me.mutex.Unlock()
+	if changed && me.OnKeyChange != nil {
+		me.OnKeyChange(name)
+	}
+	return nil
+}
+
+// InUse - the name of the key the requests are signed with, KeyPrimary or KeySecondary
+func (me *TKeyProvider) InUse() string {
+	me.mutex.RLock()
+	defer me.mutex.RUnlock()
+	return me.in_use
+}
+
+// Key - name and value of the key in use
+func (me *TKeyProvider) Key() (Name string, Key string) {
+	me.mutex.RLock()
+	defer me.mutex.RUnlock()
+	return me.in_use, me.key(me.in_use)
+}
+
+// alternate - name and value of the other key, "" if it is not set
+func (me *TKeyProvider) alternate(name string) (string, string) {
+	me.mutex.RLock()
+	defer me.mutex.RUnlock()
+	if name == KeyPrimary {
+		return KeySecondary, me.secondary
+	}
+	return KeyPrimary, me.primary
+}
+
+func (me *TKeyProvider) key(name string) string {
+	if name == KeySecondary {
+		return me.secondary
+	}
+	return me.primary
+}
+
+/*
+WatchFiles - reads the keys from files, i.e. a mounted secret, and sets them when the files change
+
+parameters:
+
+	primary_path - file of the primary key
+	secondary_path - file of the secondary key or ""
+	interval - interval to read the files, 0 for DefaultKeyWatchInterval
+
+returns:
+
+	stop - stops watching
+	err - the files can not be read or the keys are invalid, then nothing is watched
+
+errors while watching are passed to OnError, the keys are kept then
+*/
+func (me *TKeyProvider) WatchFiles(primary_path string, secondary_path string, interval time.Duration) (stop func(), err error) {
+	if interval <= 0 {
+		interval = DefaultKeyWatchInterval
+	}
+	read := func() (string, string, error) {
+		primary, err := os.ReadFile(primary_path)
+		if err != nil {
+			return "", "", err
+		}
+		var secondary []byte
+		if secondary_path != "" {
+			if secondary, err = os.ReadFile(secondary_path); err != nil {
+				return "", "", err
+			}
+		}
+		return string(primary), string(secondary), nil
+	}
+	primary, secondary, err := read()
+	if err == nil {
+		err = me.SetKeys(primary, secondary)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	done := make(chan struct{})
+	var once sync.Once
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+			next_primary, next_secondary, err := read()
+			if err == nil && (next_primary != primary || next_secondary != secondary) {
+				if err = me.SetKeys(next_primary, next_secondary); err == nil {
+					primary, secondary = next_primary, next_secondary
+				}
+			}
+			if err != nil && me.OnError != nil {
+				me.OnError(err)
+			}
+		}
+	}()
+	return func() { once.Do(func() { close(done) }) }, nil
+}
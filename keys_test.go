@@ -0,0 +1,142 @@
+package cosmos_db_restapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestKeyProvider(t *testing.T) {
+	primary, secondary, rotated := "cHJpbWFyeQ==", "c2Vjb25kYXJ5", "cm90YXRlZA=="
+	var mutex sync.Mutex
+	accepted := primary
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		requests += 1
+		resource_type, resource_link := resourceOfPath(r.URL.Path)
+		token, _ := AuthorizationToken(r.Method, resource_type, resource_link, r.Header.Get("x-ms-date"), accepted)
+		if r.Header.Get("authorization") != token {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"code":"Unauthorized","message":"The input authorization token can't serve the request."}`))
+			return
+		}
+		w.Write([]byte(`{"id":"1"}`))
+	}))
+	defer server.Close()
+	accept := func(key string) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		accepted, requests = key, 0
+	}
+
+	keys, err := KeyProviderFactory(primary, secondary)
+	if err != nil {
+		t.Fatalf("KeyProviderFactory() = %v", err)
+	}
+	var changes []string
+	keys.OnKeyChange = func(name string) { changes = append(changes, name) }
+	database := DatabaseFactory(server.URL+"/", "", "db")
+	database.Keys = keys
+	container := ContainerFactory(database, "user", "a")
+
+	tests := []struct {
+		name         string
+		accept       string
+		wantStatus   string
+		wantRequests int
+		wantInUse    string
+	}{
+		{"primary", primary, "200 OK", 1, KeyPrimary},
+		{"primary regenerated", secondary, "200 OK", 2, KeySecondary},
+		{"secondary in use", secondary, "200 OK", 1, KeySecondary},
+		{"both keys invalid", rotated, "401 Unauthorized", 2, KeySecondary},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			accept(tt.accept)
+			status, _ := container.GetDocumentByID("1")
+			if status != tt.wantStatus || requests != tt.wantRequests || database.KeyInUse() != tt.wantInUse {
+				t.Errorf("status = %v, requests = %v, key = %v", status, requests, database.KeyInUse())
+			}
+		})
+	}
+	if len(changes) != 1 || changes[0] != KeySecondary {
+		t.Errorf("OnKeyChange() = %v", changes)
+	}
+
+	//the primary key is regenerated and set, the secondary stays in use
+	if err := keys.SetKeys(rotated, secondary); err != nil || keys.InUse() != KeySecondary {
+		t.Errorf("SetKeys() = %v, %v", err, keys.InUse())
+	}
+	if err := keys.SetKeys(rotated, ""); err != nil || keys.InUse() != KeyPrimary {
+		t.Errorf("SetKeys() without secondary = %v, %v", err, keys.InUse())
+	}
+	accept(rotated)
+	if status, _ := container.GetDocumentByID("1"); status != "200 OK" || requests != 1 {
+		t.Errorf("after SetKeys() = %v, %v", status, requests)
+	}
+	if err := keys.Use(KeySecondary); err == nil {
+		t.Errorf("Use() of a missing key, want error")
+	}
+	if err := keys.SetKeys("not base64", secondary); err == nil || keys.InUse() != KeyPrimary {
+		t.Errorf("SetKeys() invalid = %v", err)
+	}
+
+	invalid := ContainerFactory(DatabaseFactory(server.URL+"/", "not base64", "db"), "user", "a")
+	accept(primary)
+	if status, _ := invalid.GetDocumentByID("1"); status != "401 Unauthorized" || requests != 0 || invalid.Error.Kind != ErrorKindUnauthorized {
+		t.Errorf("invalid master key = %v, %v, %v", status, requests, invalid.Error)
+	}
+	if _, err := AuthorizationToken("GET", "docs", "dbs/db", "date", "not base64"); err == nil {
+		t.Errorf("AuthorizationToken() invalid key, want error")
+	}
+}
+
+func TestKeyProviderWatchFiles(t *testing.T) {
+	dir := t.TempDir()
+	primary_path, secondary_path := filepath.Join(dir, "primary"), filepath.Join(dir, "secondary")
+	os.WriteFile(primary_path, []byte("cHJpbWFyeQ==\n"), 0o600)
+	os.WriteFile(secondary_path, []byte("c2Vjb25kYXJ5\n"), 0o600)
+
+	keys := &TKeyProvider{}
+	errors := make(chan error, 10)
+	keys.OnError = func(err error) { errors <- err }
+	if _, err := keys.WatchFiles(filepath.Join(dir, "missing"), "", time.Millisecond); err == nil {
+		t.Errorf("WatchFiles() missing file, want error")
+	}
+	stop, err := keys.WatchFiles(primary_path, secondary_path, 5*time.Millisecond)
+	if err != nil {
+		t.Fatalf("WatchFiles() = %v", err)
+	}
+	defer stop()
+	if name, key := keys.Key(); name != KeyPrimary || key != "cHJpbWFyeQ==" {
+		t.Errorf("Key() = %v, %v", name, key)
+	}
+
+	os.WriteFile(primary_path, []byte("cm90YXRlZA=="), 0o600)
+	deadline := time.Now().Add(2 * time.Second)
+	for _, key := keys.Key(); key != "cm90YXRlZA==" && time.Now().Before(deadline); _, key = keys.Key() {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if _, key := keys.Key(); key != "cm90YXRlZA==" {
+		t.Errorf("Key() after change = %v", key)
+	}
+
+	os.WriteFile(primary_path, []byte("not base64"), 0o600)
+	select {
+	case err := <-errors:
+		if _, key := keys.Key(); err == nil || key != "cm90YXRlZA==" {
+			t.Errorf("invalid key file = %v, %v", err, key)
+		}
+	case <-time.After(2 * time.Second):
+		t.Errorf("OnError() not called for an invalid key file")
+	}
+	stop()
+	stop()
+}
@@ -0,0 +1,117 @@
+package cosmos_db_restapi
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// forbiddenIDCharacters - characters cosmos db does not allow in the id of a resource
+const forbiddenIDCharacters = `/\?#`
+
+// ValidateID - the id of a resource must not be empty and must not contain / \ ? or #
+func ValidateID(id string) error {
+	if id == "" {
+		return fmt.Errorf("the id must not be empty")
+	}
+	if i := strings.IndexAny(id, forbiddenIDCharacters); i >= 0 {
+		return fmt.Errorf("the id %q must not contain %q", id, id[i:i+1])
+	}
+	return nil
+}
+
+/*
+TResourceLink - the link of a cosmos db resource, addressed by names or by _rid
+
+the link is built of resource types and ids, i.e. DatabaseLink("Shop").Child("colls", "Order").Child("docs", "a b"),
+the names keep their case, they are signed unescaped and escaped for the url:
+
+	Link() - "dbs/Shop/colls/Order/docs/a b" for the signature
+	Path() - "dbs/Shop/colls/Order/docs/a%20b" for the url
+
+a link by _rid is signed with the _rid of the resource in lower case
+*/
+type TResourceLink struct {
+	segments []string //resource types and ids, unescaped
+	by_rid   bool     //the ids are _rids
+	err      error    //the first invalid id
+}
+
+// DatabaseLink - the link of a database by name, i.e. "dbs/db"
+func DatabaseLink(database string) TResourceLink {
+	return TResourceLink{}.Child("dbs", database)
+}
+
+// DatabaseRidLink - the link of a database by _rid, the children are addressed by _rid, too
+func DatabaseRidLink(rid string) TResourceLink {
+	return TResourceLink{by_rid: true}.Child("dbs", rid)
+}
+
+/*
+Child - the link of a child resource
+
+parameters:
+
+	resource_type - type of the child i.e. "colls", "docs", "sprocs" or "attachments"
+	id - id or _rid of the child, must pass ValidateID
+*/
+func (me TResourceLink) Child(resource_type string, id string) TResourceLink {
+	segments := make([]string, 0, len(me.segments)+2)
+	segments = append(segments, me.segments...)
+	me.segments = append(segments, resource_type, id)
+	if err := ValidateID(id); err != nil && me.err == nil {
+		me.err = fmt.Errorf("%s: %w", resource_type, err)
+	}
+	return me
+}
+
+// Err - the first invalid id of the link, nil if the link is valid
+func (me TResourceLink) Err() error {
+	return me.err
+}
+
+// ByRid - the resources are addressed by _rid
+func (me TResourceLink) ByRid() bool {
+	return me.by_rid
+}
+
+// ResourceType - the type of the resource of the link i.e. "docs", "" for an empty link
+func (me TResourceLink) ResourceType() string {
+	if len(me.segments) < 2 {
+		return ""
+	}
+	return me.segments[len(me.segments)-2]
+}
+
+// Link - the resource link for the signature, the unescaped names or the lower case _rid of the resource
+func (me TResourceLink) Link() string {
+	if me.by_rid {
+		if len(me.segments) == 0 {
+			return ""
+		}
+		return strings.ToLower(me.segments[len(me.segments)-1])
+	}
+	return strings.Join(me.segments, "/")
+}
+
+// Path - the url path relative to the endpoint, each segment escaped
+func (me TResourceLink) Path() string {
+	segments := make([]string, len(me.segments))
+	for i, segment := range me.segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// Feed - the url path of the feed of children, i.e. "dbs/db/colls/coll/docs" for "docs"
+func (me TResourceLink) Feed(resource_type string) string {
+	if len(me.segments) == 0 {
+		return resource_type
+	}
+	return me.Path() + "/" + resource_type
+}
+
+// String - the unescaped link, i.e. "dbs/db/colls/coll"
+func (me TResourceLink) String() string {
+	return strings.Join(me.segments, "/")
+}
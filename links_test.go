@@ -0,0 +1,65 @@
+package cosmos_db_restapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestResourceLink(t *testing.T) {
+	tests := []struct {
+		name     string
+		link     TResourceLink
+		wantLink string
+		wantPath string
+		wantErr  bool
+	}{
+		{"case is kept", DatabaseLink("Shop").Child("colls", "Order"), "dbs/Shop/colls/Order", "dbs/Shop/colls/Order", false},
+		{"escaped for the url", DatabaseLink("db").Child("colls", "coll").Child("docs", "a b%c"),
+			"dbs/db/colls/coll/docs/a b%c", "dbs/db/colls/coll/docs/a%20b%25c", false},
+		{"unicode", DatabaseLink("db").Child("colls", "coll").Child("docs", "Bär"),
+			"dbs/db/colls/coll/docs/Bär", "dbs/db/colls/coll/docs/B%C3%A4r", false},
+		{"by rid", DatabaseRidLink("AbCd==").Child("colls", "AbCdEf+=").Child("docs", "AbCdEfGh-="),
+			"abcdefgh-=", "dbs/AbCd==/colls/AbCdEf+=/docs/AbCdEfGh-=", false},
+		{"slash", DatabaseLink("db").Child("colls", "coll").Child("docs", "a/b"), "", "", true},
+		{"backslash", DatabaseLink("db").Child("colls", `a\b`), "", "", true},
+		{"question mark", DatabaseLink("db?"), "", "", true},
+		{"hash", DatabaseLink("db").Child("colls", "coll").Child("sprocs", "#1"), "", "", true},
+		{"empty", DatabaseLink(""), "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.link.Err(); (err != nil) != tt.wantErr {
+				t.Fatalf("Err() = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if tt.link.Link() != tt.wantLink || tt.link.Path() != tt.wantPath {
+				t.Errorf("Link() = %q, Path() = %q", tt.link.Link(), tt.link.Path())
+			}
+		})
+	}
+
+	collection := DatabaseLink("my db").Child("colls", "Order")
+	if collection.Feed("docs") != "dbs/my%20db/colls/Order/docs" || collection.ResourceType() != "colls" {
+		t.Errorf("Feed() = %q, ResourceType() = %q", collection.Feed("docs"), collection.ResourceType())
+	}
+	if parent := DatabaseLink("db"); parent.Child("colls", "a").String() != "dbs/db/colls/a" || parent.String() != "dbs/db" {
+		t.Errorf("Child() changed the parent %q", parent.String())
+	}
+}
+
+func TestInvalidIDIsNotSent(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests += 1
+	}))
+	defer server.Close()
+
+	container := ContainerFactory(DatabaseFactory(server.URL+"/", "a2V5", "db"), "user", "a")
+	status, _ := container.GetDocumentByID("a/b")
+	if status != "400 Bad Request" || requests != 0 || container.Error == nil || container.Error.Kind != ErrorKindBadRequest {
+		t.Errorf("GetDocumentByID() = %v, requests = %v, error = %v", status, requests, container.Error)
+	}
+}
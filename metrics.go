@@ -0,0 +1,234 @@
+package cosmos_db_restapi
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// TQueryMetrics - parsed x-ms-documentdb-query-metrics header of a query response
+type TQueryMetrics struct {
+	RetrievedDocumentCount          int64   `json:"retrieved_document_count"`
+	RetrievedDocumentSize           int64   `json:"retrieved_document_size"`
+	OutputDocumentCount             int64   `json:"output_document_count"`
+	OutputDocumentSize              int64   `json:"output_document_size"`
+	IndexHitDocumentCount           int64   `json:"index_hit_document_count"`
+	IndexUtilizationRatio           float64 `json:"index_utilization_ratio"`
+	TotalQueryExecutionTimeInMs     float64 `json:"total_query_execution_time_ms"`
+	QueryCompileTimeInMs            float64 `json:"query_compile_time_ms"`
+	LogicalPlanBuildTimeInMs        float64 `json:"logical_plan_build_time_ms"`
+	PhysicalPlanBuildTimeInMs       float64 `json:"physical_plan_build_time_ms"`
+	QueryOptimizationTimeInMs       float64 `json:"query_optimization_time_ms"`
+	IndexLookupTimeInMs             float64 `json:"index_lookup_time_ms"`
+	DocumentLoadTimeInMs            float64 `json:"document_load_time_ms"`
+	VMExecutionTimeInMs             float64 `json:"vm_execution_time_ms"`
+	RuntimeExecutionTimeInMs        float64 `json:"runtime_execution_time_ms"`
+	DocumentWriteTimeInMs           float64 `json:"document_write_time_ms"`
+	SystemFunctionExecutionTimeInMs float64 `json:"system_function_execution_time_ms"`
+	UserFunctionExecutionTimeInMs   float64 `json:"user_function_execution_time_ms"`
+	Pages                           int     `json:"pages"` //number of responses aggregated
+}
+
+// TIndexMetricsEntry - one single or composite index of the index utilization
+type TIndexMetricsEntry struct {
+	FilterExpression string   `json:"FilterExpression"`
+	IndexSpec        string   `json:"IndexSpec"`
+	IndexSpecs       []string `json:"IndexSpecs"` //composite indexes only
+	FilterPreciseSet bool     `json:"FilterPreciseSet"`
+	IndexPreciseSet  bool     `json:"IndexPreciseSet"`
+	IndexImpactScore string   `json:"IndexImpactScore"`
+}
+
+// TIndexMetrics - decoded x-ms-cosmos-index-utilization header of a query response
+type TIndexMetrics struct {
+	UtilizedSingleIndexes     []TIndexMetricsEntry `json:"UtilizedSingleIndexes"`
+	PotentialSingleIndexes    []TIndexMetricsEntry `json:"PotentialSingleIndexes"`
+	UtilizedCompositeIndexes  []TIndexMetricsEntry `json:"UtilizedCompositeIndexes"`
+	PotentialCompositeIndexes []TIndexMetricsEntry `json:"PotentialCompositeIndexes"`
+}
+
+// TQueryDiagnostics - query and index metrics of one or more query pages
+type TQueryDiagnostics struct {
+	QueryMetrics TQueryMetrics `json:"query_metrics"`
+	IndexMetrics TIndexMetrics `json:"index_metrics"`
+	Err          error         `json:"-"` //a malformed metrics header of the last page, the query itself succeeded
+}
+
+/*
+ParseQueryMetrics - parse the semicolon-delimited query metrics header
+
+	"totalExecutionTimeInMs=33.67;queryCompileTimeInMs=0.06;...;retrievedDocumentCount=2000"
+
+unknown keys are ignored, a malformed value returns an error
+*/
+func ParseQueryMetrics(header string) (Metrics TQueryMetrics, err error) {
+	if strings.TrimSpace(header) == "" {
+		return
+	}
+	for _, pair := range strings.Split(header, ";") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		key, value, _ := strings.Cut(pair, "=")
+		if value == "" {
+			continue
+		}
+
+		var int_field *int64
+		var float_field *float64
+		switch key {
+		case "retrievedDocumentCount":
+			int_field = &Metrics.RetrievedDocumentCount
+		case "retrievedDocumentSize":
+			int_field = &Metrics.RetrievedDocumentSize
+		case "outputDocumentCount":
+			int_field = &Metrics.OutputDocumentCount
+		case "outputDocumentSize":
+			int_field = &Metrics.OutputDocumentSize
+		case "indexHitDocumentCount":
+			int_field = &Metrics.IndexHitDocumentCount
+		case "indexUtilizationRatio":
+			float_field = &Metrics.IndexUtilizationRatio
+		case "totalExecutionTimeInMs":
+			float_field = &Metrics.TotalQueryExecutionTimeInMs
+		case "queryCompileTimeInMs":
+			float_field = &Metrics.QueryCompileTimeInMs
+		case "queryLogicalPlanBuildTimeInMs":
+			float_field = &Metrics.LogicalPlanBuildTimeInMs
+		case "queryPhysicalPlanBuildTimeInMs":
+			float_field = &Metrics.PhysicalPlanBuildTimeInMs
+		case "queryOptimizationTimeInMs":
+			float_field = &Metrics.QueryOptimizationTimeInMs
+		case "indexLookupTimeInMs":
+			float_field = &Metrics.IndexLookupTimeInMs
+		case "documentLoadTimeInMs":
+			float_field = &Metrics.DocumentLoadTimeInMs
+		case "VMExecutionTimeInMs":
+			float_field = &Metrics.VMExecutionTimeInMs
+		case "writeOutputTimeInMs":
+			float_field = &Metrics.DocumentWriteTimeInMs
+		case "systemFunctionExecuteTimeInMs":
+			float_field = &Metrics.SystemFunctionExecutionTimeInMs
+		case "userFunctionExecuteTimeInMs":
+			float_field = &Metrics.UserFunctionExecutionTimeInMs
+		default:
+			continue
+		}
+
+		if int_field != nil {
+			if *int_field, err = strconv.ParseInt(value, 10, 64); err != nil {
+				return TQueryMetrics{}, err
+			}
+		} else {
+			if *float_field, err = strconv.ParseFloat(value, 64); err != nil {
+				return TQueryMetrics{}, err
+			}
+		}
+	}
+
+	//the runtime is the part of the vm execution not spent in index lookup, loading and writing
+	Metrics.RuntimeExecutionTimeInMs = Metrics.VMExecutionTimeInMs -
+		Metrics.IndexLookupTimeInMs - Metrics.DocumentLoadTimeInMs - Metrics.DocumentWriteTimeInMs
+	if Metrics.RuntimeExecutionTimeInMs < 0 {
+		Metrics.RuntimeExecutionTimeInMs = 0
+	}
+	Metrics.Pages = 1
+	return
+}
+
+/*
+ParseIndexMetrics - decode the base64 encoded json of the index utilization header
+*/
+func ParseIndexMetrics(header string) (Metrics TIndexMetrics, err error) {
+	if strings.TrimSpace(header) == "" {
+		return
+	}
+	data, err := base64.StdEncoding.DecodeString(header)
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal(data, &Metrics)
+	return
+}
+
+// Add - accumulates the metrics of a further page
+func (me *TQueryMetrics) Add(other TQueryMetrics) {
+	//the ratio is weighted by the retrieved documents of each page
+	if total := me.RetrievedDocumentCount + other.RetrievedDocumentCount; total > 0 {
+		me.IndexUtilizationRatio = (me.IndexUtilizationRatio*float64(me.RetrievedDocumentCount) +
+			other.IndexUtilizationRatio*float64(other.RetrievedDocumentCount)) / float64(total)
+	} else if me.Pages == 0 {
+		me.IndexUtilizationRatio = other.IndexUtilizationRatio
+	}
+
+	me.RetrievedDocumentCount += other.RetrievedDocumentCount
+	me.RetrievedDocumentSize += other.RetrievedDocumentSize
+	me.OutputDocumentCount += other.OutputDocumentCount
+	me.OutputDocumentSize += other.OutputDocumentSize
+	me.IndexHitDocumentCount += other.IndexHitDocumentCount
+	me.TotalQueryExecutionTimeInMs += other.TotalQueryExecutionTimeInMs
+	me.QueryCompileTimeInMs += other.QueryCompileTimeInMs
+	me.LogicalPlanBuildTimeInMs += other.LogicalPlanBuildTimeInMs
+	me.PhysicalPlanBuildTimeInMs += other.PhysicalPlanBuildTimeInMs
+	me.QueryOptimizationTimeInMs += other.QueryOptimizationTimeInMs
+	me.IndexLookupTimeInMs += other.IndexLookupTimeInMs
+	me.DocumentLoadTimeInMs += other.DocumentLoadTimeInMs
+	me.VMExecutionTimeInMs += other.VMExecutionTimeInMs
+	me.RuntimeExecutionTimeInMs += other.RuntimeExecutionTimeInMs
+	me.DocumentWriteTimeInMs += other.DocumentWriteTimeInMs
+	me.SystemFunctionExecutionTimeInMs += other.SystemFunctionExecutionTimeInMs
+	me.UserFunctionExecutionTimeInMs += other.UserFunctionExecutionTimeInMs
+	me.Pages += other.Pages
+}
+
+// Add - merges the index metrics of a further page, each index is listed only once
+func (me *TIndexMetrics) Add(other TIndexMetrics) {
+	me.UtilizedSingleIndexes = mergeIndexMetrics(me.UtilizedSingleIndexes, other.UtilizedSingleIndexes)
+	me.PotentialSingleIndexes = mergeIndexMetrics(me.PotentialSingleIndexes, other.PotentialSingleIndexes)
+	me.UtilizedCompositeIndexes = mergeIndexMetrics(me.UtilizedCompositeIndexes, other.UtilizedCompositeIndexes)
+	me.PotentialCompositeIndexes = mergeIndexMetrics(me.PotentialCompositeIndexes, other.PotentialCompositeIndexes)
+}
+
+// Add - accumulates the diagnostics of a further page
+func (me *TQueryDiagnostics) Add(other TQueryDiagnostics) {
+	me.QueryMetrics.Add(other.QueryMetrics)
+	me.IndexMetrics.Add(other.IndexMetrics)
+	if other.Err != nil {
+		me.Err = other.Err
+	}
+}
+
+func mergeIndexMetrics(list []TIndexMetricsEntry, other []TIndexMetricsEntry) []TIndexMetricsEntry {
+	for _, entry := range other {
+		key := entry.IndexSpec + "|" + strings.Join(entry.IndexSpecs, ",")
+		found := false
+		for _, known := range list {
+			if known.IndexSpec+"|"+strings.Join(known.IndexSpecs, ",") == key {
+				found = true
+				break
+			}
+		}
+		if !found {
+			list = append(list, entry)
+		}
+	}
+	return list
+}
+
+/*
+parseQueryDiagnostics - query and index metrics from the response headers
+
+the headers are parsed independently, the metrics of a valid header are kept
+if the other one is malformed, err is the error of the first malformed header
+*/
+func parseQueryDiagnostics(query_metrics string, index_metrics string) (Diagnostics TQueryDiagnostics, err error) {
+	var index_err error
+	Diagnostics.QueryMetrics, err = ParseQueryMetrics(query_metrics)
+	Diagnostics.IndexMetrics, index_err = ParseIndexMetrics(index_metrics)
+	if err == nil {
+		err = index_err
+	}
+	return
+}
@@ -0,0 +1,145 @@
+package cosmos_db_restapi
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestParseQueryMetrics(t *testing.T) {
+	vm_time, lookup_time, load_time, write_time := 32.56, 0.99, 9.58, 18.10
+
+	tests := []struct {
+		name    string
+		header  string
+		want    TQueryMetrics
+		wantErr bool
+	}{
+		{
+			name:   "empty header",
+			header: "",
+			want:   TQueryMetrics{},
+		},
+		{
+			name: "full header",
+			header: "totalExecutionTimeInMs=33.67;queryCompileTimeInMs=0.06;queryLogicalPlanBuildTimeInMs=0.02;" +
+				"queryPhysicalPlanBuildTimeInMs=0.10;queryOptimizationTimeInMs=0.00;VMExecutionTimeInMs=32.56;" +
+				"indexLookupTimeInMs=0.99;documentLoadTimeInMs=9.58;systemFunctionExecuteTimeInMs=0.00;" +
+				"userFunctionExecuteTimeInMs=0.00;retrievedDocumentCount=2000;retrievedDocumentSize=1125600;" +
+				"outputDocumentCount=2000;outputDocumentSize=1125600;writeOutputTimeInMs=18.10;indexUtilizationRatio=1.00",
+			want: TQueryMetrics{
+				RetrievedDocumentCount:      2000,
+				RetrievedDocumentSize:       1125600,
+				OutputDocumentCount:         2000,
+				OutputDocumentSize:          1125600,
+				IndexUtilizationRatio:       1,
+				TotalQueryExecutionTimeInMs: 33.67,
+				QueryCompileTimeInMs:        0.06,
+				LogicalPlanBuildTimeInMs:    0.02,
+				PhysicalPlanBuildTimeInMs:   0.10,
+				IndexLookupTimeInMs:         0.99,
+				DocumentLoadTimeInMs:        9.58,
+				VMExecutionTimeInMs:         32.56,
+				RuntimeExecutionTimeInMs:    vm_time - lookup_time - load_time - write_time,
+				DocumentWriteTimeInMs:       18.10,
+				Pages:                       1,
+			},
+		},
+		{
+			name:    "malformed value",
+			header:  "retrievedDocumentCount=abc",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseQueryMetrics(tt.header)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseQueryMetrics() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("ParseQueryMetrics() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueryDiagnosticsAdd(t *testing.T) {
+	index_json := `{"UtilizedSingleIndexes":[{"FilterExpression":"","IndexSpec":"/word/?","FilterPreciseSet":true,"IndexPreciseSet":true,"IndexImpactScore":"High"}],` +
+		`"PotentialSingleIndexes":[],"UtilizedCompositeIndexes":[],"PotentialCompositeIndexes":[]}`
+	index_header := base64.StdEncoding.EncodeToString([]byte(index_json))
+
+	var total TQueryDiagnostics
+	for i := 0; i < 2; i++ {
+		page, err := parseQueryDiagnostics("retrievedDocumentCount=10;indexUtilizationRatio=0.5;VMExecutionTimeInMs=2", index_header)
+		if err != nil {
+			t.Fatalf("parseQueryDiagnostics() error = %v", err)
+		}
+		total.Add(page)
+	}
+
+	if total.QueryMetrics.Pages != 2 || total.QueryMetrics.RetrievedDocumentCount != 20 {
+		t.Errorf("Add() metrics = %+v", total.QueryMetrics)
+	}
+	if total.QueryMetrics.IndexUtilizationRatio != 0.5 {
+		t.Errorf("Add() IndexUtilizationRatio = %v, want 0.5", total.QueryMetrics.IndexUtilizationRatio)
+	}
+	if len(total.IndexMetrics.UtilizedSingleIndexes) != 1 {
+		t.Errorf("Add() UtilizedSingleIndexes = %v, want one entry", total.IndexMetrics.UtilizedSingleIndexes)
+	}
+}
+
+func TestQueryDiagnosticsMalformed(t *testing.T) {
+	index_header := base64.StdEncoding.EncodeToString([]byte(`{"UtilizedSingleIndexes":[{"IndexSpec":"/word/?"}]}`))
+
+	got, err := parseQueryDiagnostics("retrievedDocumentCount=abc", index_header)
+	if err == nil || len(got.IndexMetrics.UtilizedSingleIndexes) != 1 {
+		t.Errorf("parseQueryDiagnostics() = %+v, %v, want the index metrics and an error", got, err)
+	}
+	got, err = parseQueryDiagnostics("retrievedDocumentCount=10", "not base64")
+	if err == nil || got.QueryMetrics.RetrievedDocumentCount != 10 {
+		t.Errorf("parseQueryDiagnostics() = %+v, %v, want the query metrics and an error", got, err)
+	}
+}
+
+func TestQueryPager(t *testing.T) {
+	index_header := base64.StdEncoding.EncodeToString([]byte(`{"UtilizedSingleIndexes":[{"IndexSpec":"/word/?"}]}`))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.Header.Get("x-ms-continuation"))
+		if page < 2 {
+			w.Header().Set("x-ms-continuation", strconv.Itoa(page+1))
+		}
+		if page == 1 {
+			w.Header().Set("x-ms-documentdb-query-metrics", "retrievedDocumentCount=malformed")
+		} else {
+			w.Header().Set("x-ms-documentdb-query-metrics", "retrievedDocumentCount=10")
+		}
+		w.Header().Set("x-ms-cosmos-index-utilization", index_header)
+		w.Write([]byte(`{"Documents":[],"_count":0}`))
+	}))
+	defer server.Close()
+
+	container := ContainerFactory(DatabaseFactory(server.URL+"/", "", "db"), "dictionary", "")
+	container.Options = TRequestOptions{PopulateQueryMetrics: true, PopulateIndexMetrics: true}
+	pager := QueryPagerFactory(container, 10, TQuery{Query: "SELECT * FROM c"})
+	for pager.HasMorePages() {
+		if status, body := pager.NextPage(); status != "200 OK" {
+			t.Fatalf("NextPage() = %v, %v", status, body)
+		}
+	}
+
+	if pager.Pages != 3 || pager.Continuation != "" {
+		t.Errorf("Pages = %v, Continuation = %q", pager.Pages, pager.Continuation)
+	}
+	if pager.Diagnostics.QueryMetrics.Pages != 2 || pager.Diagnostics.QueryMetrics.RetrievedDocumentCount != 20 {
+		t.Errorf("QueryMetrics = %+v, want the metrics of the valid pages", pager.Diagnostics.QueryMetrics)
+	}
+	if len(pager.Diagnostics.IndexMetrics.UtilizedSingleIndexes) != 1 || pager.Diagnostics.Err == nil {
+		t.Errorf("Diagnostics = %+v, want the index metrics and the error of the malformed page", pager.Diagnostics)
+	}
+	if status, _ := pager.NextPage(); status != "204 No Content" {
+		t.Errorf("NextPage() after the last page = %v", status)
+	}
+}
@@ -0,0 +1,51 @@
+package cosmos_db_restapi
+
+import "strings"
+
+// TQueryPager - reads the pages of a query via the continuation token, the diagnostics of all pages are aggregated
+type TQueryPager struct {
+	Container    TContainer        `json:"container"` //the container with its options, Meta is the metadata of the last page
+	Query        TQuery            `json:"query"`
+	MaxItemCount int               `json:"max_item_count"`
+	Continuation string            `json:"continuation"` //the token of the next page, "" after the last page
+	Pages        int               `json:"pages"`        //pages read
+	Diagnostics  TQueryDiagnostics `json:"diagnostics"`  //metrics of all pages read, if requested by the options of the container
+	done         bool
+}
+
+// QueryPagerFactory - creates a pager for the query, the first page is read by NextPage
+func QueryPagerFactory(container TContainer, max_item_count int, query TQuery) *TQueryPager {
+	return &TQueryPager{
+		Container:    container,
+		Query:        query,
+		MaxItemCount: max_item_count,
+	}
+}
+
+// HasMorePages - true until the last page was read or a page failed
+func (me *TQueryPager) HasMorePages() bool {
+	return !me.done
+}
+
+/*
+NextPage - reads the next page of the query
+
+returns:
+
+	Status - response status i.e. 200 ok, 204 No Content if there are no more pages
+	Body - response body as string
+
+the query and index metrics of the page are added to Diagnostics,
+a failed page ends the pager
+*/
+func (me *TQueryPager) NextPage() (Status string, Body string) {
+	if me.done {
+		return "204 No Content", ""
+	}
+	var diagnostics TQueryDiagnostics
+	Status, Body, me.Continuation, diagnostics = me.Container.executeQuerry(me.MaxItemCount, me.Continuation, me.Query)
+	me.Pages += 1
+	me.Diagnostics.Add(diagnostics)
+	me.done = me.Continuation == "" || !strings.HasPrefix(Status, "2")
+	return
+}
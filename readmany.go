@@ -0,0 +1,185 @@
+package cosmos_db_restapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// readManyQueryChunk - ids per IN query
+const readManyQueryChunk = 100
+
+// TItemIdentity - id and partition key of a document
+type TItemIdentity struct {
+	ID           string `json:"id"`
+	PartitionKey string `json:"partition_key"`
+}
+
+// TReadManyResult - result of one item of ReadMany
+type TReadManyResult struct {
+	Index        int    `json:"index"` //position in the input list
+	ID           string `json:"id"`
+	PartitionKey string `json:"partition_key"`
+	Found        bool   `json:"found"`       //false if the document does not exist
+	StatusCode   int    `json:"status_code"` //200 found, 404 missing, else the error status
+	Body         string `json:"body"`        //the document as json, if found
+	Err          error  `json:"-"`
+}
+
+// Decode - unmarshals the document into a struct, i.e. a pointer to the application type
+func (me TReadManyResult) Decode(target interface{}) error {
+	if !me.Found {
+		return fmt.Errorf("document %q not found", me.ID)
+	}
+	return json.Unmarshal([]byte(me.Body), target)
+}
+
+/*
+ReadMany - read documents by id and partition key
+
+parameters:
+
+	items - id and partition key of each document
+	max_concurrency - parallel requests, else 4
+
+returns:
+
+	Results - one result per item in the order of the items, missing documents have Found false
+	RequestCharge - the sum of the request charges (RU)
+
+the items are grouped by partition key, a single id is read with a point read,
+several ids of a partition with "SELECT * FROM c WHERE c.id IN (...)"
+*/
+func (me *TContainer) ReadMany(items []TItemIdentity, max_concurrency int) (Results []TReadManyResult, RequestCharge float64) {
+	if max_concurrency <= 0 {
+		max_concurrency = 4
+	}
+	Results = make([]TReadManyResult, len(items))
+	groups := map[string][]int{} //partition key -> positions in items
+	var order []string
+	for i, item := range items {
+		Results[i] = TReadManyResult{Index: i, ID: item.ID, PartitionKey: item.PartitionKey}
+		if _, found := groups[item.PartitionKey]; !found {
+			order = append(order, item.PartitionKey)
+		}
+		groups[item.PartitionKey] = append(groups[item.PartitionKey], i)
+	}
+
+	var mutex sync.Mutex
+	var wait sync.WaitGroup
+	slots := make(chan struct{}, max_concurrency)
+	run := func(task func(container *TContainer)) {
+		wait.Add(1)
+		slots <- struct{}{}
+		go func() {
+			defer wait.Done()
+			defer func() { <-slots }()
+			container := *me //each request with its own copy, the database state is shared
+			task(&container)
+			mutex.Lock()
+			RequestCharge += container.Meta.RequestCharge
+			mutex.Unlock()
+		}()
+	}
+
+	for _, partitionkey := range order {
+		partitionkey := partitionkey
+		positions := groups[partitionkey]
+		if len(positions) == 1 {
+			position := positions[0]
+			run(func(container *TContainer) {
+				container.PartitionKey = partitionkey
+				status, body := container.GetDocumentByID(items[position].ID)
+				mutex.Lock()
+				defer mutex.Unlock()
+				result := &Results[position]
+				result.StatusCode = container.Meta.StatusCode
+				switch {
+				case container.Meta.StatusCode == http.StatusOK:
+					result.Found = true
+					result.Body = body
+				case container.Meta.StatusCode != http.StatusNotFound:
+					result.Err = fmt.Errorf("%s %s", status, body)
+				}
+			})
+			continue
+		}
+
+		for start := 0; start < len(positions); start += readManyQueryChunk {
+			end := start + readManyQueryChunk
+			if end > len(positions) {
+				end = len(positions)
+			}
+			chunk := positions[start:end]
+			run(func(container *TContainer) {
+				container.PartitionKey = partitionkey
+				documents, charge, err := container.queryIDs(items, chunk)
+				mutex.Lock()
+				defer mutex.Unlock()
+				RequestCharge += charge
+				for _, position := range chunk {
+					result := &Results[position]
+					if err != nil {
+						result.StatusCode = container.Meta.StatusCode
+						result.Err = err
+						continue
+					}
+					if body, found := documents[items[position].ID]; found {
+						result.Found = true
+						result.StatusCode = http.StatusOK
+						result.Body = body
+					} else {
+						result.StatusCode = http.StatusNotFound
+					}
+				}
+			})
+		}
+	}
+	wait.Wait()
+	return
+}
+
+/*
+queryIDs - reads the documents of the positions with an IN query in the partition
+of the container, all pages are read
+
+returns the documents by id and the request charge of all pages except the last,
+the last is in Meta
+*/
+func (me *TContainer) queryIDs(items []TItemIdentity, positions []int) (Documents map[string]string, RequestCharge float64, err error) {
+	query := TQuery{}
+	names := make([]string, len(positions))
+	for i, position := range positions {
+		names[i] = "@id" + fmt.Sprint(i)
+		query.Parameters = append(query.Parameters, TParameter{Name: names[i], Value: items[position].ID})
+	}
+	query.Query = "SELECT * FROM c WHERE c.id IN (" + strings.Join(names, ", ") + ")"
+
+	Documents = map[string]string{}
+	continuation := ""
+	for steps := 0; steps == 0 || continuation != ""; steps++ {
+		if steps > 0 {
+			RequestCharge += me.Meta.RequestCharge
+		}
+		var status, body string
+		status, body, continuation, _ = me.executeQuerry(0, continuation, query)
+		if me.Meta.StatusCode != http.StatusOK {
+			return nil, RequestCharge, fmt.Errorf("%s %s", status, body)
+		}
+		var page struct {
+			Documents []json.RawMessage `json:"Documents"`
+		}
+		if err = json.Unmarshal([]byte(body), &page); err != nil {
+			return nil, RequestCharge, err
+		}
+		for _, document := range page.Documents {
+			var identity TItemIdentity
+			if json.Unmarshal(document, &identity) == nil {
+				Documents[identity.ID] = string(document)
+			}
+		}
+	}
+	return
+}
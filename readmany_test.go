@@ -0,0 +1,78 @@
+package cosmos_db_restapi
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestReadMany(t *testing.T) {
+	documents := map[string]string{"a": "pk1", "b": "pk1", "c": "pk2"}
+	var point_reads, queries int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("x-ms-request-charge", "1")
+		partitionkey := strings.Trim(r.Header.Get("x-ms-documentdb-partitionkey"), `[ "]`)
+		if r.Method == "GET" {
+			atomic.AddInt32(&point_reads, 1)
+			id := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+			if documents[id] != partitionkey {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Write([]byte(`{"id":"` + id + `","word":"` + id + `"}`))
+			return
+		}
+		atomic.AddInt32(&queries, 1)
+		var query TQuery
+		data, _ := ioutil.ReadAll(r.Body)
+		json.Unmarshal(data, &query)
+		var found []string
+		for _, parameter := range query.Parameters {
+			if documents[parameter.Value] == partitionkey {
+				found = append(found, `{"id":"`+parameter.Value+`","word":"`+parameter.Value+`"}`)
+			}
+		}
+		w.Write([]byte(`{"Documents":[` + strings.Join(found, ",") + `],"_count":` + strconv.Itoa(len(found)) + `}`))
+	}))
+	defer server.Close()
+
+	container := ContainerFactory(DatabaseFactory(server.URL+"/", "", "db"), "dictionary", "")
+	items := []TItemIdentity{
+		{ID: "b", PartitionKey: "pk1"},
+		{ID: "c", PartitionKey: "pk2"},
+		{ID: "x", PartitionKey: "pk1"},
+		{ID: "a", PartitionKey: "pk1"},
+		{ID: "y", PartitionKey: "pk3"},
+	}
+	results, charge := container.ReadMany(items, 2)
+
+	want := []struct {
+		id    string
+		found bool
+		code  int
+	}{{"b", true, 200}, {"c", true, 200}, {"x", false, 404}, {"a", true, 200}, {"y", false, 404}}
+	for i, w := range want {
+		got := results[i]
+		if got.ID != w.id || got.Found != w.found || got.StatusCode != w.code || got.Err != nil {
+			t.Errorf("results[%v] = %+v, want %+v", i, got, w)
+		}
+	}
+
+	var document struct {
+		Word string `json:"word"`
+	}
+	if err := results[3].Decode(&document); err != nil || document.Word != "a" {
+		t.Errorf("Decode() = %v, %v", document, err)
+	}
+	if err := results[2].Decode(&document); err == nil {
+		t.Errorf("Decode() of a missing document, want error")
+	}
+	if point_reads != 2 || queries != 1 || charge != 3 {
+		t.Errorf("point reads = %v, queries = %v, charge = %v", point_reads, queries, charge)
+	}
+}
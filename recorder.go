@@ -0,0 +1,258 @@
+package cosmos_db_restapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// TRecorderMode - records the interactions to a cassette or replays them
+type TRecorderMode string
+
+const (
+	RecorderModeRecord TRecorderMode = "record" //requests are sent and recorded
+	RecorderModeReplay TRecorderMode = "replay" //requests are answered from the cassette, nothing is sent
+)
+
+// RecorderRedacted - replaces the authorization header and the secrets in a cassette
+const RecorderRedacted = "REDACTED"
+
+// RecorderDate - the date headers of a cassette, the real date changes with each recording
+const RecorderDate = "Thu, 01 Jan 1970 00:00:00 GMT"
+
+// TRecordedRequest - a request of a cassette, URL is the path and query without the endpoint
+type TRecordedRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header"`
+	Body   string      `json:"body"`
+}
+
+// TRecordedResponse - the response of a recorded request
+type TRecordedResponse struct {
+	StatusCode int         `json:"status_code"`
+	Status     string      `json:"status"`
+	Header     http.Header `json:"header"`
+	Body       string      `json:"body"`
+}
+
+// TInteraction - a request and its response
+type TInteraction struct {
+	Request  TRecordedRequest  `json:"request"`
+	Response TRecordedResponse `json:"response"`
+}
+
+// TCassette - the interactions of a test in order of the requests
+type TCassette struct {
+	Interactions []TInteraction `json:"interactions"`
+}
+
+/*
+TRecorder - a http.RoundTripper which records the interactions with the cosmos db to a cassette file
+and replays them, safe for concurrent use
+
+	recorder, err := RecorderFactory("testdata/cassettes/query.json", RecorderModeReplay, nil)
+	database.Transport = recorder
+
+in the cassette the authorization header and the Secrets are redacted and the date headers are
+normalized, in replay mode a request is answered by the first unused interaction with the same
+method, url, body, partition key and continuation
+*/
+type TRecorder struct {
+	Path      string            `json:"path"`
+	Mode      TRecorderMode     `json:"mode"`
+	Transport http.RoundTripper `json:"-"` //transport of the recording, default http.DefaultTransport
+	Secrets   []string          `json:"-"` //values replaced in the cassette, i.e. the master key
+
+	mutex    sync.Mutex
+	cassette TCassette
+	used     []bool
+}
+
+/*
+RecorderFactory - creates a recorder of the cassette file
+
+parameters:
+
+	path - the cassette file
+	mode - RecorderModeRecord or RecorderModeReplay
+	transport - transport of the recording, nil for http.DefaultTransport
+
+returns:
+
+	the recorder, in replay mode with the loaded cassette
+	error if the cassette of the replay can not be read
+*/
+func RecorderFactory(path string, mode TRecorderMode, transport http.RoundTripper) (*TRecorder, error) {
+	me := &TRecorder{Path: path, Mode: mode, Transport: transport}
+	if mode != RecorderModeReplay {
+		return me, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &me.cassette); err != nil {
+		return nil, fmt.Errorf("cassette %s: %w", path, err)
+	}
+	me.used = make([]bool, len(me.cassette.Interactions))
+	return me, nil
+}
+
+// RoundTrip - records or replays the request
+func (me *TRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		data, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		body = data
+	}
+	recorded := TRecordedRequest{
+		Method: req.Method,
+		URL:    "/" + strings.TrimLeft(req.URL.RequestURI(), "/"),
+		Header: me.redactHeader(req.Header),
+		Body:   me.redact(string(body)),
+	}
+
+	if me.Mode == RecorderModeReplay {
+		return me.replay(req, recorded)
+	}
+
+	transport := me.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	res, err := transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	res.Body = io.NopCloser(bytes.NewReader(data))
+
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	me.cassette.Interactions = append(me.cassette.Interactions, TInteraction{
+		Request: recorded,
+		Response: TRecordedResponse{
+			StatusCode: res.StatusCode,
+			Status:     res.Status,
+			Header:     me.redactHeader(res.Header),
+			Body:       me.redact(string(data)),
+		},
+	})
+	return res, nil
+}
+
+// replay - the response of the first unused interaction of the request
+func (me *TRecorder) replay(req *http.Request, recorded TRecordedRequest) (*http.Response, error) {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	for i, interaction := range me.cassette.Interactions {
+		if me.used[i] || !sameRequest(interaction.Request, recorded) {
+			continue
+		}
+		me.used[i] = true
+		response := interaction.Response
+		return &http.Response{
+			Status:        response.Status,
+			StatusCode:    response.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        response.Header.Clone(),
+			Body:          io.NopCloser(strings.NewReader(response.Body)),
+			ContentLength: int64(len(response.Body)),
+			Request:       req,
+		}, nil
+	}
+	return nil, fmt.Errorf("cassette %s has no interaction for %s %s", me.Path, recorded.Method, recorded.URL)
+}
+
+// sameRequest - the requests are equal for the replay
+func sameRequest(recorded TRecordedRequest, req TRecordedRequest) bool {
+	for _, key := range []string{"x-ms-documentdb-partitionkey", "x-ms-continuation"} {
+		if recorded.Header.Get(key) != req.Header.Get(key) {
+			return false
+		}
+	}
+	return recorded.Method == req.Method && recorded.URL == req.URL && recorded.Body == req.Body
+}
+
+// Unused - the number of interactions which were not replayed
+func (me *TRecorder) Unused() int {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	count := 0
+	for _, used := range me.used {
+		if !used {
+			count += 1
+		}
+	}
+	return count
+}
+
+// Save - writes the recorded interactions to the cassette file, nothing is written in replay mode
+func (me *TRecorder) Save() error {
+	if me.Mode != RecorderModeRecord {
+		return nil
+	}
+	me.mutex.Lock()
+	data, err := json.MarshalIndent(me.cassette, "", "  ")
+	me.mutex.Unlock()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(me.Path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(me.Path, append(data, '\n'), 0o644)
+}
+
+// redactHeader - a copy of the header without authorization, secrets and real dates
+func (me *TRecorder) redactHeader(header http.Header) http.Header {
+	redacted := http.Header{}
+	keys := make([]string, 0, len(header))
+	for key := range header {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		for _, value := range header[key] {
+			switch http.CanonicalHeaderKey(key) {
+			case "Authorization":
+				value = RecorderRedacted
+			case "Date", "X-Ms-Date":
+				value = RecorderDate
+			default:
+				value = me.redact(value)
+			}
+			redacted.Add(key, value)
+		}
+	}
+	return redacted
+}
+
+// redact - replaces the secrets in the text
+func (me *TRecorder) redact(text string) string {
+	for _, secret := range me.Secrets {
+		if secret != "" {
+			text = strings.ReplaceAll(text, secret, RecorderRedacted)
+		}
+	}
+	return text
+}
@@ -0,0 +1,62 @@
+package cosmos_db_restapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecorder(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("x-ms-continuation", r.Header.Get("x-ms-documentdb-partitionkey"))
+		w.Write([]byte(`{"id":"Zwerg","secret":"c2VjcmV0"}`))
+	}))
+	defer server.Close()
+	path := filepath.Join(t.TempDir(), "cassettes", "zwerg.json")
+
+	recorder, err := RecorderFactory(path, RecorderModeRecord, nil)
+	if err != nil {
+		t.Fatalf("RecorderFactory() = %v", err)
+	}
+	recorder.Secrets = []string{"c2VjcmV0"}
+	database := DatabaseFactory(server.URL+"/", "c2VjcmV0", "db")
+	database.Transport = recorder
+	container := ContainerFactory(database, "dictionary", "Zwerg")
+	if status, body := container.GetDocumentByID("Zwerg"); status != "200 OK" || !strings.Contains(body, "c2VjcmV0") {
+		t.Fatalf("GetDocumentByID() recording = %v, %v", status, body)
+	}
+	if err := recorder.Save(); err != nil {
+		t.Fatalf("Save() = %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	if strings.Contains(string(data), "c2VjcmV0") || strings.Contains(string(data), "type%3Dmaster") ||
+		!strings.Contains(string(data), RecorderDate) || strings.Contains(string(data), server.URL) {
+		t.Errorf("cassette = %s", data)
+	}
+
+	replay, err := RecorderFactory(path, RecorderModeReplay, nil)
+	if err != nil {
+		t.Fatalf("RecorderFactory() replay = %v", err)
+	}
+	database = DatabaseFactory("https://localhost:8081/", "", "db")
+	database.Transport = replay
+	container = ContainerFactory(database, "dictionary", "Zwerg")
+	if status, body := container.GetDocumentByID("Zwerg"); status != "200 OK" || !strings.Contains(body, RecorderRedacted) ||
+		container.Meta.Continuation != `[ "Zwerg" ]` || replay.Unused() != 0 {
+		t.Errorf("GetDocumentByID() replay = %v, %v, %+v", status, body, container.Meta)
+	}
+	if status, _ := container.GetDocumentByID("Zwerg"); status != "" || container.Error == nil || container.Error.Kind != ErrorKindRequest {
+		t.Errorf("GetDocumentByID() replayed twice = %v, %v", status, container.Error)
+	}
+	other := ContainerFactory(database, "dictionary", "Nase")
+	if status, _ := other.GetDocumentByID("Zwerg"); status != "" {
+		t.Errorf("GetDocumentByID() other partition key = %v", status)
+	}
+
+	if _, err := RecorderFactory(filepath.Join(t.TempDir(), "missing.json"), RecorderModeReplay, nil); err == nil {
+		t.Errorf("RecorderFactory() missing cassette, want error")
+	}
+}
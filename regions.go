@@ -0,0 +1,136 @@
+package cosmos_db_restapi
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DefaultTopologyRefreshInterval - age after which the regions of the account are read again
+const DefaultTopologyRefreshInterval = 5 * time.Minute
+
+// get - the cached account and the time it was read
+func (me *tAccountCache) get() (*TDatabaseAccount, time.Time) {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	return me.account, me.read_at
+}
+
+/*
+RefreshTopology - read the database account again to learn the readable and
+writable regional endpoints
+*/
+func (me *TDatabase) RefreshTopology() error {
+	status, body, _ := me.ReadDatabaseAccount()
+	if !strings.HasPrefix(status, "200") {
+		return fmt.Errorf("read database account: %s %s", status, body)
+	}
+	return nil
+}
+
+// refreshInBackground - refresh a stale topology without delaying the current request
+func (me *TDatabase) refreshInBackground() {
+	me.account.mutex.Lock()
+	if me.account.refreshing {
+		me.account.mutex.Unlock()
+		return
+	}
+	me.account.refreshing = true
+	me.account.mutex.Unlock()
+
+	go func() {
+		_ = me.RefreshTopology()
+		me.account.mutex.Lock()
+		me.account.refreshing = false
+		me.account.mutex.Unlock()
+	}()
+}
+
+func (me *TDatabase) topologyRefreshInterval() time.Duration {
+	if me.TopologyRefreshInterval > 0 {
+		return me.TopologyRefreshInterval
+	}
+	return DefaultTopologyRefreshInterval
+}
+
+/*
+Endpoints - the regional endpoints in the order they are used
+
+returns:
+
+	Read - readable endpoints, the preferred regions first
+	Write - writable endpoints, with multiple write locations the preferred regions first
+
+unhealthy endpoints are moved to the end of the lists,
+without endpoint discovery or a known account both lists only hold the EndpointUri
+*/
+func (me *TDatabase) Endpoints() (Read []string, Write []string) {
+	if !me.EnableEndpointDiscovery || me.account == nil {
+		return []string{me.EndpointUri}, []string{me.EndpointUri}
+	}
+	account, _ := me.account.get()
+	if account == nil {
+		return []string{me.EndpointUri}, []string{me.EndpointUri}
+	}
+
+	Read = orderLocations(account.ReadableLocations, me.PreferredRegions)
+	if account.EnableMultipleWriteLocations {
+		Write = orderLocations(account.WritableLocations, me.PreferredRegions)
+	} else if len(account.WritableLocations) > 0 {
+		Write = []string{account.WritableLocations[0].DatabaseAccountEndpoint}
+	}
+	if len(Write) == 0 {
+		Write = []string{me.EndpointUri}
+	}
+	if len(Read) == 0 {
+		Read = Write
+	}
+	return me.preferHealthy(Read), me.preferHealthy(Write)
+}
+
+// routeEndpoints - the endpoints for the next read or write request, the first one is used
+func (me *TDatabase) routeEndpoints(read bool) []string {
+	if !me.EnableEndpointDiscovery || me.account == nil {
+		return []string{me.EndpointUri}
+	}
+	account, read_at := me.account.get()
+	if account == nil {
+		//read once, a failed read is repeated after the backoff of cachedAccount
+		if _, err := me.cachedAccount(); err != nil {
+			return []string{me.EndpointUri}
+		}
+	} else if time.Since(read_at) > me.topologyRefreshInterval() {
+		me.refreshInBackground()
+	}
+
+	read_endpoints, write_endpoints := me.Endpoints()
+	if read {
+		return read_endpoints
+	}
+	return write_endpoints
+}
+
+// orderLocations - the endpoints of the preferred regions in their order, then all others
+func orderLocations(locations []TAccountLocation, preferred []string) (Endpoints []string) {
+	used := map[string]bool{}
+	for _, region := range preferred {
+		for _, location := range locations {
+			if strings.EqualFold(regionKey(location.Name), regionKey(region)) && !used[location.DatabaseAccountEndpoint] {
+				used[location.DatabaseAccountEndpoint] = true
+				Endpoints = append(Endpoints, location.DatabaseAccountEndpoint)
+			}
+		}
+	}
+	for _, location := range locations {
+		if !used[location.DatabaseAccountEndpoint] {
+			used[location.DatabaseAccountEndpoint] = true
+			Endpoints = append(Endpoints, location.DatabaseAccountEndpoint)
+		}
+	}
+	return
+}
+
+// regionKey - "West Europe" and "westeurope" name the same region
+func regionKey(region string) string {
+	return strings.ReplaceAll(region, " ", "")
+}
@@ -0,0 +1,124 @@
+package cosmos_db_restapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// regionServer - a regional endpoint which counts its requests
+func regionServer(name string, hits map[string]int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits[name] += 1
+		if r.Method == "POST" {
+			w.WriteHeader(http.StatusCreated)
+		}
+	}))
+}
+
+func TestEndpointRouting(t *testing.T) {
+	hits := map[string]int{}
+	west := regionServer("west", hits)
+	defer west.Close()
+	north := regionServer("north", hits)
+	defer north.Close()
+
+	account := `{"id":"account",
+		"writableLocations":[{"name":"West Europe","databaseAccountEndpoint":"` + west.URL + `/"}],
+		"readableLocations":[{"name":"West Europe","databaseAccountEndpoint":"` + west.URL + `/"},
+			{"name":"North Europe","databaseAccountEndpoint":"` + north.URL + `/"}]}`
+	global := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits["global"] += 1
+		w.Write([]byte(account))
+	}))
+	defer global.Close()
+
+	database := DatabaseFactory(global.URL+"/", "", "db")
+	database.EnableEndpointDiscovery = true
+	database.PreferredRegions = []string{"northeurope"}
+	container := ContainerFactory(database, "coll", "pk")
+
+	tests := []struct {
+		name     string
+		call     func() (string, string)
+		wantHits map[string]int
+	}{
+		{"read to preferred region", func() (string, string) { return container.GetDocumentByID("1") },
+			map[string]int{"global": 1, "north": 1}},
+		{"write to write region", func() (string, string) { return container.CreateDocument(false, `{"id":"1"}`) },
+			map[string]int{"global": 1, "north": 1, "west": 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.call()
+			for name, want := range tt.wantHits {
+				if hits[name] != want {
+					t.Errorf("hits[%v] = %v, want %v", name, hits[name], want)
+				}
+			}
+		})
+	}
+
+	read, write := database.Endpoints()
+	if read[0] != north.URL+"/" || read[1] != west.URL+"/" || write[0] != west.URL+"/" {
+		t.Errorf("Endpoints() = %v, %v", read, write)
+	}
+}
+
+func TestWriteForbiddenRefresh(t *testing.T) {
+	hits := map[string]int{}
+	new_write := regionServer("new", hits)
+	defer new_write.Close()
+	old_write := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits["old"] += 1
+		w.Header().Set("x-ms-substatus", "3")
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer old_write.Close()
+
+	write_endpoint := old_write.URL
+	global := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits["global"] += 1
+		w.Write([]byte(`{"writableLocations":[{"name":"a","databaseAccountEndpoint":"` + write_endpoint + `/"}]}`))
+		write_endpoint = new_write.URL //the write region moved after the first read
+	}))
+	defer global.Close()
+
+	database := DatabaseFactory(global.URL+"/", "", "db")
+	database.EnableEndpointDiscovery = true
+	container := ContainerFactory(database, "coll", "pk")
+
+	gotStatus, _ := container.CreateDocument(false, `{"id":"1"}`)
+	if gotStatus != "201 Created" {
+		t.Errorf("CreateDocument() gotStatus = %v, want 201 Created", gotStatus)
+	}
+	if hits["global"] != 2 || hits["old"] != 1 || hits["new"] != 1 {
+		t.Errorf("hits = %v", hits)
+	}
+}
+
+func TestRoutingWithoutAccount(t *testing.T) {
+	account_reads, documents := 0, 0
+	global := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			account_reads += 1
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		documents += 1
+	}))
+	defer global.Close()
+
+	database := DatabaseFactory(global.URL+"/", "", "db")
+	database.EnableEndpointDiscovery = true
+	container := ContainerFactory(database, "coll", "pk")
+	for i := 0; i < 5; i++ {
+		if status, _ := container.GetDocumentByID("1"); status != "200 OK" {
+			t.Errorf("GetDocumentByID() = %v", status)
+		}
+	}
+	//the failed account read is not repeated for each request
+	if account_reads != 1 || documents != 5 {
+		t.Errorf("account reads = %v, document reads = %v", account_reads, documents)
+	}
+}
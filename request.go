@@ -0,0 +1,383 @@
+package cosmos_db_restapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TResponseMeta - metadata of a cosmos db response, read from the response headers
+type TResponseMeta struct {
+	StatusCode         int              `json:"status_code"`
+	SubStatusCode      int              `json:"sub_status_code"`    //x-ms-substatus
+	RequestCharge      float64          `json:"request_charge"`     //x-ms-request-charge in RU
+	ActivityID         string           `json:"activity_id"`        //x-ms-activity-id
+	SessionToken       string           `json:"session_token"`      //x-ms-session-token
+	ItemCount          int              `json:"item_count"`         //x-ms-item-count
+	ServerDurationInMs float64          `json:"server_duration_ms"` //x-ms-request-duration-ms
+	ETag               string           `json:"etag"`               //etag of the resource
+	Continuation       string           `json:"continuation"`       //x-ms-continuation
+	RetryAfterInMs     int              `json:"retry_after_ms"`     //x-ms-retry-after-ms
+	ResourceQuota      map[string]int64 `json:"resource_quota"`     //x-ms-resource-quota
+	ResourceUsage      map[string]int64 `json:"resource_usage"`     //x-ms-resource-usage
+	Duration           time.Duration    `json:"duration"`           //client side duration of the request
+}
+
+/*
+ParseResponseMeta - read the metadata of a response from the status code and the headers
+*/
+func ParseResponseMeta(status_code int, header http.Header) (Meta TResponseMeta) {
+	Meta.StatusCode = status_code
+	Meta.SubStatusCode, _ = strconv.Atoi(header.Get("x-ms-substatus"))
+	Meta.RequestCharge, _ = strconv.ParseFloat(header.Get("x-ms-request-charge"), 64)
+	Meta.ActivityID = header.Get("x-ms-activity-id")
+	Meta.SessionToken = header.Get("x-ms-session-token")
+	Meta.ItemCount, _ = strconv.Atoi(header.Get("x-ms-item-count"))
+	Meta.ServerDurationInMs, _ = strconv.ParseFloat(header.Get("x-ms-request-duration-ms"), 64)
+	Meta.ETag = header.Get("etag")
+	Meta.Continuation = header.Get("x-ms-continuation")
+	Meta.RetryAfterInMs, _ = strconv.Atoi(header.Get("x-ms-retry-after-ms"))
+	Meta.ResourceQuota = parseResourceHeader(header.Get("x-ms-resource-quota"))
+	Meta.ResourceUsage = parseResourceHeader(header.Get("x-ms-resource-usage"))
+	return
+}
+
+// parseResourceHeader - "documentSize=10240;documentsSize=10485760;collectionSize=10485760;"
+func parseResourceHeader(header string) map[string]int64 {
+	if header == "" {
+		return nil
+	}
+	values := map[string]int64{}
+	for _, pair := range strings.Split(header, ";") {
+		key, value, found := strings.Cut(strings.TrimSpace(pair), "=")
+		if !found {
+			continue
+		}
+		if number, err := strconv.ParseInt(value, 10, 64); err == nil {
+			values[key] = number
+		}
+	}
+	return values
+}
+
+// TRequestChargeSnapshot - exported state of a TRequestCharge counter
+type TRequestChargeSnapshot struct {
+	Total       float64            `json:"total"`        //sum of all request charges in RU
+	Requests    int64              `json:"requests"`     //number of responses
+	ByOperation map[string]float64 `json:"by_operation"` //RU per operation, i.e. "Query docs"
+	Since       time.Time          `json:"since"`        //start or last reset of the counter
+}
+
+// TRequestCharge - cumulative request charge (RU) counter, safe for concurrent use
+type TRequestCharge struct {
+	mutex        sync.Mutex
+	total        float64
+	requests     int64
+	by_operation map[string]float64
+	since        time.Time
+}
+
+// RequestChargeFactory - creates a request charge counter
+func RequestChargeFactory() *TRequestCharge {
+	return &TRequestCharge{
+		by_operation: map[string]float64{},
+		since:        time.Now().UTC(),
+	}
+}
+
+// Add - adds the charge of one response
+func (me *TRequestCharge) Add(operation string, charge float64) {
+	if me == nil {
+		return
+	}
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	if me.by_operation == nil {
+		me.by_operation = map[string]float64{}
+	}
+	me.total += charge
+	me.requests += 1
+	me.by_operation[operation] += charge
+}
+
+// Total - the sum of all request charges in RU since the last reset
+func (me *TRequestCharge) Total() float64 {
+	if me == nil {
+		return 0
+	}
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	return me.total
+}
+
+// Export - the current state of the counter
+func (me *TRequestCharge) Export() (Snapshot TRequestChargeSnapshot) {
+	if me == nil {
+		return
+	}
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	Snapshot.Total = me.total
+	Snapshot.Requests = me.requests
+	Snapshot.Since = me.since
+	Snapshot.ByOperation = map[string]float64{}
+	for operation, charge := range me.by_operation {
+		Snapshot.ByOperation[operation] = charge
+	}
+	return
+}
+
+// Reset - sets the counter to zero and returns the state before the reset
+func (me *TRequestCharge) Reset() (Snapshot TRequestChargeSnapshot) {
+	if me == nil {
+		return
+	}
+	Snapshot = me.Export()
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	me.total = 0
+	me.requests = 0
+	me.by_operation = map[string]float64{}
+	me.since = time.Now().UTC()
+	return
+}
+
+// tResponse - result of a request sent via TDatabase.send
+type tResponse struct {
+	Status string
+	Body   string
+	Header http.Header
+	Meta   TResponseMeta
+	Err    error
+}
+
+// errorResponse - a response created by the client, with an error body like the cosmos db
+func errorResponse(status_code int, code string, err error) tResponse {
+	body, _ := json.Marshal(TBody{Code: code, Message: err.Error()})
+	return tResponse{
+		Status: strconv.Itoa(status_code) + " " + http.StatusText(status_code),
+		Body:   string(body),
+		Meta:   TResponseMeta{StatusCode: status_code},
+		Err:    err,
+	}
+}
+
+// operationName - name of the operation for the request charge statistic, i.e. "Query docs"
+func operationName(verb string, resource_type string, header http.Header) string {
+	operation := verb
+	switch {
+	case header.Get("x-ms-documentdb-isquery") != "":
+		operation = "Query"
+	case header.Get("x-ms-documentdb-is-upsert") != "":
+		operation = "Upsert"
+	case verb == "GET":
+		operation = "Read"
+	case verb == "POST":
+		operation = "Create"
+	case verb == "PUT":
+		operation = "Replace"
+	case verb == "DELETE":
+		operation = "Delete"
+	case verb == "PATCH":
+		operation = "Patch"
+	}
+	return operation + " " + resource_type
+}
+
+/*
+send - signs and executes a request against the cosmos db
+
+parameters:
+
+	ctx - context of the request
+	verb - http method i.e. "GET"
+	resource_type - type of the resource i.e. "docs"
+	resource_link - link for the signature i.e. "dbs/db/colls/coll"
+	path - path of the url relative to the endpoint i.e. "dbs/db/colls/coll/docs"
+	header - additional request headers or nil
+	body - request body or nil
+
+with endpoint discovery reads go to the preferred read region and writes to
+the write region, a 403 with sub status 3 (write forbidden) refreshes the
+regions of the account and the request is sent once more,
+on network errors or 503 the request fails over to the next region
+(writes only for accounts with multiple write locations)
+*/
+func (me *TDatabase) send(ctx context.Context, verb string, resource_type string, resource_link string, path string, header http.Header, body []byte) (res tResponse) {
+
+	if header == nil {
+		header = http.Header{}
+	}
+
+	//the database account itself is always read via the global endpoint
+	if resource_type == "" && resource_link == "" {
+		return me.sendTo(ctx, me.EndpointUri, verb, resource_type, resource_link, path, header, body)
+	}
+
+	read := isReadRequest(verb, header)
+	endpoints := me.routeEndpoints(read)
+	refreshed := false
+	for i := 0; i < len(endpoints); i++ {
+		endpoint := endpoints[i]
+		res = me.sendTo(ctx, endpoint, verb, resource_type, resource_link, path, header, body)
+
+		if me.EnableEndpointDiscovery && !refreshed && res.Meta.StatusCode == http.StatusForbidden && res.Meta.SubStatusCode == 3 {
+			refreshed = true
+			me.RefreshTopology()
+			endpoints = me.routeEndpoints(read)
+			i = -1
+			continue
+		}
+
+		//the health of the endpoints is only kept for the failover between the regions
+		if !me.EnableEndpointDiscovery || !me.recordResult(endpoint, res) || ctx.Err() != nil {
+			return
+		}
+		if i+1 < len(endpoints) {
+			me.emitEndpointEvent(TEndpointEvent{Kind: EndpointFailover, Endpoint: endpoint, Next: endpoints[i+1], Err: res.Err})
+		}
+	}
+	return
+}
+
+/*
+sendTo - signs and executes a request against one endpoint of the cosmos db
+
+parameters:
+
+	ctx - context of the request
+	endpoint - the global or a regional endpoint uri
+	verb - http method i.e. "GET"
+	resource_type - type of the resource i.e. "docs"
+	resource_link - link for the signature i.e. "dbs/db/colls/coll"
+	path - path of the url relative to the endpoint i.e. "dbs/db/colls/coll/docs"
+	header - additional request headers or nil
+	body - request body or nil
+
+the common headers (authorization, date, version, accept) are set here,
+reads and queries get the session token of the collection,
+the request charge of the response is added to the counter of the database
+and the session token of the response is kept for the collection,
+with Keys a request which is not authorized is sent once more with the other key,
+a request rejected for the clock skew is signed once more with the time of the server
+*/
+func (me *TDatabase) sendTo(ctx context.Context, endpoint string, verb string, resource_type string, resource_link string, path string, header http.Header, body []byte) (res tResponse) {
+	if me.Keys == nil {
+		return me.sendInTime(ctx, endpoint, verb, resource_type, resource_link, path, header, body, me.MasterKey)
+	}
+	name, key := me.Keys.Key()
+	res = me.sendInTime(ctx, endpoint, verb, resource_type, resource_link, path, header, body, key)
+	if res.Meta.StatusCode != http.StatusUnauthorized {
+		return
+	}
+	alternate_name, alternate := me.Keys.alternate(name)
+	if alternate == "" || alternate == key {
+		return
+	}
+	alternate_res := me.sendInTime(ctx, endpoint, verb, resource_type, resource_link, path, header, body, alternate)
+	if alternate_res.Meta.StatusCode == http.StatusUnauthorized {
+		return
+	}
+	_ = me.Keys.Use(alternate_name)
+	return alternate_res
+}
+
+// KeyInUse - the key the requests are signed with, KeyPrimary or KeySecondary of the Keys, else KeyMaster
+func (me *TDatabase) KeyInUse() string {
+	if me.Keys == nil {
+		return KeyMaster
+	}
+	return me.Keys.InUse()
+}
+
+/*
+sendInTime - executes the request signed with the master key, corrects the clock skew
+
+if the date of the request is too far off the time of the server, the offset of the clock
+is taken from the Date header of the response and the request is signed and sent once more
+*/
+func (me *TDatabase) sendInTime(ctx context.Context, endpoint string, verb string, resource_type string, resource_link string, path string, header http.Header, body []byte, master_key string) (res tResponse) {
+	res, skew, measured := me.sendSigned(ctx, endpoint, verb, resource_type, resource_link, path, header, body, master_key)
+	if me.Clock == nil || !isClockSkewError(res) || !measured {
+		return
+	}
+	me.Clock.correct(skew) //the skew of this response, other requests may have measured since
+	res, _, _ = me.sendSigned(ctx, endpoint, verb, resource_type, resource_link, path, header, body, master_key)
+	return
+}
+
+// sendSigned - executes the request signed with the master key, returns the clock skew measured from the Date header of the response
+func (me *TDatabase) sendSigned(ctx context.Context, endpoint string, verb string, resource_type string, resource_link string, path string, header http.Header, body []byte, master_key string) (res tResponse, skew time.Duration, measured bool) {
+
+	start := time.Now()
+
+	date_str := strings.ToLower(me.Clock.Now().UTC().Format(http.TimeFormat))
+
+	autorization_str, err := AuthorizationToken(verb, resource_type, resource_link, date_str, master_key)
+	if err != nil {
+		res = errorResponse(http.StatusUnauthorized, "Unauthorized", err)
+		return
+	}
+
+	req, err := http.NewRequestWithContext(ctx, verb, endpoint+path, bytes.NewBuffer(body))
+	if err != nil {
+		res.Err = err
+		res.Body = err.Error()
+		return
+	}
+
+	req.Header.Set("Accept", "*/*")
+	req.Header.Set("authorization", autorization_str)
+	req.Header.Set("x-ms-version", "2020-11-05")
+	req.Header.Set("x-ms-date", date_str)
+	req.Header.Set("Content-Type", "application/json")
+	for key, values := range header {
+		req.Header[http.CanonicalHeaderKey(key)] = values
+	}
+
+	collection_link := collectionOfLink(unescapePath(path))
+	if req.Header.Get("x-ms-session-token") == "" && isReadRequest(verb, header) {
+		if token := me.Sessions.tokenFor(collection_link, req.Header); token != "" {
+			req.Header.Set("x-ms-session-token", token)
+		}
+	}
+
+	transport := me.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	http_client := &http.Client{Transport: transport}
+	http_res, err := http_client.Do(req)
+	if err != nil {
+		res.Err = err
+		res.Body = err.Error()
+		res.Meta.Duration = time.Since(start)
+		return
+	}
+	defer http_res.Body.Close()
+
+	res_body, err := ioutil.ReadAll(http_res.Body)
+	res.Err = err
+	res.Status = http_res.Status
+	res.Body = string(res_body)
+	res.Header = http_res.Header
+	res.Meta = ParseResponseMeta(http_res.StatusCode, http_res.Header)
+	res.Meta.Duration = time.Since(start)
+	skew, measured = me.Clock.measure(http_res.Header, start, start.Add(res.Meta.Duration))
+
+	me.Charge.Add(operationName(verb, resource_type, header), res.Meta.RequestCharge)
+	me.Sessions.Update(collection_link, res.Meta.SessionToken)
+	me.Sessions.learnRange(collection_link, req.Header.Get("x-ms-documentdb-partitionkey"), res.Meta.SessionToken)
+	return
+}
+
+// partitionKeyHeader - the partition key as json array for x-ms-documentdb-partitionkey
+func partitionKeyHeader(partitionkey string) string {
+	return "[ " + "\"" + partitionkey + "\"" + " ]"
+}
@@ -0,0 +1,133 @@
+package cosmos_db_restapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseResponseMeta(t *testing.T) {
+	header := http.Header{}
+	header.Set("x-ms-request-charge", "2.85")
+	header.Set("x-ms-activity-id", "856acd38-320d-47df-ab6f-9761bb987668")
+	header.Set("x-ms-session-token", "0:-1#12")
+	header.Set("x-ms-item-count", "3")
+	header.Set("x-ms-request-duration-ms", "0.512")
+	header.Set("x-ms-resource-quota", "documentSize=10240;documentsSize=10485760;collectionSize=10485760;")
+	header.Set("x-ms-resource-usage", "documentSize=0;documentsSize=1;collectionSize=2;")
+
+	meta := ParseResponseMeta(200, header)
+
+	if meta.StatusCode != 200 || meta.RequestCharge != 2.85 || meta.ItemCount != 3 || meta.ServerDurationInMs != 0.512 {
+		t.Errorf("ParseResponseMeta() = %+v", meta)
+	}
+	if meta.ActivityID != "856acd38-320d-47df-ab6f-9761bb987668" || meta.SessionToken != "0:-1#12" {
+		t.Errorf("ParseResponseMeta() ids = %v, %v", meta.ActivityID, meta.SessionToken)
+	}
+	if meta.ResourceQuota["documentsSize"] != 10485760 || meta.ResourceUsage["collectionSize"] != 2 {
+		t.Errorf("ParseResponseMeta() quota = %v, usage = %v", meta.ResourceQuota, meta.ResourceUsage)
+	}
+}
+
+func TestRequestCharge(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("x-ms-request-charge", "1.5")
+		w.Header().Set("x-ms-activity-id", "activity")
+		switch {
+		case r.Method == "DELETE":
+			w.WriteHeader(http.StatusNoContent)
+			return
+		case r.Method == "POST" && r.Header.Get("x-ms-documentdb-isquery") == "":
+			w.WriteHeader(http.StatusCreated)
+		}
+		w.Write([]byte(`{"id":"Zwerg"}`))
+	}))
+	defer server.Close()
+
+	container := ContainerFactory(DatabaseFactory(server.URL+"/", "", "db"), "dictionary", "Zwerg")
+
+	tests := []struct {
+		name       string
+		call       func() (string, string)
+		wantStatus string
+		wantTotal  float64
+	}{
+		{"read", func() (string, string) { return container.GetDocumentByID("Zwerg") }, "200 OK", 1.5},
+		{"create", func() (string, string) { return container.CreateDocument(false, `{"id":"Zwerg"}`) }, "201 Created", 3},
+		{"query", func() (string, string) {
+			status, body, _ := container.ExecuteQuerry(0, "", TQuery{Query: "SELECT * FROM c"})
+			return status, body
+		}, "200 OK", 4.5},
+		{"delete", func() (string, string) { return container.DeleteDocumentByID("Zwerg") }, "204 No Content", 6},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotStatus, _ := tt.call()
+			if gotStatus != tt.wantStatus {
+				t.Errorf("gotStatus = %v, want %v", gotStatus, tt.wantStatus)
+			}
+			if container.Meta.RequestCharge != 1.5 || container.Meta.ActivityID != "activity" {
+				t.Errorf("Meta = %+v", container.Meta)
+			}
+			if got := container.Database.Charge.Total(); got != tt.wantTotal {
+				t.Errorf("Charge.Total() = %v, want %v", got, tt.wantTotal)
+			}
+		})
+	}
+
+	snapshot := container.Database.Charge.Reset()
+	if snapshot.Requests != 4 || snapshot.ByOperation["Read docs"] != 1.5 || snapshot.ByOperation["Create docs"] != 1.5 {
+		t.Errorf("Reset() = %+v", snapshot)
+	}
+	if container.Database.Charge.Total() != 0 {
+		t.Errorf("Total() after Reset() = %v, want 0", container.Database.Charge.Total())
+	}
+}
+
+func TestPackageFunctionsWithMeta(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("x-ms-request-charge", "2.5")
+		w.Header().Set("x-ms-session-token", "0:1#7")
+		switch {
+		case r.Method == "DELETE":
+			w.WriteHeader(http.StatusNoContent)
+			return
+		case r.Method == "POST" && r.Header.Get("x-ms-documentdb-isquery") == "":
+			w.WriteHeader(http.StatusCreated)
+		}
+		w.Write([]byte(`{"id":"Zwerg"}`))
+	}))
+	defer server.Close()
+	uri := server.URL + "/"
+
+	tests := []struct {
+		name       string
+		call       func() (string, TResponseMeta)
+		wantStatus string
+	}{
+		{"read", func() (string, TResponseMeta) {
+			status, _, meta := GetDocumentByIDWithMeta(uri, "", "db", "dictionary", "Zwerg", "Zwerg")
+			return status, meta
+		}, "200 OK"},
+		{"create", func() (string, TResponseMeta) {
+			status, _, meta := CreateDocumentWithMeta(uri, "", "db", "dictionary", "Zwerg", true, `{"id":"Zwerg"}`)
+			return status, meta
+		}, "201 Created"},
+		{"query", func() (string, TResponseMeta) {
+			status, _, _, _, meta := ExecuteQuerryWithMeta(uri, "", "db", "dictionary", "", 0, "", TQuery{Query: "SELECT * FROM c"}, TRequestOptions{})
+			return status, meta
+		}, "200 OK"},
+		{"delete", func() (string, TResponseMeta) {
+			status, _, meta := DeleteDocumentByIDWithMeta(uri, "", "db", "dictionary", "Zwerg", "Zwerg")
+			return status, meta
+		}, "204 No Content"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, meta := tt.call()
+			if status != tt.wantStatus || meta.RequestCharge != 2.5 || meta.SessionToken != "0:1#7" {
+				t.Errorf("status = %v, meta = %+v", status, meta)
+			}
+		})
+	}
+}
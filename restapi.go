@@ -5,16 +5,14 @@ Golang Package for the cosmos db rest api
 package cosmos_db_restapi
 
 import (
-	"bytes"
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"net/http"
 	"net/url"
-	"os"
 	"strconv"
 	"strings"
 	"time"
@@ -53,7 +51,7 @@ type TBody struct {
 
 /*
 GetAuthorizationTokenUsingMasterKey
-function for generating access token
+function for generating access token, "" for a master key which is not base64 encoded
 
 https://docs.microsoft.com/en-us/rest/api/cosmos-db/access-control-on-cosmosdb-resources
 */
@@ -64,12 +62,29 @@ func GetAuthorizationTokenUsingMasterKey(
 	date string,
 	masterKey string) string {
 
-	key, _ := base64.StdEncoding.DecodeString(masterKey)
+	token, _ := AuthorizationToken(verb, resourceType, resourceId, date, masterKey)
+	return token
+}
+
+/*
+AuthorizationToken - the access token of a request signed with the master key
+
+returns:
+
+	Token - the url encoded token for the authorization header
+	err - the master key is not base64 encoded
+*/
+func AuthorizationToken(verb string, resource_type string, resource_link string, date string, master_key string) (Token string, err error) {
+
+	key, err := base64.StdEncoding.DecodeString(master_key)
+	if err != nil {
+		return "", fmt.Errorf("the master key is not base64 encoded: %w", err)
+	}
 
 	text := strings.ToLower(verb) + "\n" +
-		strings.ToLower(resourceType) + "\n" +
+		strings.ToLower(resource_type) + "\n" +
 		//		strings.ToLower(resourceId) + "\n" +
-		resourceId + "\n" +
+		resource_link + "\n" +
 		strings.ToLower(date) + "\n" +
 		"" + "\n"
 
@@ -83,7 +98,7 @@ func GetAuthorizationTokenUsingMasterKey(
 
 	TokenVersion := "1.0"
 
-	return url.QueryEscape("type=" + MasterToken + "&ver=" + TokenVersion + "&sig=" + signature)
+	return url.QueryEscape("type=" + MasterToken + "&ver=" + TokenVersion + "&sig=" + signature), nil
 }
 
 /*
@@ -104,55 +119,58 @@ returns:
 	Status - response status i.e. 200 ok
 	Body - response body as string
 	Continuation - the Continuation-token if there are more items to read
+
+the metadata of the response is returned by ExecuteQuerryWithMeta
 */
 func ExecuteQuerry(endpoint_uri string, master_key string, database string, container string, partitionkey string, max_item_count int, continuation string, query TQuery) (Status string, Body string, Continuation string) {
+	Status, Body, Continuation, _ = ExecuteQuerryWithOptions(endpoint_uri, master_key, database, container, partitionkey, max_item_count, continuation, query, TRequestOptions{})
+	return
+}
 
-	date_str := strings.ToLower(time.Now().UTC().Format(http.TimeFormat))
-
-	resource_link := strings.ToLower("dbs/" + database + "/colls/" + container)
-
-	autorization_str := GetAuthorizationTokenUsingMasterKey("POST", "docs",
-		resource_link, date_str, master_key)
-
-	url := endpoint_uri + resource_link + "/docs"
-
-	querry_json, _ := json.Marshal(query)
-
-	req, _ := http.NewRequest("POST", url, bytes.NewBuffer(querry_json))
-
-	req.Header.Set("Accept", "*/*")
-	req.Header.Set("x-ms-documentdb-isquery", "True")
-	req.Header.Set("x-ms-documentdb-query-enablecrosspartition", "True")
-	req.Header.Set("authorization", autorization_str)
-
-	req.Header.Set("x-ms-version", "2020-11-05")
-	req.Header.Set("x-ms-date", date_str)
-	req.Header.Set("Content-Type", "application/query+json")
-	if partitionkey != "" {
-		req.Header.Set("x-ms-documentdb-partitionkey", "[ "+"\""+partitionkey+"\""+" ]")
-	} else {
-		req.Header.Set("x-ms-documentdb-query-enablecrosspartition", "True")
-	}
+// TRequestOptions - optional settings for read, query and write requests
+type TRequestOptions struct {
+	PopulateQueryMetrics bool              `json:"populate_query_metrics"` //x-ms-documentdb-populatequerymetrics
+	PopulateIndexMetrics bool              `json:"populate_index_metrics"` //x-ms-cosmos-populateindexmetrics
+	SessionToken         string            `json:"session_token"`          //explicit x-ms-session-token for reads, else the tracked token
+	ConsistencyLevel     TConsistencyLevel `json:"consistency_level"`      //x-ms-consistency-level for reads, only weaker than the account default
+	PreTriggers          []string          `json:"pre_triggers"`           //triggers run before document writes
+	PostTriggers         []string          `json:"post_triggers"`          //triggers run after document writes
+}
 
-	if max_item_count > 0 {
-		req.Header.Set("x-ms-max-item-count", strconv.Itoa(max_item_count))
-	}
+/*
+ExecuteQuerryWithOptions - execute a query as rest api with request options
 
-	if continuation != "" {
-		req.Header.Set("x-ms-continuation", continuation)
-	}
+parameters:
 
-	//req.Header.Set("x-ms-documentdb-populatequerymetrics", "True")
+	endpoint_uri - uri from cosmos db
+	master_key - master key from cosmos db
+	database - name of database
+	container - name of container
+	partitionkey - optional partition key else ""
+	max_item_count - optional max item count else 0
+	querry - like TQuery
+	options - like TRequestOptions, i.e. to request query and index metrics
 
-	http_client := &http.Client{}
-	res, _ := http_client.Do(req)
+returns:
 
-	Continuation = res.Header.Get("x-ms-continuation")
+	Status - response status i.e. 200 ok
+	Body - response body as string
+	Continuation - the Continuation-token if there are more items to read
+	Diagnostics - query and index metrics, if requested by the options
 
-	res_body, _ := ioutil.ReadAll(res.Body)
-	defer res.Body.Close()
+the metadata of the response is returned by ExecuteQuerryWithMeta
+*/
+func ExecuteQuerryWithOptions(endpoint_uri string, master_key string, database string, container string, partitionkey string, max_item_count int, continuation string, query TQuery, options TRequestOptions) (Status string, Body string, Continuation string, Diagnostics TQueryDiagnostics) {
+	Status, Body, Continuation, Diagnostics, _ = ExecuteQuerryWithMeta(endpoint_uri, master_key, database, container, partitionkey, max_item_count, continuation, query, options)
+	return
+}
 
-	return res.Status, string(res_body), Continuation
+// ExecuteQuerryWithMeta - ExecuteQuerryWithOptions, Meta is the metadata of the response i.e. request charge and session token
+func ExecuteQuerryWithMeta(endpoint_uri string, master_key string, database string, container string, partitionkey string, max_item_count int, continuation string, query TQuery, options TRequestOptions) (Status string, Body string, Continuation string, Diagnostics TQueryDiagnostics, Meta TResponseMeta) {
+	me := ContainerFactory(DatabaseFactory(endpoint_uri, master_key, database), container, partitionkey)
+	me.Options = options
+	Status, Body, Continuation, Diagnostics = me.executeQuerry(max_item_count, continuation, query)
+	return Status, Body, Continuation, Diagnostics, me.Meta
 }
 
 /*
@@ -171,39 +189,19 @@ returns:
 
 	Status - response status i.e. 200 ok
 	Body - response body as string
+
+the metadata of the response is returned by GetDocumentByIDWithMeta
 */
 func GetDocumentByID(endpoint_uri string, master_key string, database string, container string, partitionkey string, id string) (Status string, Body string) {
+	Status, Body, _ = GetDocumentByIDWithMeta(endpoint_uri, master_key, database, container, partitionkey, id)
+	return
+}
 
-	date_str := strings.ToLower(time.Now().UTC().Format(http.TimeFormat))
-
-	resource_link := strings.ToLower("dbs/"+database+"/colls/"+container+"/docs/") + id
-
-	autorization_str := GetAuthorizationTokenUsingMasterKey("GET", "docs", resource_link, date_str, master_key)
-
-	url := endpoint_uri + resource_link
-
-	req, _ := http.NewRequest("GET", url, nil)
-
-	req.Header.Set("Accept", "*/*")
-
-	req.Header.Set("authorization", autorization_str)
-
-	req.Header.Set("x-ms-version", "2020-11-05")
-	req.Header.Set("x-ms-date", date_str)
-	req.Header.Set("Content-Type", "application/json")
-	if partitionkey != "" {
-		req.Header.Set("x-ms-documentdb-partitionkey", "[ "+"\""+partitionkey+"\""+" ]")
-	}
-
-	//req.Header.Set("x-ms-documentdb-populatequerymetrics", "True")
-
-	http_client := &http.Client{}
-	res, _ := http_client.Do(req)
-
-	res_body, _ := ioutil.ReadAll(res.Body)
-	defer res.Body.Close()
-
-	return res.Status, string(res_body)
+// GetDocumentByIDWithMeta - GetDocumentByID, Meta is the metadata of the response i.e. request charge and session token
+func GetDocumentByIDWithMeta(endpoint_uri string, master_key string, database string, container string, partitionkey string, id string) (Status string, Body string, Meta TResponseMeta) {
+	me := ContainerFactory(DatabaseFactory(endpoint_uri, master_key, database), container, partitionkey)
+	Status, Body = me.GetDocumentByID(id)
+	return Status, Body, me.Meta
 }
 
 /*
@@ -221,42 +219,20 @@ parameters:
 returns:
 	Status - response status i.e. 201 Created
 	Body - response body as string
+
+the metadata of the response is returned by CreateDocumentWithMeta
 */
 
 func CreateDocument(endpoint_uri string, master_key string, database string, container string, partitionkey string, upset bool, data string) (Status string, Body string) {
+	Status, Body, _ = CreateDocumentWithMeta(endpoint_uri, master_key, database, container, partitionkey, upset, data)
+	return
+}
 
-	date_str := strings.ToLower(time.Now().UTC().Format(http.TimeFormat))
-
-	resource_link := strings.ToLower("dbs/" + database + "/colls/" + container)
-
-	autorization_str := GetAuthorizationTokenUsingMasterKey("POST", "docs", resource_link, date_str, master_key)
-
-	url := endpoint_uri + resource_link + "/docs"
-
-	req, _ := http.NewRequest("POST", url, bytes.NewBuffer([]byte(data)))
-
-	req.Header.Set("Accept", "*/*")
-
-	req.Header.Set("authorization", autorization_str)
-
-	req.Header.Set("x-ms-version", "2020-11-05")
-	req.Header.Set("x-ms-date", date_str)
-	req.Header.Set("Content-Type", "application/json")
-	if upset == true {
-		req.Header.Set("x-ms-documentdb-is-upsert", "True") //create or update if exist
-	}
-	if partitionkey != "" {
-		req.Header.Set("x-ms-documentdb-partitionkey", "[ "+"\""+partitionkey+"\""+" ]")
-	}
-
-	//req.Header.Set("x-ms-documentdb-populatequerymetrics", "True")
-
-	http_client := &http.Client{}
-	res, _ := http_client.Do(req)
-
-	res_body, _ := ioutil.ReadAll(res.Body)
-
-	return res.Status, string(res_body)
+// CreateDocumentWithMeta - CreateDocument, Meta is the metadata of the response i.e. request charge and session token
+func CreateDocumentWithMeta(endpoint_uri string, master_key string, database string, container string, partitionkey string, upset bool, data string) (Status string, Body string, Meta TResponseMeta) {
+	me := ContainerFactory(DatabaseFactory(endpoint_uri, master_key, database), container, partitionkey)
+	Status, Body = me.CreateDocument(upset, data)
+	return Status, Body, me.Meta
 }
 
 /*
@@ -275,39 +251,19 @@ returns:
 
 	Status - response status i.e. 204 No Content
 	Body - response body as string i.e. ""
+
+the metadata of the response is returned by DeleteDocumentByIDWithMeta
 */
 func DeleteDocumentByID(endpoint_uri string, master_key string, database string, container string, partitionkey string, id string) (Status string, Body string) {
+	Status, Body, _ = DeleteDocumentByIDWithMeta(endpoint_uri, master_key, database, container, partitionkey, id)
+	return
+}
 
-	date_str := strings.ToLower(time.Now().UTC().Format(http.TimeFormat))
-
-	resource_link := strings.ToLower("dbs/"+database+"/colls/"+container+"/docs/") + id
-
-	autorization_str := GetAuthorizationTokenUsingMasterKey("DELETE", "docs", resource_link, date_str, master_key)
-
-	url := endpoint_uri + resource_link
-
-	req, _ := http.NewRequest("DELETE", url, nil)
-
-	req.Header.Set("Accept", "*/*")
-
-	req.Header.Set("authorization", autorization_str)
-
-	req.Header.Set("x-ms-version", "2020-11-05")
-	req.Header.Set("x-ms-date", date_str)
-	req.Header.Set("Content-Type", "application/json")
-	if partitionkey != "" {
-		req.Header.Set("x-ms-documentdb-partitionkey", "[ "+"\""+partitionkey+"\""+" ]")
-	}
-
-	//req.Header.Set("x-ms-documentdb-populatequerymetrics", "True")
-
-	http_client := &http.Client{}
-	res, _ := http_client.Do(req)
-
-	res_body, _ := ioutil.ReadAll(res.Body)
-	defer res.Body.Close()
-
-	return res.Status, string(res_body)
+// DeleteDocumentByIDWithMeta - DeleteDocumentByID, Meta is the metadata of the response i.e. request charge and session token
+func DeleteDocumentByIDWithMeta(endpoint_uri string, master_key string, database string, container string, partitionkey string, id string) (Status string, Body string, Meta TResponseMeta) {
+	me := ContainerFactory(DatabaseFactory(endpoint_uri, master_key, database), container, partitionkey)
+	Status, Body = me.DeleteDocumentByID(id)
+	return Status, Body, me.Meta
 }
 
 // TDatabase - Structure for the access of the server and the database
@@ -315,6 +271,25 @@ type TDatabase struct {
 	EndpointUri string `json:"endpoint_uri"`
 	MasterKey   string `json:"master_key"`
 	Database    string `json:"database"`
+
+	EnableEndpointDiscovery bool          `json:"enable_endpoint_discovery"` //route via the regions of the account
+	PreferredRegions        []string      `json:"preferred_regions"`         //read regions in order of preference, i.e. "West Europe"
+	TopologyRefreshInterval time.Duration `json:"topology_refresh_interval"` //default DefaultTopologyRefreshInterval
+	FailureThreshold        int           `json:"failure_threshold"`         //failures until an endpoint is unhealthy, default DefaultFailureThreshold
+	ProbeInterval           time.Duration `json:"probe_interval"`            //probing of unhealthy endpoints, default DefaultProbeInterval
+	MaxRetries              int           `json:"max_retries"`               //retries of Do for throttled and transient responses, default DefaultMaxRetries, -1 for none
+	MaxRetryWait            time.Duration `json:"max_retry_wait"`            //the maximum wait of Do for retries, default DefaultMaxRetryWait
+
+	OnEndpointEvent func(event TEndpointEvent) `json:"-"` //optional, observes failover and recovery of endpoints
+	Transport       http.RoundTripper          `json:"-"` //optional, i.e. a TFaultInjector, default http.DefaultTransport
+	Keys            *TKeyProvider              `json:"-"` //optional, primary and secondary key instead of MasterKey
+
+	Charge   *TRequestCharge    `json:"-"` //cumulative request charge of all requests, shared by copies
+	Sessions *TSessionContainer `json:"-"` //session tokens per collection, shared by copies
+	Clock    *TClockSkew        `json:"-"` //offset of the local time to the server time, shared by copies
+
+	account *tAccountCache   //the database account, read on demand
+	health  *tEndpointHealth //health of the regional endpoints
 }
 
 //DatabaseFactory - creates a database object
@@ -323,6 +298,11 @@ func DatabaseFactory(endpoint_uri string, master_key string, database string) TD
 		EndpointUri: endpoint_uri,
 		MasterKey:   master_key,
 		Database:    database,
+		Charge:      RequestChargeFactory(),
+		Sessions:    SessionContainerFactory(),
+		Clock:       ClockSkewFactory(),
+		account:     &tAccountCache{},
+		health:      &tEndpointHealth{},
 	}
 }
 
@@ -337,6 +317,12 @@ type TContainer struct {
 	Steps        int       `json:"steps"`
 	Status       string    `json:"status"`
 	Body         string    `json:"body"`
+	DatabaseRid  string    `json:"database_rid"` //with ContainerByRidFactory the _rid of the database, Container is the _rid of the container
+
+	Options     TRequestOptions   `json:"options"`     //options for reads, queries and writes
+	Diagnostics TQueryDiagnostics `json:"diagnostics"` //metrics aggregated over all fetched pages
+	Meta        TResponseMeta     `json:"meta"`        //metadata of the last response
+	Error       *TCosmosError     `json:"error"`       //error of the last response, nil on success
 }
 
 // ContainerFactory - creates a container object
@@ -354,6 +340,88 @@ func ContainerFactory(database TDatabase, container string, partitionkey string)
 	}
 }
 
+/*
+ContainerByRidFactory - creates a container object which addresses the container and its resources by _rid
+
+parameters:
+
+	database - the database object, its name is not used for the resources of the container
+	database_rid - the _rid of the database
+	container_rid - the _rid of the container
+	partitionkey - the partition key
+
+the ids of the documents, attachments and scripts are their _rids then
+*/
+func ContainerByRidFactory(database TDatabase, database_rid string, container_rid string, partitionkey string) TContainer {
+	container := ContainerFactory(database, container_rid, partitionkey)
+	container.DatabaseRid = database_rid
+	return container
+}
+
+// collectionLink - the resource link of the container i.e. "dbs/db/colls/coll", by _rid with DatabaseRid
+func (me *TContainer) collectionLink() TResourceLink {
+	if me.DatabaseRid != "" {
+		return DatabaseRidLink(me.DatabaseRid).Child("colls", me.Container)
+	}
+	return DatabaseLink(me.Database.Database).Child("colls", me.Container)
+}
+
+// documentLink - the resource link of a document i.e. "dbs/db/colls/coll/docs/id"
+func (me *TContainer) documentLink(id string) TResourceLink {
+	return me.collectionLink().Child("docs", id)
+}
+
+// sendResource - sends a request for the resource of the link, i.e. read a document
+func (me *TContainer) sendResource(verb string, link TResourceLink, header http.Header, body []byte) tResponse {
+	if err := link.Err(); err != nil {
+		return me.failed(errorResponse(http.StatusBadRequest, "BadRequest", err))
+	}
+	return me.send(verb, link.ResourceType(), link.Link(), link.Path(), header, body)
+}
+
+// sendFeed - sends a request for the feed of the children of the link, i.e. query the documents of the container
+func (me *TContainer) sendFeed(verb string, resource_type string, link TResourceLink, header http.Header, body []byte) tResponse {
+	if err := link.Err(); err != nil {
+		return me.failed(errorResponse(http.StatusBadRequest, "BadRequest", err))
+	}
+	return me.send(verb, resource_type, link.Link(), link.Feed(resource_type), header, body)
+}
+
+// failed - keeps the metadata of a response created by the client
+func (me *TContainer) failed(res tResponse) tResponse {
+	me.Meta = res.Meta
+	me.Error = responseError(res)
+	return res
+}
+
+// send - sends a request for a resource of the container and keeps the metadata of the response
+func (me *TContainer) send(verb string, resource_type string, resource_link string, path string, header http.Header, body []byte) tResponse {
+	if header == nil {
+		header = http.Header{}
+	}
+	if me.PartitionKey != "" && header.Get("x-ms-documentdb-partitionkey") == "" {
+		header.Set("x-ms-documentdb-partitionkey", partitionKeyHeader(me.PartitionKey))
+	}
+	if me.Options.SessionToken != "" && isReadRequest(verb, header) {
+		header.Set("x-ms-session-token", me.Options.SessionToken)
+	}
+	if me.Options.ConsistencyLevel != "" && isReadRequest(verb, header) {
+		if err := me.Database.validateConsistency(me.Options.ConsistencyLevel); err != nil {
+			return me.failed(errorResponse(http.StatusBadRequest, "BadRequest", err))
+		}
+		header.Set("x-ms-consistency-level", string(me.Options.ConsistencyLevel))
+	}
+	if resource_type == "docs" && !isReadRequest(verb, header) {
+		if len(me.Options.PreTriggers) > 0 {
+			header.Set("x-ms-documentdb-pre-trigger-include", triggerHeader(me.Options.PreTriggers))
+		}
+		if len(me.Options.PostTriggers) > 0 {
+			header.Set("x-ms-documentdb-post-trigger-include", triggerHeader(me.Options.PostTriggers))
+		}
+	}
+	return me.failed(me.Database.send(context.Background(), verb, resource_type, resource_link, path, header, body))
+}
+
 //OpenQuery - defines a query for execution in fetch mode
 func (me *TContainer) OpenQuery(max_item_count int, query TQuery) {
 	me.MaxItemCount = max_item_count
@@ -362,6 +430,7 @@ func (me *TContainer) OpenQuery(max_item_count int, query TQuery) {
 	me.Steps = 0
 	me.Status = ""
 	me.Body = ""
+	me.Diagnostics = TQueryDiagnostics{}
 	return
 }
 
@@ -371,32 +440,123 @@ func (me *TContainer) Fetch() (Status string, Body string) {
 	me.Body = ""
 	if me.Continuation != "" || me.Steps == 0 {
 		me.Steps += 1
-		me.Status, me.Body, me.Continuation = ExecuteQuerry(me.Database.EndpointUri, me.Database.MasterKey, me.Database.Database, me.Container, me.PartitionKey, me.MaxItemCount, me.Continuation, me.Query)
-
+		var diagnostics TQueryDiagnostics
+		me.Status, me.Body, me.Continuation, diagnostics = me.executeQuerry(me.MaxItemCount, me.Continuation, me.Query)
+		me.Diagnostics.Add(diagnostics)
 	}
 	return me.Status, me.Body
 
 }
 
 func (me *TContainer) ExecuteQuerry(max_item_count int, continuation string, query TQuery) (Status string, Body string, Continuation string) {
-	return ExecuteQuerry(me.Database.EndpointUri, me.Database.MasterKey, me.Database.Database, me.Container, me.PartitionKey, max_item_count, continuation, query)
+	Status, Body, Continuation, _ = me.executeQuerry(max_item_count, continuation, query)
+	return
+}
+
+// executeQuerry - executes one page of a query with the options of the container
+func (me *TContainer) executeQuerry(max_item_count int, continuation string, query TQuery) (Status string, Body string, Continuation string, Diagnostics TQueryDiagnostics) {
+
+	querry_json, _ := json.Marshal(query)
+
+	header := http.Header{}
+	header.Set("x-ms-documentdb-isquery", "True")
+	header.Set("Content-Type", "application/query+json")
+	if me.PartitionKey == "" {
+		header.Set("x-ms-documentdb-query-enablecrosspartition", "True")
+	}
+
+	if max_item_count > 0 {
+		header.Set("x-ms-max-item-count", strconv.Itoa(max_item_count))
+	}
+
+	if continuation != "" {
+		header.Set("x-ms-continuation", continuation)
+	}
+
+	if me.Options.PopulateQueryMetrics {
+		header.Set("x-ms-documentdb-populatequerymetrics", "True")
+	}
+	if me.Options.PopulateIndexMetrics {
+		header.Set("x-ms-cosmos-populateindexmetrics", "True")
+	}
+
+	res := me.sendFeed("POST", "docs", me.collectionLink(), header, querry_json)
+
+	//metrics are only diagnostics, a malformed header must not fail the query
+	if res.Header != nil {
+		var err error
+		Diagnostics, err = parseQueryDiagnostics(
+			res.Header.Get("x-ms-documentdb-query-metrics"),
+			res.Header.Get("x-ms-cosmos-index-utilization"))
+		Diagnostics.Err = err
+	}
+
+	return res.Status, res.Body, res.Meta.Continuation, Diagnostics
+}
+
+func (me *TContainer) GetDocumentByID(id string) (Status string, Body string) {
+	res := me.sendResource("GET", me.documentLink(id), nil, nil)
+	return res.Status, res.Body
 }
 
 func (me *TContainer) CreateDocument(upset bool, data string) (Status string, Body string) {
-	return CreateDocument(me.Database.EndpointUri, me.Database.MasterKey, me.Database.Database, me.Container, me.PartitionKey, upset, data)
+	header := http.Header{}
+	if upset == true {
+		header.Set("x-ms-documentdb-is-upsert", "True") //create or update if exist
+	}
+	res := me.sendFeed("POST", "docs", me.collectionLink(), header, []byte(data))
+	return res.Status, res.Body
 }
 
 func (me *TContainer) DeleteDocumentByID(id string) (Status string, Body string) {
-	return DeleteDocumentByID(me.Database.EndpointUri, me.Database.MasterKey, me.Database.Database, me.Container, me.PartitionKey, id)
+	res := me.sendResource("DELETE", me.documentLink(id), nil, nil)
+	return res.Status, res.Body
+}
+
+/*
+ReplaceDocument - replace a document by ID via rest api, the id of the data must be the ID
+
+returns:
+
+	Status - response status i.e. 200 ok
+	Body - response body as string, the replaced document
+*/
+func (me *TContainer) ReplaceDocument(id string, data string) (Status string, Body string) {
+	res := me.sendResource("PUT", me.documentLink(id), nil, []byte(data))
+	return res.Status, res.Body
+}
+
+/*
+PatchDocument - partial update of a document by ID via rest api
+
+parameters:
+
+	id - id of the document
+	operations - up to 10 operations like TPatchOperation
+
+returns:
+
+	Status - response status i.e. 200 ok
+	Body - response body as string, the patched document
+*/
+func (me *TContainer) PatchDocument(id string, operations []TPatchOperation) (Status string, Body string) {
+	data, _ := json.Marshal(map[string]interface{}{"operations": operations})
+	header := http.Header{}
+	header.Set("Content-Type", "application/json_patch+json")
+	res := me.sendResource("PATCH", me.documentLink(id), header, data)
+	return res.Status, res.Body
 }
 
 // test()
 func test() (status string) {
 	//get the "endpoint" and master-key from the .env file
 	godotenv.Load(".env")
-	endpoint := os.Getenv("ENDPOINT_URI")
-	key := os.Getenv("MASTER_KEY")
+	config, _ := ConfigFromEnv("") //without account the requests fail
+	return testDatabase(DatabaseFactory(config.EndpointUri, config.MasterKey, "lerneria-express"))
+}
 
+// testDatabase - the tests of test with the database, i.e. with a TRecorder as Transport
+func testDatabase(database TDatabase) (status string) {
 	var querry = TQuery{
 		Query: "SELECT * FROM c WHERE c.word = @word1 OR c.word = @word2 ",
 		Parameters: []TParameter{
@@ -418,9 +578,8 @@ func test() (status string) {
 		steps += 1
 		fmt.Println("Step:", steps)
 
-		res_status, res_body, res_continuation := ExecuteQuerry(
-			endpoint, key,
-			"lerneria-express", "dictionary", "", //no patition key
+		native := ContainerFactory(database, "dictionary", "") //no patition key
+		res_status, res_body, res_continuation := native.ExecuteQuerry(
 			3, req_continuation, //only 3 documents per request
 			querry)
 		fmt.Println("Status: " + res_status)
@@ -457,10 +616,7 @@ func test() (status string) {
 	// test 2 - the object-like operations
 	fmt.Println("test 2 - the object-like operations")
 	container := ContainerFactory(
-		DatabaseFactory(
-			endpoint,
-			key,
-			"lerneria-express"),
+		database,
 		"dictionary",
 		"")
 
@@ -3,7 +3,12 @@ package cosmos_db_restapi
 import (
 	"encoding/json"
 	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"os"
+	"path/filepath"
 	"strconv"
 	"testing"
 
@@ -11,9 +16,81 @@ import (
 	"github.com/mitchellh/mapstructure"
 )
 
+/*
+useCassette - replays the cassette of the test from testdata/cassettes
+
+the cassettes are not from a real account, they were generated against the cosmosfake server
+with the containers "dictionary" (partition key /word) and "user" (/id) of "lerneria-express",
+so they have the session tokens, rids and activity ids of the fake,
+with COSMOS_RECORD=1 the test runs against the account of the .env file and the cassette is
+recorded, the database of the account must be "lerneria-express" like in test()
+
+returns the database of the test with the recorder as Transport
+*/
+func useCassette(t *testing.T, name string) TDatabase {
+	endpoint, key, database := "https://localhost:8081/", "", "lerneria-express"
+	mode := RecorderModeReplay
+	if os.Getenv("COSMOS_RECORD") == "1" {
+		godotenv.Load(".env")
+		config, err := ConfigFromEnv("")
+		if err != nil {
+			t.Fatalf("ConfigFromEnv() = %v", err)
+		}
+		endpoint, key, database = config.EndpointUri, config.MasterKey, config.Database
+		mode = RecorderModeRecord
+	}
+	recorder, err := RecorderFactory(filepath.Join("testdata", "cassettes", name+".json"), mode, nil)
+	if err != nil {
+		t.Fatalf("useCassette() = %v", err)
+	}
+	recorder.Secrets = []string{key}
+	t.Cleanup(func() {
+		if err := recorder.Save(); err != nil {
+			t.Errorf("Save() = %v", err)
+		}
+		if unused := recorder.Unused(); unused > 0 {
+			t.Errorf("%d interactions of the cassette %s were not replayed", unused, name)
+		}
+	})
+	result := DatabaseFactory(endpoint, key, database)
+	result.Transport = recorder
+	return result
+}
+
+/*
+serveCassette - serves the recorder of the database on a local endpoint for the package-level
+functions, they create their own database with http.DefaultTransport
+
+returns the endpoint uri for the package-level functions, the requests are forwarded
+to the endpoint of the database via the recorder
+*/
+func serveCassette(t *testing.T, database TDatabase) string {
+	target, err := url.Parse(database.EndpointUri)
+	if err != nil {
+		t.Fatalf("serveCassette() = %v", err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := r.Clone(r.Context())
+		req.URL.Scheme, req.URL.Host, req.Host, req.RequestURI = target.Scheme, target.Host, target.Host, ""
+		res, err := database.Transport.RoundTrip(req)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		defer res.Body.Close()
+		for key, values := range res.Header {
+			w.Header()[key] = values
+		}
+		w.WriteHeader(res.StatusCode)
+		io.Copy(w, res.Body)
+	}))
+	t.Cleanup(server.Close)
+	return server.URL + "/"
+}
+
 func TestExecuteQuerry(t *testing.T) {
 
-	godotenv.Load(".env")
+	database := useCassette(t, "execute_querry")
 
 	var querry = TQuery{
 		Query: "SELECT * FROM c WHERE c.word = @word1 OR c.word = @word2 ",
@@ -29,9 +106,6 @@ func TestExecuteQuerry(t *testing.T) {
 	}
 
 	type args struct {
-		endpoint_uri   string
-		master_key     string
-		database       string
 		container      string
 		partitionkey   string
 		max_item_count int
@@ -48,9 +122,6 @@ func TestExecuteQuerry(t *testing.T) {
 		{
 			name: "Test 1",
 			args: args{
-				endpoint_uri:   os.Getenv("ENDPOINT_URI"),
-				master_key:     os.Getenv("MASTER_KEY"),
-				database:       os.Getenv("DATABASE"),
 				container:      "dictionary",
 				partitionkey:   "",
 				max_item_count: 0,
@@ -74,9 +145,8 @@ func TestExecuteQuerry(t *testing.T) {
 				steps += 1
 				fmt.Println("Step:", steps)
 
-				res_status, res_body, res_continuation = ExecuteQuerry(
-					tt.args.endpoint_uri, tt.args.master_key,
-					tt.args.database, tt.args.container, tt.args.partitionkey,
+				container := ContainerFactory(database, tt.args.container, tt.args.partitionkey)
+				res_status, res_body, res_continuation = container.ExecuteQuerry(
 					tt.args.max_item_count, tt.args.continuation,
 					tt.args.querry)
 
@@ -115,12 +185,9 @@ func TestExecuteQuerry(t *testing.T) {
 
 func TestGetDocumentByID(t *testing.T) {
 
-	godotenv.Load(".env")
+	database := useCassette(t, "get_document_by_id")
 
 	type args struct {
-		endpoint_uri string
-		master_key   string
-		database     string
 		container    string
 		partitionkey string
 		id           string
@@ -134,9 +201,6 @@ func TestGetDocumentByID(t *testing.T) {
 		{
 			name: "Test 2",
 			args: args{
-				endpoint_uri: os.Getenv("ENDPOINT_URI"),
-				master_key:   os.Getenv("MASTER_KEY"),
-				database:     os.Getenv("DATABASE"),
 				container:    "dictionary",
 				partitionkey: "Zwerg",
 				id:           "Zwerg",
@@ -147,7 +211,8 @@ func TestGetDocumentByID(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			gotStatus, gotBody := GetDocumentByID(tt.args.endpoint_uri, tt.args.master_key, tt.args.database, tt.args.container, tt.args.partitionkey, tt.args.id)
+			container := ContainerFactory(database, tt.args.container, tt.args.partitionkey)
+			gotStatus, gotBody := container.GetDocumentByID(tt.args.id)
 			if gotStatus != tt.wantStatus {
 				t.Errorf("GetDocumentByID() gotStatus = %v, want %v", gotStatus, tt.wantStatus)
 			}
@@ -161,12 +226,9 @@ func TestGetDocumentByID(t *testing.T) {
 
 func TestCreateDocument(t *testing.T) {
 
-	godotenv.Load(".env")
+	database := useCassette(t, "create_document")
 
 	type args struct {
-		endpoint_uri string
-		master_key   string
-		database     string
 		container    string
 		partitionkey string
 		upset        bool
@@ -181,9 +243,6 @@ func TestCreateDocument(t *testing.T) {
 		{
 			name: "Test 3",
 			args: args{
-				endpoint_uri: os.Getenv("ENDPOINT_URI"),
-				master_key:   os.Getenv("MASTER_KEY"),
-				database:     os.Getenv("DATABASE"),
 				container:    "user",
 				partitionkey: "SuperJoda2",
 				upset:        false,
@@ -195,7 +254,8 @@ func TestCreateDocument(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			gotStatus, gotBody := CreateDocument(tt.args.endpoint_uri, tt.args.master_key, tt.args.database, tt.args.container, tt.args.partitionkey, tt.args.upset, tt.args.data)
+			container := ContainerFactory(database, tt.args.container, tt.args.partitionkey)
+			gotStatus, gotBody := container.CreateDocument(tt.args.upset, tt.args.data)
 			if gotStatus != tt.wantStatus {
 				t.Errorf("CreateDocument() gotStatus = %v, want %v", gotStatus, tt.wantStatus)
 			}
@@ -210,12 +270,9 @@ func TestCreateDocument(t *testing.T) {
 
 func TestDeleteDocumentByID(t *testing.T) {
 
-	godotenv.Load(".env")
+	database := useCassette(t, "delete_document_by_id")
 
 	type args struct {
-		endpoint_uri string
-		master_key   string
-		database     string
 		container    string
 		partitionkey string
 		id           string
@@ -229,9 +286,6 @@ func TestDeleteDocumentByID(t *testing.T) {
 		{
 			name: "Test 4",
 			args: args{
-				endpoint_uri: os.Getenv("ENDPOINT_URI"),
-				master_key:   os.Getenv("MASTER_KEY"),
-				database:     os.Getenv("DATABASE"),
 				container:    "user",
 				partitionkey: "SuperJoda2",
 				id:           "SuperJoda2",
@@ -242,7 +296,8 @@ func TestDeleteDocumentByID(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			gotStatus, gotBody := DeleteDocumentByID(tt.args.endpoint_uri, tt.args.master_key, tt.args.database, tt.args.container, tt.args.partitionkey, tt.args.id)
+			container := ContainerFactory(database, tt.args.container, tt.args.partitionkey)
+			gotStatus, gotBody := container.DeleteDocumentByID(tt.args.id)
 			if gotStatus != tt.wantStatus {
 				t.Errorf("GetDocumentByID() gotStatus = %v, want %v", gotStatus, tt.wantStatus)
 			}
@@ -254,7 +309,51 @@ func TestDeleteDocumentByID(t *testing.T) {
 	}
 }
 
+func TestExecuteQuerryFunction(t *testing.T) {
+	database := useCassette(t, "execute_querry_function")
+	endpoint_uri := serveCassette(t, database)
+
+	query := TQuery{
+		Query:      "SELECT * FROM c WHERE c.word = @word1 OR c.word = @word2 ",
+		Parameters: []TParameter{{Name: "@word1", Value: "Zwerg"}, {Name: "@word2", Value: "Nase"}},
+	}
+	status, body, continuation := ExecuteQuerry(endpoint_uri, database.MasterKey, database.Database, "dictionary", "", 0, "", query)
+	var content TBody
+	_ = json.Unmarshal([]byte(body), &content)
+	if status != "200 OK" || content.Count != 2 || continuation != "" {
+		t.Errorf("ExecuteQuerry() = %v, count %v, continuation %q", status, content.Count, continuation)
+	}
+}
+
+func TestDocumentFunctions(t *testing.T) {
+	tests := []struct {
+		name       string
+		cassette   string
+		call       func(endpoint_uri string, master_key string, database string) (string, string)
+		wantStatus string
+	}{
+		{"GetDocumentByID", "get_document_by_id_function", func(endpoint_uri string, master_key string, database string) (string, string) {
+			return GetDocumentByID(endpoint_uri, master_key, database, "dictionary", "Zwerg", "Zwerg")
+		}, "200 OK"},
+		{"CreateDocument", "create_document_function", func(endpoint_uri string, master_key string, database string) (string, string) {
+			return CreateDocument(endpoint_uri, master_key, database, "user", "SuperJoda2", false, `{"id": "SuperJoda2","name": "SuperJoda2"}`)
+		}, "201 Created"},
+		{"DeleteDocumentByID", "delete_document_by_id_function", func(endpoint_uri string, master_key string, database string) (string, string) {
+			return DeleteDocumentByID(endpoint_uri, master_key, database, "user", "SuperJoda2", "SuperJoda2")
+		}, "204 No Content"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			database := useCassette(t, tt.cassette)
+			if gotStatus, gotBody := tt.call(serveCassette(t, database), database.MasterKey, database.Database); gotStatus != tt.wantStatus {
+				t.Errorf("%s() = %v, %v, want %v", tt.name, gotStatus, gotBody, tt.wantStatus)
+			}
+		})
+	}
+}
+
 func Test_test(t *testing.T) {
+	database := useCassette(t, "test")
 	tests := []struct {
 		name       string
 		wantStatus string
@@ -264,7 +363,7 @@ func Test_test(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if gotStatus := test(); gotStatus != tt.wantStatus {
+			if gotStatus := testDatabase(database); gotStatus != tt.wantStatus {
 				t.Errorf("test() = %v, want %v", gotStatus, tt.wantStatus)
 			}
 		})
@@ -0,0 +1,150 @@
+package cosmos_db_restapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// TStoredProcedure - a javascript stored procedure, Body like "function (name) {...}"
+type TStoredProcedure struct {
+	ID   string `json:"id"`
+	Body string `json:"body"`
+	Rid  string `json:"_rid,omitempty"`
+	Ts   int64  `json:"_ts,omitempty"`
+	Self string `json:"_self,omitempty"`
+	Etag string `json:"_etag,omitempty"`
+}
+
+const (
+	TriggerTypePre  = "Pre"
+	TriggerTypePost = "Post"
+
+	TriggerOperationAll     = "All"
+	TriggerOperationCreate  = "Create"
+	TriggerOperationReplace = "Replace"
+	TriggerOperationDelete  = "Delete"
+	TriggerOperationUpsert  = "Upsert"
+)
+
+// TTrigger - a javascript trigger, used by a write with TRequestOptions PreTriggers or PostTriggers
+type TTrigger struct {
+	ID               string `json:"id"`
+	Body             string `json:"body"`
+	TriggerType      string `json:"triggerType"`      //TriggerTypePre or TriggerTypePost
+	TriggerOperation string `json:"triggerOperation"` //TriggerOperationAll, ...Create, ...Replace, ...Delete or ...Upsert
+	Rid              string `json:"_rid,omitempty"`
+	Ts               int64  `json:"_ts,omitempty"`
+	Self             string `json:"_self,omitempty"`
+	Etag             string `json:"_etag,omitempty"`
+}
+
+// TUserDefinedFunction - a javascript function for queries i.e. "SELECT udf.tax(c.price) FROM c"
+type TUserDefinedFunction struct {
+	ID   string `json:"id"`
+	Body string `json:"body"`
+	Rid  string `json:"_rid,omitempty"`
+	Ts   int64  `json:"_ts,omitempty"`
+	Self string `json:"_self,omitempty"`
+	Etag string `json:"_etag,omitempty"`
+}
+
+// writeScript - creates or replaces a sproc, trigger or udf, resource_type is "sprocs", "triggers" or "udfs"
+func (me *TContainer) writeScript(resource_type string, id string, script interface{}, replace bool) (Status string, Body string) {
+	data, _ := json.Marshal(script)
+	if replace {
+		res := me.sendResource("PUT", me.collectionLink().Child(resource_type, id), http.Header{}, data)
+		return res.Status, res.Body
+	}
+	res := me.sendFeed("POST", resource_type, me.collectionLink(), http.Header{}, data)
+	return res.Status, res.Body
+}
+
+// deleteScript - deletes a sproc, trigger or udf
+func (me *TContainer) deleteScript(resource_type string, id string) (Status string, Body string) {
+	res := me.sendResource("DELETE", me.collectionLink().Child(resource_type, id), http.Header{}, nil)
+	return res.Status, res.Body
+}
+
+/*
+CreateStoredProcedure - create a stored procedure in the container via rest api
+
+returns:
+
+	Status - response status i.e. 201 Created
+	Body - response body as string
+*/
+func (me *TContainer) CreateStoredProcedure(sproc TStoredProcedure) (Status string, Body string) {
+	return me.writeScript("sprocs", sproc.ID, sproc, false)
+}
+
+// ReplaceStoredProcedure - replace the body of a stored procedure via rest api
+func (me *TContainer) ReplaceStoredProcedure(sproc TStoredProcedure) (Status string, Body string) {
+	return me.writeScript("sprocs", sproc.ID, sproc, true)
+}
+
+// DeleteStoredProcedure - delete a stored procedure via rest api
+func (me *TContainer) DeleteStoredProcedure(id string) (Status string, Body string) {
+	return me.deleteScript("sprocs", id)
+}
+
+/*
+ExecuteStoredProcedure - execute a stored procedure in the partition of the container via rest api
+
+parameters:
+
+	id - id of the stored procedure
+	params - the parameters of the function, marshalled to json
+
+returns:
+
+	Status - response status i.e. 200 ok, 400 Bad Request for an exception in the procedure
+	Body - the body set with getContext().getResponse().setBody()
+*/
+func (me *TContainer) ExecuteStoredProcedure(id string, params ...interface{}) (Status string, Body string) {
+	if params == nil {
+		params = []interface{}{}
+	}
+	data, err := json.Marshal(params)
+	if err != nil {
+		res := me.failed(errorResponse(http.StatusBadRequest, "BadRequest", err))
+		return res.Status, res.Body
+	}
+	res := me.sendResource("POST", me.collectionLink().Child("sprocs", id), http.Header{}, data)
+	return res.Status, res.Body
+}
+
+// CreateTrigger - create a trigger in the container via rest api
+func (me *TContainer) CreateTrigger(trigger TTrigger) (Status string, Body string) {
+	return me.writeScript("triggers", trigger.ID, trigger, false)
+}
+
+// ReplaceTrigger - replace a trigger via rest api
+func (me *TContainer) ReplaceTrigger(trigger TTrigger) (Status string, Body string) {
+	return me.writeScript("triggers", trigger.ID, trigger, true)
+}
+
+// DeleteTrigger - delete a trigger via rest api
+func (me *TContainer) DeleteTrigger(id string) (Status string, Body string) {
+	return me.deleteScript("triggers", id)
+}
+
+// CreateUserDefinedFunction - create a user defined function in the container via rest api
+func (me *TContainer) CreateUserDefinedFunction(udf TUserDefinedFunction) (Status string, Body string) {
+	return me.writeScript("udfs", udf.ID, udf, false)
+}
+
+// ReplaceUserDefinedFunction - replace a user defined function via rest api
+func (me *TContainer) ReplaceUserDefinedFunction(udf TUserDefinedFunction) (Status string, Body string) {
+	return me.writeScript("udfs", udf.ID, udf, true)
+}
+
+// DeleteUserDefinedFunction - delete a user defined function via rest api
+func (me *TContainer) DeleteUserDefinedFunction(id string) (Status string, Body string) {
+	return me.deleteScript("udfs", id)
+}
+
+// triggerHeader - the trigger names for x-ms-documentdb-pre-trigger-include and -post-trigger-include
+func triggerHeader(names []string) string {
+	return strings.Join(names, ",")
+}
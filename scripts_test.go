@@ -0,0 +1,80 @@
+package cosmos_db_restapi
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestScripts(t *testing.T) {
+	var got_method, got_path, got_body string
+	var got_header http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		got_method, got_path, got_body, got_header = r.Method, r.URL.Path, string(data), r.Header
+		switch r.Method {
+		case "DELETE":
+			w.WriteHeader(http.StatusNoContent)
+		case "POST":
+			if r.URL.Path == "/dbs/db/colls/user/sprocs/count" {
+				w.Write([]byte(`3`))
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+		default:
+			w.Write([]byte(`{}`))
+		}
+	}))
+	defer server.Close()
+
+	container := ContainerFactory(DatabaseFactory(server.URL+"/", "", "db"), "user", "tenant")
+
+	tests := []struct {
+		name       string
+		call       func() (string, string)
+		wantMethod string
+		wantPath   string
+		wantStatus string
+	}{
+		{"create sproc", func() (string, string) {
+			return container.CreateStoredProcedure(TStoredProcedure{ID: "count", Body: "function () {}"})
+		}, "POST", "/dbs/db/colls/user/sprocs", "201 Created"},
+		{"replace sproc", func() (string, string) {
+			return container.ReplaceStoredProcedure(TStoredProcedure{ID: "count", Body: "function () {}"})
+		}, "PUT", "/dbs/db/colls/user/sprocs/count", "200 OK"},
+		{"delete sproc", func() (string, string) { return container.DeleteStoredProcedure("count") }, "DELETE", "/dbs/db/colls/user/sprocs/count", "204 No Content"},
+		{"create trigger", func() (string, string) {
+			return container.CreateTrigger(TTrigger{ID: "stamp", Body: "function () {}", TriggerType: TriggerTypePre, TriggerOperation: TriggerOperationAll})
+		}, "POST", "/dbs/db/colls/user/triggers", "201 Created"},
+		{"delete trigger", func() (string, string) { return container.DeleteTrigger("stamp") }, "DELETE", "/dbs/db/colls/user/triggers/stamp", "204 No Content"},
+		{"create udf", func() (string, string) {
+			return container.CreateUserDefinedFunction(TUserDefinedFunction{ID: "tax", Body: "function (p) { return p * 0.19 }"})
+		}, "POST", "/dbs/db/colls/user/udfs", "201 Created"},
+		{"delete udf", func() (string, string) { return container.DeleteUserDefinedFunction("tax") }, "DELETE", "/dbs/db/colls/user/udfs/tax", "204 No Content"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotStatus, _ := tt.call()
+			if gotStatus != tt.wantStatus || got_method != tt.wantMethod || got_path != tt.wantPath {
+				t.Errorf("gotStatus = %v, method = %v, path = %v", gotStatus, got_method, got_path)
+			}
+		})
+	}
+
+	status, body := container.ExecuteStoredProcedure("count", "a", 1)
+	if status != "200 OK" || body != "3" || got_body != `["a",1]` || got_header.Get("x-ms-documentdb-partitionkey") != `[ "tenant" ]` {
+		t.Errorf("ExecuteStoredProcedure() = %v, %v, sent %v", status, body, got_body)
+	}
+
+	container.Options.PreTriggers = []string{"stamp", "validate"}
+	container.Options.PostTriggers = []string{"audit"}
+	container.CreateDocument(false, `{"id":"1","tenant":"tenant"}`)
+	if got_header.Get("x-ms-documentdb-pre-trigger-include") != "stamp,validate" || got_header.Get("x-ms-documentdb-post-trigger-include") != "audit" {
+		t.Errorf("trigger headers = %v, %v", got_header.Get("x-ms-documentdb-pre-trigger-include"), got_header.Get("x-ms-documentdb-post-trigger-include"))
+	}
+	container.GetDocumentByID("1")
+	if got_header.Get("x-ms-documentdb-pre-trigger-include") != "" {
+		t.Errorf("trigger header on read = %v", got_header.Get("x-ms-documentdb-pre-trigger-include"))
+	}
+}
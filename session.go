@@ -0,0 +1,241 @@
+package cosmos_db_restapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// tSessionToken - one session token of a partition key range, i.e. "0:1#12#3=11"
+type tSessionToken struct {
+	Token   string //token without the partition key range id
+	Version int64
+	LSN     int64 //global logical sequence number
+}
+
+/*
+parseSessionToken - splits "pkrangeid:version#globallsn#region=lsn" or the
+older format "pkrangeid:lsn" into its parts
+*/
+func parseSessionToken(token string) (PKRange string, Session tSessionToken, ok bool) {
+	PKRange, value, found := strings.Cut(strings.TrimSpace(token), ":")
+	if !found || PKRange == "" || value == "" {
+		return "", tSessionToken{}, false
+	}
+	Session.Token = value
+	parts := strings.Split(value, "#")
+	var err error
+	if len(parts) == 1 {
+		Session.LSN, err = strconv.ParseInt(parts[0], 10, 64)
+	} else {
+		if Session.Version, err = strconv.ParseInt(parts[0], 10, 64); err == nil {
+			Session.LSN, err = strconv.ParseInt(parts[1], 10, 64)
+		}
+	}
+	return PKRange, Session, err == nil
+}
+
+// newer - true if the token is more recent than the other token
+func (me tSessionToken) newer(other tSessionToken) bool {
+	if me.Version != other.Version {
+		return me.Version > other.Version
+	}
+	return me.LSN > other.LSN
+}
+
+// TSessionContainer - session tokens per collection and partition key range, safe for concurrent use
+type TSessionContainer struct {
+	mutex  sync.RWMutex
+	tokens map[string]map[string]tSessionToken //collection link -> partition key range id -> token
+	ranges map[string]map[string]string        //collection link -> partition key header -> partition key range id
+}
+
+// SessionContainerFactory - creates an empty session container
+func SessionContainerFactory() *TSessionContainer {
+	return &TSessionContainer{tokens: map[string]map[string]tSessionToken{}}
+}
+
+/*
+Update - merges the x-ms-session-token header of a response into the container
+
+parameters:
+
+	collection_link - link of the collection i.e. "dbs/db/colls/coll"
+	header - the session token(s) of the response, i.e. "0:1#12,1:1#8"
+
+per partition key range only the most recent token is kept
+*/
+func (me *TSessionContainer) Update(collection_link string, header string) {
+	if me == nil || collection_link == "" || header == "" {
+		return
+	}
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	if me.tokens == nil {
+		me.tokens = map[string]map[string]tSessionToken{}
+	}
+	ranges := me.tokens[collection_link]
+	if ranges == nil {
+		ranges = map[string]tSessionToken{}
+		me.tokens[collection_link] = ranges
+	}
+	for _, token := range strings.Split(header, ",") {
+		pkrange, session, ok := parseSessionToken(token)
+		if !ok {
+			continue
+		}
+		if known, found := ranges[pkrange]; !found || session.newer(known) {
+			ranges[pkrange] = session
+		}
+	}
+}
+
+/*
+Get - the session token for a request on the collection
+
+with pkrange "" all tokens of the collection are returned as compound token
+"0:1#12,1:1#8", else only the token of the partition key range
+*/
+func (me *TSessionContainer) Get(collection_link string, pkrange string) string {
+	if me == nil {
+		return ""
+	}
+	me.mutex.RLock()
+	defer me.mutex.RUnlock()
+	ranges := me.tokens[collection_link]
+	if pkrange != "" {
+		if session, found := ranges[pkrange]; found {
+			return pkrange + ":" + session.Token
+		}
+		return ""
+	}
+	list := make([]string, 0, len(ranges))
+	for id, session := range ranges {
+		list = append(list, id+":"+session.Token)
+	}
+	sort.Strings(list)
+	return strings.Join(list, ",")
+}
+
+// Clear - removes the tokens of a collection, i.e. after the collection was recreated
+func (me *TSessionContainer) Clear(collection_link string) {
+	if me == nil {
+		return
+	}
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	delete(me.tokens, collection_link)
+	delete(me.ranges, collection_link)
+}
+
+/*
+learnRange - remembers the partition key range of a partition key
+
+the response of a request on one partition key has the token of one range only,
+after a split the token of the new range replaces the known range
+*/
+func (me *TSessionContainer) learnRange(collection_link string, partitionkey string, header string) {
+	if me == nil || collection_link == "" || partitionkey == "" || header == "" || strings.Contains(header, ",") {
+		return
+	}
+	pkrange, _, ok := parseSessionToken(header)
+	if !ok {
+		return
+	}
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	if me.ranges == nil {
+		me.ranges = map[string]map[string]string{}
+	}
+	if me.ranges[collection_link] == nil {
+		me.ranges[collection_link] = map[string]string{}
+	}
+	me.ranges[collection_link][partitionkey] = pkrange
+}
+
+/*
+tokenFor - the session token for a request with the headers
+
+the token of the partition key range of x-ms-documentdb-partitionkeyrangeid or of the
+range learned for x-ms-documentdb-partitionkey, the compound token if the range is unknown
+*/
+func (me *TSessionContainer) tokenFor(collection_link string, header http.Header) string {
+	if me == nil {
+		return ""
+	}
+	pkrange := header.Get("x-ms-documentdb-partitionkeyrangeid")
+	if partitionkey := header.Get("x-ms-documentdb-partitionkey"); pkrange == "" && partitionkey != "" {
+		me.mutex.RLock()
+		pkrange = me.ranges[collection_link][partitionkey]
+		me.mutex.RUnlock()
+	}
+	return me.Get(collection_link, pkrange)
+}
+
+// Export - the compound session token per collection link, i.e. to pass it to another service
+func (me *TSessionContainer) Export() map[string]string {
+	tokens := map[string]string{}
+	if me == nil {
+		return tokens
+	}
+	me.mutex.RLock()
+	links := make([]string, 0, len(me.tokens))
+	for link := range me.tokens {
+		links = append(links, link)
+	}
+	me.mutex.RUnlock()
+	for _, link := range links {
+		if token := me.Get(link, ""); token != "" {
+			tokens[link] = token
+		}
+	}
+	return tokens
+}
+
+// Import - merges exported session tokens, newer local tokens are kept
+func (me *TSessionContainer) Import(tokens map[string]string) {
+	for link, token := range tokens {
+		me.Update(link, token)
+	}
+}
+
+// MarshalJSON - the exported tokens as json object
+func (me *TSessionContainer) MarshalJSON() ([]byte, error) {
+	return json.Marshal(me.Export())
+}
+
+// UnmarshalJSON - imports tokens from a json object
+func (me *TSessionContainer) UnmarshalJSON(data []byte) error {
+	var tokens map[string]string
+	if err := json.Unmarshal(data, &tokens); err != nil {
+		return err
+	}
+	me.Import(tokens)
+	return nil
+}
+
+// collectionOfLink - the collection link "dbs/db/colls/coll" of a resource link, or "" if there is none
+func collectionOfLink(resource_link string) string {
+	parts := strings.Split(strings.Trim(resource_link, "/"), "/")
+	if len(parts) < 4 || parts[0] != "dbs" || parts[2] != "colls" {
+		return ""
+	}
+	return strings.Join(parts[:4], "/")
+}
+
+// unescapePath - the url path with the unescaped names, the path itself if it is not escaped properly
+func unescapePath(path string) string {
+	if unescaped, err := url.PathUnescape(path); err == nil {
+		return unescaped
+	}
+	return path
+}
+
+// isReadRequest - reads and queries, which need the session token for session consistency
+func isReadRequest(verb string, header http.Header) bool {
+	return verb == "GET" || verb == "HEAD" || header.Get("x-ms-documentdb-isquery") != ""
+}
@@ -0,0 +1,136 @@
+package cosmos_db_restapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSessionContainerUpdate(t *testing.T) {
+	tests := []struct {
+		name    string
+		updates []string
+		pkrange string
+		want    string
+	}{
+		{"single token", []string{"0:1#12"}, "", "0:1#12"},
+		{"newer lsn wins", []string{"0:1#12", "0:1#15", "0:1#13"}, "", "0:1#15"},
+		{"newer version wins", []string{"0:1#99", "0:2#3"}, "", "0:2#3"},
+		{"compound token", []string{"1:1#8,0:1#12#3=11"}, "", "0:1#12#3=11,1:1#8"},
+		{"one range", []string{"1:1#8,0:1#12"}, "1", "1:1#8"},
+		{"legacy format", []string{"0:12", "0:9"}, "", "0:12"},
+		{"malformed token ignored", []string{"0:abc", "garbage"}, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sessions := SessionContainerFactory()
+			for _, update := range tt.updates {
+				sessions.Update("dbs/db/colls/coll", update)
+			}
+			if got := sessions.Get("dbs/db/colls/coll", tt.pkrange); got != tt.want {
+				t.Errorf("Get() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSessionContainerExportImport(t *testing.T) {
+	sessions := SessionContainerFactory()
+	sessions.Update("dbs/db/colls/a", "0:1#5")
+	sessions.Update("dbs/db/colls/b", "0:1#7,1:1#2")
+
+	data, err := json.Marshal(sessions)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+
+	other := SessionContainerFactory()
+	other.Update("dbs/db/colls/a", "0:1#9")
+	if err := json.Unmarshal(data, other); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if got := other.Get("dbs/db/colls/a", ""); got != "0:1#9" {
+		t.Errorf("Get(a) = %v, want the newer local token", got)
+	}
+	if got := other.Get("dbs/db/colls/b", ""); got != "0:1#7,1:1#2" {
+		t.Errorf("Get(b) = %v, want imported token", got)
+	}
+}
+
+func TestSessionTokenRoundTrip(t *testing.T) {
+	var got_token string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got_token = r.Header.Get("x-ms-session-token")
+		w.Header().Set("x-ms-session-token", "0:1#42")
+		if r.Method == "POST" {
+			w.WriteHeader(http.StatusCreated)
+		}
+	}))
+	defer server.Close()
+
+	database := DatabaseFactory(server.URL+"/", "", "db")
+	writer := ContainerFactory(database, "coll", "pk")
+	reader := ContainerFactory(database, "coll", "pk")
+
+	writer.CreateDocument(false, `{"id":"1"}`)
+	if got_token != "" {
+		t.Errorf("write sent session token %v, want none", got_token)
+	}
+	reader.GetDocumentByID("1")
+	if got_token != "0:1#42" {
+		t.Errorf("read sent session token %v, want 0:1#42", got_token)
+	}
+
+	reader.Options.SessionToken = "0:1#50"
+	reader.GetDocumentByID("1")
+	if got_token != "0:1#50" {
+		t.Errorf("read sent session token %v, want the explicit token", got_token)
+	}
+}
+
+func TestSessionTokenOfRange(t *testing.T) {
+	var got_token string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got_token = r.Header.Get("x-ms-session-token")
+		switch r.Header.Get("x-ms-documentdb-partitionkey") {
+		case `[ "a" ]`:
+			w.Header().Set("x-ms-session-token", "0:1#5")
+		case `[ "b" ]`:
+			w.Header().Set("x-ms-session-token", "1:1#9")
+		}
+	}))
+	defer server.Close()
+
+	database := DatabaseFactory(server.URL+"/", "", "db")
+	a, b := ContainerFactory(database, "coll", "a"), ContainerFactory(database, "coll", "b")
+	a.CreateDocument(true, `{"id":"1"}`)
+	b.CreateDocument(true, `{"id":"2"}`)
+
+	tests := []struct {
+		name      string
+		read      func()
+		wantToken string
+	}{
+		{"range of a", func() { a.GetDocumentByID("1") }, "0:1#5"},
+		{"range of b", func() { b.GetDocumentByID("2") }, "1:1#9"},
+		{"unknown range", func() {
+			other := ContainerFactory(database, "coll", "c")
+			other.GetDocumentByID("3")
+		}, "0:1#5,1:1#9"},
+		{"range header", func() {
+			header := http.Header{}
+			header.Set("x-ms-documentdb-partitionkeyrangeid", "1")
+			database.Do(context.Background(), "GET", "docs", "dbs/db/colls/coll", header, nil)
+		}, "1:1#9"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.read()
+			if got_token != tt.wantToken {
+				t.Errorf("read sent session token %v, want %v", got_token, tt.wantToken)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package cosmos_db_restapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// TTLNever - ttl of a document or default ttl of a container for documents which never expire
+const TTLNever = -1
+
+/*
+TTL - pointer to a ttl in seconds for DefaultTtl and AnalyticalStorageTtl of TContainerProperties
+
+	nil - time to live is off, the documents never expire and a document ttl is ignored
+	TTL(TTLNever) - on, documents expire only if they have their own ttl
+	TTL(3600) - documents expire one hour after their last update (_ts)
+*/
+func TTL(seconds int) *int {
+	return &seconds
+}
+
+/*
+SetTTL - sets the ttl field of a document as json string
+
+parameters:
+
+	data - json data of the item
+	seconds - seconds after the last update until the document expires, TTLNever for never
+
+returns:
+
+	Data - json data of the item with ttl
+*/
+func SetTTL(data string, seconds int) (Data string, err error) {
+	if seconds == 0 || seconds < TTLNever {
+		return data, fmt.Errorf("invalid ttl %d, it must be positive or %d", seconds, TTLNever)
+	}
+	return setDocumentField(data, "ttl", seconds)
+}
+
+// ClearTTL - removes the ttl field of a document as json string, the default ttl of the container applies
+func ClearTTL(data string) (Data string, err error) {
+	return setDocumentField(data, "ttl", nil)
+}
+
+// SetTTLPatch - patch operation to set the ttl of a document
+func SetTTLPatch(seconds int) TPatchOperation {
+	return TPatchOperation{Op: "set", Path: "/ttl", Value: seconds}
+}
+
+// ClearTTLPatch - patch operation to remove the ttl of a document
+func ClearTTLPatch() TPatchOperation {
+	return TPatchOperation{Op: "remove", Path: "/ttl"}
+}
+
+/*
+setDocumentField - sets or, with value nil, removes a top level field of a json document
+
+the other fields keep their order and their values as they are, numbers are not
+converted to float64, a new field is appended at the end
+*/
+func setDocumentField(data string, field string, value interface{}) (Data string, err error) {
+	decoder := json.NewDecoder(strings.NewReader(data))
+	decoder.UseNumber()
+	if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+		if err == nil {
+			err = fmt.Errorf("document is not a json object")
+		}
+		return data, err
+	}
+
+	var raw_value json.RawMessage
+	if value != nil {
+		if raw_value, err = json.Marshal(value); err != nil {
+			return data, err
+		}
+	}
+
+	var result bytes.Buffer
+	result.WriteByte('{')
+	found := false
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return data, err
+		}
+		key, _ := token.(string)
+		var raw json.RawMessage
+		if err = decoder.Decode(&raw); err != nil {
+			return data, err
+		}
+		if key == field {
+			if found || value == nil {
+				continue
+			}
+			raw, found = raw_value, true
+		}
+		writeDocumentField(&result, key, raw)
+	}
+	if _, err = decoder.Token(); err != nil { //the closing brace
+		return data, err
+	}
+	if _, err = decoder.Token(); err != io.EOF {
+		return data, fmt.Errorf("document is not a single json object")
+	}
+	if !found && value != nil {
+		writeDocumentField(&result, field, raw_value)
+	}
+	result.WriteByte('}')
+	return result.String(), nil
+}
+
+// writeDocumentField - writes "key":value to a json object, with a comma after the first field
+func writeDocumentField(buffer *bytes.Buffer, key string, raw json.RawMessage) {
+	if buffer.Len() > 1 {
+		buffer.WriteByte(',')
+	}
+	name, _ := json.Marshal(key)
+	buffer.Write(name)
+	buffer.WriteByte(':')
+	buffer.Write(raw)
+}
@@ -0,0 +1,58 @@
+package cosmos_db_restapi
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestSetTTL(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		seconds int
+		want    string
+		wantErr bool
+	}{
+		{"set", `{"id":"Zwerg"}`, 3600, `{"id":"Zwerg","ttl":3600}`, false},
+		{"replace", `{"id":"Zwerg","ttl":10}`, TTLNever, `{"id":"Zwerg","ttl":-1}`, false},
+		{"order is kept", `{"word":"Zwerg","ttl":10,"id":"1","count":2}`, 60, `{"word":"Zwerg","ttl":60,"id":"1","count":2}`, false},
+		{"numbers are kept", `{"id":"1","big":12345678901234567890,"price":1.10,"nested":{"b":1,"a":2}}`, 60,
+			`{"id":"1","big":12345678901234567890,"price":1.10,"nested":{"b":1,"a":2},"ttl":60}`, false},
+		{"zero", `{"id":"Zwerg"}`, 0, "", true},
+		{"no object", `[1]`, 10, "", true},
+		{"invalid json", `{`, 10, "", true},
+		{"trailing data", `{"id":"Zwerg"} {}`, 10, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SetTTL(tt.data, tt.seconds)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SetTTL() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("SetTTL() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if got, _ := ClearTTL(`{"ttl":10,"word":"Zwerg","id":"Zwerg"}`); got != `{"word":"Zwerg","id":"Zwerg"}` {
+		t.Errorf("ClearTTL() = %v", got)
+	}
+}
+
+func TestContainerTTLProperties(t *testing.T) {
+	properties := TContainerProperties{ID: "session", DefaultTtl: TTL(TTLNever), AnalyticalStorageTtl: TTL(86400)}
+	data, _ := json.Marshal(properties)
+	if string(data) != `{"id":"session","defaultTtl":-1,"analyticalStorageTtl":86400}` {
+		t.Errorf("Marshal() = %s", data)
+	}
+	data, _ = json.Marshal(TContainerProperties{ID: "session"})
+	if string(data) != `{"id":"session"}` {
+		t.Errorf("Marshal() without ttl = %s", data)
+	}
+
+	patch, _ := json.Marshal([]TPatchOperation{SetTTLPatch(60), ClearTTLPatch()})
+	if string(patch) != `[{"op":"set","path":"/ttl","value":60},{"op":"remove","path":"/ttl"}]` {
+		t.Errorf("patch = %s", patch)
+	}
+}